@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"devformat/backend/pkg/devformat"
+)
+
+func runConvert(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("convert", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	from := fs.String("from", "", "source format: yaml, json or toml (default detected from the file name and content)")
+	to := fs.String("to", "", "target format: yaml, json or toml (required)")
+	indent := fs.Int("indent", 2, "spaces per indentation level of YAML and JSON output")
+	ndjson := fs.Bool("ndjson", false, "write a multi-document YAML stream as one JSON document per line")
+	output := fs.String("o", "", "output file (default stdout)")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if *to == "" {
+		fmt.Fprintln(stderr, "devformat convert: --to is required")
+		return exitError
+	}
+	if fs.NArg() > 1 {
+		fmt.Fprintln(stderr, "devformat convert: convert takes a single file")
+		return exitError
+	}
+
+	var data []byte
+	var err error
+	name := "<stdin>"
+	if path := fs.Arg(0); path == "" || path == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		name = path
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "devformat convert: %v\n", err)
+		return exitError
+	}
+
+	res, err := devformat.Convert(context.Background(), devformat.ConvertRequest{
+		Content:  string(data),
+		Filename: fs.Arg(0),
+		From:     *from,
+		To:       *to,
+		Indent:   *indent,
+		NDJSON:   *ndjson,
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", name, err)
+		return exitProblems
+	}
+	for _, w := range res.Warnings {
+		if w.Line > 0 {
+			fmt.Fprintf(stderr, "%s:%d: warning: %s\n", name, w.Line, w.Message)
+		} else {
+			fmt.Fprintf(stderr, "%s: warning: %s\n", name, w.Message)
+		}
+	}
+
+	if *output == "" {
+		fmt.Fprint(stdout, res.Content)
+		return exitOK
+	}
+	if err := os.WriteFile(*output, []byte(res.Content), 0644); err != nil {
+		fmt.Fprintf(stderr, "devformat convert: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
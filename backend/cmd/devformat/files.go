@@ -0,0 +1,180 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"devformat/backend/pkg/devformat"
+)
+
+// input is one file (or stdin) to process.
+type input struct {
+	// path is the file to read and write; empty for stdin.
+	path string
+	// name is the path relative to the configuration file's directory, used
+	// to match ignore and schema globs.
+	name    string
+	content string
+}
+
+// display returns the name used in messages.
+func (in input) display() string {
+	if in.path == "" {
+		if in.name != "" {
+			return in.name
+		}
+		return "<stdin>"
+	}
+	return in.path
+}
+
+// extensions lists the file types picked up when walking directories.
+var extensions = map[string]bool{".yaml": true, ".yml": true, ".json": true}
+
+// loadConfig reads the configuration named by --config or, failing that, the
+// nearest .devformat.yaml above the working directory. It also returns the
+// directory globs are relative to.
+func loadConfig(path string) (*devformat.Config, string, error) {
+	if path == "" {
+		found, ok := devformat.FindConfig(".")
+		if !ok {
+			return nil, "", nil
+		}
+		path = found
+	}
+	cfg, err := devformat.LoadConfig(path)
+	if err != nil {
+		return nil, "", err
+	}
+	root, err := filepath.Abs(filepath.Dir(path))
+	return cfg, root, err
+}
+
+// collectInputs expands paths into inputs. Directories are walked for known
+// extensions, skipping hidden directories; "-" or no paths reads stdin, named
+// stdinName for configuration matching.
+func collectInputs(paths []string, stdin io.Reader, stdinName, root string) ([]input, error) {
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+	var out []input
+	for _, p := range paths {
+		if p == "-" {
+			data, err := io.ReadAll(stdin)
+			if err != nil {
+				return nil, fmt.Errorf("reading stdin: %w", err)
+			}
+			out = append(out, input{name: stdinName, content: string(data)})
+			continue
+		}
+		st, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if !st.IsDir() {
+			in, err := readInput(p, root)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, in)
+			continue
+		}
+		err = filepath.WalkDir(p, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if path != p && strings.HasPrefix(d.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !extensions[strings.ToLower(filepath.Ext(path))] {
+				return nil
+			}
+			in, err := readInput(path, root)
+			if err != nil {
+				return err
+			}
+			out = append(out, in)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func readInput(path, root string) (input, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return input{}, err
+	}
+	return input{path: path, name: relativeName(path, root), content: string(data)}, nil
+}
+
+// relativeName returns path relative to root in slash form, or path itself
+// when there is no root or path lies outside it.
+func relativeName(path, root string) string {
+	if root == "" {
+		return filepath.ToSlash(path)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// zipDir packs a directory into an in-memory zip archive, leaving out
+// hidden files and directories such as .git.
+func zipDir(dir string) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p != dir && d.Name()[0] == '.' {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		w, err := zw.Create(filepath.ToSlash(rel))
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(content)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"devformat/backend/internal/fixer"
+	"devformat/backend/internal/parser"
+	"devformat/backend/pkg/devformat"
+)
+
+// runFix implements both "fix", which repairs broken indentation through the
+// fixer, and "fmt" (formatOnly), which only re-formats content that already
+// parses and keeps its comments and key order.
+func runFix(args []string, stdin io.Reader, stdout, stderr io.Writer, formatOnly bool) int {
+	name, verb := "fix", "fixed"
+	if formatOnly {
+		name, verb = "fmt", "formatted"
+	}
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	write := fs.Bool("write", false, "write the result back to the files instead of stdout")
+	fs.BoolVar(write, "w", false, "shorthand for --write")
+	check := fs.Bool("check", false, "list files that need changes without modifying them; exit 1 if any")
+	schemaName := fs.String("schema", "", "schema-aware fixes to apply, e.g. kubernetes (fix only)")
+	fixes := fs.String("fixes", "", "comma-separated fixer IDs, or \"all\" (fix only; default indentation)")
+	configPath := fs.String("config", "", "path to a .devformat.yaml file")
+	stdinName := fs.String("stdin-filename", "", "file name used for stdin when matching config globs")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if *write && *check {
+		fmt.Fprintf(stderr, "devformat %s: --write and --check are mutually exclusive\n", name)
+		return exitError
+	}
+
+	cfg, root, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "devformat %s: %v\n", name, err)
+		return exitError
+	}
+	inputs, err := collectInputs(fs.Args(), stdin, *stdinName, root)
+	if err != nil {
+		fmt.Fprintf(stderr, "devformat %s: %v\n", name, err)
+		return exitError
+	}
+	if !*write && !*check && len(inputs) > 1 {
+		fmt.Fprintf(stderr, "devformat %s: use --write or --check with more than one file\n", name)
+		return exitError
+	}
+
+	var fixIDs []string
+	if *fixes != "" {
+		fixIDs = strings.Split(*fixes, ",")
+	}
+	if _, err := fixer.Select(fixIDs); err != nil {
+		fmt.Fprintf(stderr, "devformat %s: %v\n", name, err)
+		return exitError
+	}
+
+	code := exitOK
+	for _, in := range inputs {
+		if cfg.Ignored(in.name) || strings.TrimSpace(in.content) == "" {
+			if !*write && !*check {
+				fmt.Fprint(stdout, in.content)
+			}
+			continue
+		}
+
+		var out string
+		if formatOnly {
+			out, err = formatContent(in.content, in.name, cfg.IndentWidth())
+		} else {
+			out, err = fixContent(in, *schemaName, fixIDs, cfg)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", in.display(), err)
+			var p problemsError
+			if !errors.As(err, &p) {
+				return exitError
+			}
+			code = exitProblems
+			continue
+		}
+
+		switch {
+		case *check:
+			if out != in.content {
+				fmt.Fprintln(stdout, in.display())
+				code = exitProblems
+			}
+		case *write && in.path != "":
+			if out == in.content {
+				continue
+			}
+			if err := os.WriteFile(in.path, []byte(out), 0644); err != nil {
+				fmt.Fprintf(stderr, "devformat %s: %v\n", name, err)
+				return exitError
+			}
+			fmt.Fprintf(stderr, "%s %s\n", verb, in.path)
+		default:
+			fmt.Fprint(stdout, out)
+		}
+	}
+	return code
+}
+
+// problemsError reports content that cannot be fixed or formatted, as
+// opposed to a failure of the engine itself.
+type problemsError struct{ error }
+
+// fixContent repairs content through the same path as /api/fix.
+func fixContent(in input, schemaName string, fixIDs []string, cfg *devformat.Config) (string, error) {
+	res, err := devformat.Fix(context.Background(), devformat.Request{Content: in.content, Filename: in.name, Schema: schemaName, Fixes: fixIDs, Config: cfg})
+	if err != nil {
+		return "", err
+	}
+	if !res.CanAutoFix {
+		msgs := []string{}
+		for _, e := range res.Problems {
+			msgs = append(msgs, fmt.Sprintf("line %d: %s", e.Line, e.Message))
+		}
+		if len(res.Suggestions) > 0 {
+			msgs = append(msgs, "run \"devformat validate\" to see suggested fixes")
+		}
+		return "", problemsError{errors.New(strings.Join(msgs, "; "))}
+	}
+	return res.Content, nil
+}
+
+// formatContent re-encodes content that already parses: JSON keeps its key
+// order, YAML keeps comments and every document of a stream. The format is
+// detected from name and content, as validate and fix do.
+func formatContent(content, name string, indent int) (string, error) {
+	switch format := parser.Detect(content, name).Format; format {
+	case parser.FormatJSON:
+		out, err := indentJSON(content, indent)
+		if err != nil {
+			return "", problemsError{fmt.Errorf("JSON syntax error: %w (try \"devformat fix\")", err)}
+		}
+		return out, nil
+	case parser.FormatYAML:
+	default:
+		return "", fmt.Errorf("%s content cannot be formatted; only YAML and JSON can", format)
+	}
+
+	dec := yaml.NewDecoder(strings.NewReader(content))
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(indent)
+	for {
+		var node yaml.Node
+		err := dec.Decode(&node)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", problemsError{fmt.Errorf("YAML syntax error: %w (try \"devformat fix\")", err)}
+		}
+		if err := enc.Encode(&node); err != nil {
+			return "", err
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func indentJSON(content string, indent int) (string, error) {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(strings.TrimSpace(content)), "", strings.Repeat(" ", indent)); err != nil {
+		return "", err
+	}
+	buf.WriteString("\n")
+	return buf.String(), nil
+}
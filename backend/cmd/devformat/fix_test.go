@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFormatContent(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		filename string
+		want     string
+		// problems is true when the content itself is at fault
+		problems bool
+	}{
+		{"json by file name", `{"b": 1, "a": [true]}`, "x.json", "{\n  \"b\": 1,\n  \"a\": [\n    true\n  ]\n}\n", false},
+		{"same content as yaml", `{"b": 1, "a": [true]}`, "x.yaml", "{\"b\": 1, \"a\": [true]}\n", false},
+		{"yaml keeps comments", "# c\nb:   1\na:\n    - x\n", "x.yml", "# c\nb: 1\na:\n  - x\n", false},
+		{"yaml stream", "a: 1\n---\nb: 2\n", "", "a: 1\n---\nb: 2\n", false},
+		{"broken json", `{"a": 1,}`, "x.json", "", true},
+		{"broken yaml", "a:\n  b: 1\n c: 2\n", "x.yaml", "", true},
+		{"toml", "a = 1\n", "x.toml", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := formatContent(tt.content, tt.filename, 2)
+			var p problemsError
+			if tt.want == "" {
+				if err == nil || errors.As(err, &p) != tt.problems {
+					t.Fatalf("formatContent() = %q, %v; want an error (problems %v)", got, err, tt.problems)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("formatContent() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	"devformat/backend/pkg/devformat"
+)
+
+func runHelmLint(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("helm-lint", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	k8sVersion := flags.String("kubernetes-version", "", "Kubernetes version of the bundled schemas (e.g. 1.28)")
+	strict := flags.Bool("strict", false, "exit with status 1 on warnings as well as errors")
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(stderr, "devformat helm-lint: expected a chart directory, .tgz or .zip")
+		return exitError
+	}
+	chart := flags.Arg(0)
+
+	var data []byte
+	info, err := os.Stat(chart)
+	if err == nil && info.IsDir() {
+		data, err = zipDir(chart)
+	} else if err == nil {
+		data, err = os.ReadFile(chart)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "devformat helm-lint: %v\n", err)
+		return exitError
+	}
+
+	res, err := devformat.LintChart(context.Background(), devformat.ChartRequest{Archive: data, KubernetesVersion: *k8sVersion})
+	if err != nil {
+		fmt.Fprintf(stderr, "devformat helm-lint: %s: %v\n", chart, err)
+		return exitError
+	}
+	code := exitOK
+	for _, f := range res.Files {
+		for _, p := range f.Problems {
+			if p.Severity == "error" || (*strict && p.Severity == "warning") {
+				code = exitProblems
+			}
+			fmt.Fprintf(stdout, "%s:%d:%d: %s: %s (%s)\n", path.Join(chart, f.File), p.Line, p.Column, p.Severity, p.Message, p.Type)
+		}
+	}
+	return code
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"devformat/backend/pkg/devformat"
+)
+
+func runKustomize(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("kustomize", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	k8sVersion := flags.String("kubernetes-version", "", "Kubernetes version of the bundled schemas (e.g. 1.28)")
+	root := flags.String("root", ".", "directory holding the kustomization and every base it refers to")
+	output := flags.String("o", "", "file the built manifests are written to (default stdout)")
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	if flags.NArg() > 1 {
+		fmt.Fprintln(stderr, "devformat kustomize: expected one kustomization directory")
+		return exitError
+	}
+	dir := flags.Arg(0)
+	if dir == "" {
+		dir = "."
+	}
+	rel, err := filepath.Rel(*root, dir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		fmt.Fprintf(stderr, "devformat kustomize: %s is outside --root %s\n", dir, *root)
+		return exitError
+	}
+
+	data, err := zipDir(*root)
+	if err != nil {
+		fmt.Fprintf(stderr, "devformat kustomize: %v\n", err)
+		return exitError
+	}
+	res, err := devformat.BuildKustomization(context.Background(), devformat.KustomizeRequest{
+		Archive:           data,
+		Path:              filepath.ToSlash(rel),
+		KubernetesVersion: *k8sVersion,
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "devformat kustomize: %v\n", err)
+		return exitError
+	}
+	code := exitOK
+	for _, f := range res.Files {
+		for _, p := range f.Problems {
+			if p.Severity == "error" {
+				code = exitProblems
+			}
+			fmt.Fprintf(stderr, "%s:%d:%d: %s: %s (%s)\n", path.Join(filepath.ToSlash(*root), f.File), p.Line, p.Column, p.Severity, p.Message, p.Type)
+		}
+	}
+
+	if *output == "" {
+		fmt.Fprint(stdout, res.Content)
+		return code
+	}
+	if err := os.WriteFile(*output, []byte(res.Content), 0644); err != nil {
+		fmt.Fprintf(stderr, "devformat kustomize: %v\n", err)
+		return exitError
+	}
+	return code
+}
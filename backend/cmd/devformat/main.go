@@ -0,0 +1,85 @@
+// Command devformat validates, fixes and formats YAML/JSON files from the
+// command line, using the same engine as the HTTP backend:
+//
+//	devformat validate [flags] [path ...]
+//	devformat fix      [flags] [path ...]
+//	devformat fmt      [flags] [path ...]
+//	devformat convert  [flags] --to json [path]
+//	devformat helm-lint [flags] chart
+//	devformat kustomize [flags] [dir]
+//	devformat zip      [flags] --main main.tf
+//
+// Paths may be files or directories, which are searched for *.yaml, *.yml and
+// *.json files. With no path, or "-", content is read from stdin.
+//
+// Exit status is 0 on success, 1 when problems were found (or, with --check,
+// files need changes) and 2 on usage or I/O errors, so the command can be
+// used directly from pre-commit hooks and CI jobs.
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+)
+
+// Exit codes.
+const (
+	exitOK       = 0
+	exitProblems = 1
+	exitError    = 2
+)
+
+const usage = `usage: devformat <command> [flags] [path ...]
+
+Commands:
+  validate  check syntax, schemas and lint rules
+  fix       repair indentation and re-format YAML/JSON
+  fmt       re-format valid YAML/JSON, keeping comments
+  convert   convert a file between YAML, JSON and TOML
+  helm-lint lint a Helm chart directory, .tgz or .zip
+  kustomize build a kustomization and validate the output
+  zip       format Terraform files and bundle them into a zip archive
+
+Run "devformat <command> -h" for the flags of a command.
+Paths may be files or directories; "-" or no path reads stdin.
+A .devformat.yaml in the current directory or a parent is used unless --config is given.
+
+Exit status: 0 ok, 1 problems found or files need changes, 2 usage or I/O error.
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return exitError
+	}
+	// the engine logs its heuristics; keep the CLI output clean
+	log.SetOutput(io.Discard)
+
+	switch args[0] {
+	case "validate":
+		return runValidate(args[1:], stdin, stdout, stderr)
+	case "fix":
+		return runFix(args[1:], stdin, stdout, stderr, false)
+	case "fmt":
+		return runFix(args[1:], stdin, stdout, stderr, true)
+	case "convert":
+		return runConvert(args[1:], stdin, stdout, stderr)
+	case "helm-lint":
+		return runHelmLint(args[1:], stdout, stderr)
+	case "kustomize":
+		return runKustomize(args[1:], stdout, stderr)
+	case "zip":
+		return runZip(args[1:], stdout, stderr)
+	case "help", "-h", "--help":
+		fmt.Fprint(stdout, usage)
+		return exitOK
+	}
+	fmt.Fprintf(stderr, "devformat: unknown command %q\n\n%s", args[0], usage)
+	return exitError
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"devformat/backend/internal/types"
+	"devformat/backend/pkg/devformat"
+)
+
+// fileResult is the JSON output of validate for one input.
+type fileResult struct {
+	File           string                  `json:"file"`
+	IsValid        bool                    `json:"isValid"`
+	Ignored        bool                    `json:"ignored,omitempty"`
+	Errors         []types.ValidationError `json:"errors"`
+	Explanation    string                  `json:"explanation,omitempty"`
+	SuggestedFixes []types.Suggestion      `json:"suggestedFixes,omitempty"`
+}
+
+func runValidate(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	schemaName := fs.String("schema", "", "schema to validate against: kubernetes, json, custom, helm, docker-compose or github-actions (default from config)")
+	schemaFile := fs.String("schema-file", "", "JSON Schema file for --schema json or custom")
+	k8sVersion := fs.String("kubernetes-version", "", "Kubernetes version of the bundled schemas (e.g. 1.28)")
+	rules := fs.String("rules", "", "comma-separated lint rule IDs, or \"all\"")
+	valuesFile := fs.String("values", "", "YAML values file Helm templates are rendered with")
+	envFile := fs.String("env-file", "", ".env file docker-compose files are interpolated with (default: the .env next to each file)")
+	configPath := fs.String("config", "", "path to a .devformat.yaml file")
+	stdinName := fs.String("stdin-filename", "", "file name used for stdin when matching config globs")
+	output := fs.String("format", "text", "output format: text or json")
+	strict := fs.Bool("strict", false, "exit with status 1 on warnings as well as errors")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if *output != "text" && *output != "json" {
+		fmt.Fprintf(stderr, "devformat validate: unknown format %q\n", *output)
+		return exitError
+	}
+
+	cfg, root, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "devformat validate: %v\n", err)
+		return exitError
+	}
+	schemaContent := ""
+	if *schemaFile != "" {
+		data, err := os.ReadFile(*schemaFile)
+		if err != nil {
+			fmt.Fprintf(stderr, "devformat validate: %v\n", err)
+			return exitError
+		}
+		schemaContent = string(data)
+		if *schemaName == "" {
+			*schemaName = "custom"
+		}
+	}
+	values := ""
+	if *valuesFile != "" {
+		data, err := os.ReadFile(*valuesFile)
+		if err != nil {
+			fmt.Fprintf(stderr, "devformat validate: %v\n", err)
+			return exitError
+		}
+		values = string(data)
+	}
+	env := ""
+	if *envFile != "" {
+		data, err := os.ReadFile(*envFile)
+		if err != nil {
+			fmt.Fprintf(stderr, "devformat validate: %v\n", err)
+			return exitError
+		}
+		env = string(data)
+	}
+	var ruleIDs []string
+	if *rules != "" {
+		ruleIDs = strings.Split(*rules, ",")
+	}
+
+	inputs, err := collectInputs(fs.Args(), stdin, *stdinName, root)
+	if err != nil {
+		fmt.Fprintf(stderr, "devformat validate: %v\n", err)
+		return exitError
+	}
+
+	code := exitOK
+	results := []fileResult{}
+	dirEnvs := map[string]string{}
+	for _, in := range inputs {
+		if strings.TrimSpace(in.content) == "" {
+			continue
+		}
+		fileEnv := env
+		schema := *schemaName
+		if schema == "" {
+			schema = cfg.SchemaFor(in.name)
+		}
+		if *envFile == "" && schema == "docker-compose" && in.path != "" {
+			if fileEnv, err = dirEnv(dirEnvs, filepath.Dir(in.path)); err != nil {
+				fmt.Fprintf(stderr, "devformat validate: %v\n", err)
+				return exitError
+			}
+		}
+		res, err := devformat.Validate(context.Background(), devformat.Request{
+			Content:           in.content,
+			Filename:          in.name,
+			Schema:            schema,
+			SchemaContent:     schemaContent,
+			KubernetesVersion: *k8sVersion,
+			Rules:             ruleIDs,
+			Values:            values,
+			Env:               fileEnv,
+			Config:            cfg,
+		})
+		if err != nil {
+			fmt.Fprintf(stderr, "devformat validate: %v\n", err)
+			return exitError
+		}
+		for _, e := range res.Problems {
+			if e.Severity == "error" || (*strict && e.Severity == "warning") {
+				code = exitProblems
+			}
+		}
+		if *output == "json" {
+			errs := make([]types.ValidationError, len(res.Problems))
+			for i, p := range res.Problems {
+				errs[i] = types.ValidationError(p)
+			}
+			results = append(results, fileResult{File: in.display(), IsValid: res.Valid, Ignored: res.Ignored, Errors: errs, Explanation: res.Explanation, SuggestedFixes: res.Suggestions})
+			continue
+		}
+		for _, e := range res.Problems {
+			fmt.Fprintf(stdout, "%s:%d:%d: %s: %s (%s)\n", in.display(), e.Line, e.Column, e.Severity, e.Message, e.Type)
+		}
+	}
+
+	if *output == "json" {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			fmt.Fprintf(stderr, "devformat validate: %v\n", err)
+			return exitError
+		}
+	}
+	return code
+}
+
+// dirEnv returns the content of the .env file in dir, as docker compose reads
+// it next to the compose file, or "" when there is none. Files are read once
+// per directory.
+func dirEnv(cache map[string]string, dir string) (string, error) {
+	if env, ok := cache[dir]; ok {
+		return env, nil
+	}
+	data, err := os.ReadFile(filepath.Join(dir, ".env"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+	cache[dir] = string(data)
+	return cache[dir], nil
+}
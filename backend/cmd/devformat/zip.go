@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"devformat/backend/handlers"
+	"devformat/backend/internal/types"
+)
+
+func runZip(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("zip", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	mainFile := fs.String("main", "", "main.tf file (required)")
+	varsFile := fs.String("variables", "", "variables.tf file")
+	outsFile := fs.String("outputs", "", "outputs.tf file")
+	tfvarsFile := fs.String("tfvars", "", "terraform.tfvars file")
+	name := fs.String("name", "", "module name used for the archive file name")
+	output := fs.String("o", "", "output file, or \"-\" for stdout (default <name>.zip)")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if *mainFile == "" {
+		fmt.Fprintln(stderr, "devformat zip: --main is required")
+		return exitError
+	}
+
+	var req types.FormatRequest
+	for _, f := range []struct {
+		path string
+		dst  *string
+	}{{*mainFile, &req.Main}, {*varsFile, &req.Vars}, {*outsFile, &req.Outs}, {*tfvarsFile, &req.Tfvars}} {
+		if f.path == "" {
+			continue
+		}
+		data, err := os.ReadFile(f.path)
+		if err != nil {
+			fmt.Fprintf(stderr, "devformat zip: %v\n", err)
+			return exitError
+		}
+		*f.dst = string(data)
+	}
+	req.Name = *name
+
+	data, err := handlers.BuildTerraformZip(req)
+	if err != nil {
+		fmt.Fprintf(stderr, "devformat zip: %v\n", err)
+		return exitProblems
+	}
+
+	if *output == "-" {
+		if _, err := stdout.Write(data); err != nil {
+			fmt.Fprintf(stderr, "devformat zip: %v\n", err)
+			return exitError
+		}
+		return exitOK
+	}
+	path := *output
+	if path == "" {
+		path = handlers.ZipName(*name)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		fmt.Fprintf(stderr, "devformat zip: %v\n", err)
+		return exitError
+	}
+	fmt.Fprintf(stderr, "wrote %s\n", path)
+	return exitOK
+}
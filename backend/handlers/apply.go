@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"devformat/backend/internal/types"
+	"devformat/backend/pkg/devformat"
+)
+
+// ApplySuggestionHandler splices a suggestion into the original content,
+// re-validates the result and returns it with a unified diff.
+func ApplySuggestionHandler(c *gin.Context) {
+	var req types.ApplySuggestionRequest
+	// Enforce maximum payload size to avoid resource exhaustion
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, getMaxPayloadBytes())
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	cfg, err := requestConfig(req.Config)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+	res, err := devformat.ApplySuggestion(c.Request.Context(), devformat.ApplyRequest{
+		Request: devformat.Request{
+			Content:           req.Content,
+			Filename:          req.Filename,
+			Schema:            req.Schema,
+			SchemaContent:     req.SchemaContent,
+			KubernetesVersion: req.KubernetesVersion,
+			Rules:             req.Rules,
+			UseAI:             req.UseAI,
+			Values:            req.Values,
+			Env:               req.Env,
+			Fixes:             req.Fixes,
+			Indent:            req.Indent,
+			DuplicateKeys:     req.DuplicateKeys,
+			Config:            cfg,
+		},
+		SuggestionID: req.SuggestionID,
+		StartLine:    req.StartLine,
+		EndLine:      req.EndLine,
+		Replacement:  req.Replacement,
+	})
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, devformat.ErrSuggestionNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.ApplySuggestionResponse{
+		APIVersion:     types.APIVersion,
+		Content:        res.Content,
+		Diff:           res.Diff,
+		Applied:        res.Applied,
+		IsValid:        res.Validation.Valid,
+		Errors:         validationErrors(res.Validation.Problems),
+		SuggestedFixes: res.Validation.Suggestions,
+		Explanation:    res.Validation.Explanation,
+	})
+}
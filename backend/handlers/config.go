@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"devformat/backend/internal/config"
+)
+
+var (
+	serverConfigOnce sync.Once
+	serverConfigVal  *config.Config
+)
+
+// serverConfig returns the configuration loaded at first use from the file
+// named by DEVFORMAT_CONFIG, or from .devformat.yaml in the working directory.
+// A missing file yields a nil config; an invalid one is logged and ignored.
+func serverConfig() *config.Config {
+	serverConfigOnce.Do(func() {
+		path := os.Getenv("DEVFORMAT_CONFIG")
+		if path == "" {
+			if _, err := os.Stat(config.FileName); err != nil {
+				return
+			}
+			path = config.FileName
+		}
+		cfg, err := config.Load(path)
+		if err != nil {
+			log.Printf("config: ignoring %s: %v", path, err)
+			return
+		}
+		serverConfigVal = cfg
+	})
+	return serverConfigVal
+}
+
+// requestConfig returns the configuration for a request: inline content sent
+// by the client takes precedence over the server's configuration file.
+func requestConfig(inline string) (*config.Config, error) {
+	if strings.TrimSpace(inline) != "" {
+		return config.Parse([]byte(inline))
+	}
+	return serverConfig(), nil
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"devformat/backend/internal/types"
+	"devformat/backend/pkg/devformat"
+)
+
+// ConvertHandler translates content between YAML, JSON and TOML.
+func ConvertHandler(c *gin.Context) {
+	var req types.ConvertRequest
+	// Enforce maximum payload size to avoid resource exhaustion
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, getMaxPayloadBytes())
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	res, err := devformat.Convert(c.Request.Context(), devformat.ConvertRequest{
+		Content:  req.Content,
+		Filename: req.Filename,
+		From:     req.From,
+		To:       req.To,
+		Indent:   req.Indent,
+		NDJSON:   req.NDJSON,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.ConvertResponse{
+		APIVersion: types.APIVersion,
+		Content:    res.Content,
+		From:       res.From,
+		To:         res.To,
+		Warnings:   res.Warnings,
+	})
+}
@@ -4,9 +4,11 @@ import (
 	"archive/zip"
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
+	"log"
 	"net/http"
 	"os"
 	"os/exec"
@@ -15,18 +17,12 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
-)
 
-type FormatRequest struct {
-	Main   string `json:"main" binding:"required"`
-	Vars   string `json:"variables"`
-	Outs   string `json:"outputs"`
-	Tfvars string `json:"tfvars"`
-	Name   string `json:"name"`
-}
+	"devformat/backend/internal/types"
+)
 
 func FormatAndZipHandler(c *gin.Context) {
-	var req FormatRequest
+	var req types.FormatRequest
 	// Enforce maximum payload size to avoid resource exhaustion
 	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, getMaxPayloadBytes())
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -34,11 +30,33 @@ func FormatAndZipHandler(c *gin.Context) {
 		return
 	}
 
-	tmpDir, err := os.MkdirTemp("", "tfgen-")
+	data, err := BuildTerraformZip(req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create temp dir"})
+		if errors.Is(err, ErrTerraformTimeout) {
+			c.JSON(http.StatusGatewayTimeout, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", ZipName(req.Name)))
+	c.Data(http.StatusOK, "application/zip", data)
+}
+
+// ErrTerraformTimeout is returned by BuildTerraformZip when terraform fmt
+// does not finish in time.
+var ErrTerraformTimeout = errors.New("terraform fmt timed out")
+
+// BuildTerraformZip writes the module files to a temporary directory, runs
+// terraform fmt over them when the binary is available and returns the zip
+// archive. It is shared by FormatAndZipHandler and the devformat CLI.
+func BuildTerraformZip(req types.FormatRequest) ([]byte, error) {
+	tmpDir, err := os.MkdirTemp("", "tfgen-")
+	if err != nil {
+		return nil, errors.New("failed to create temp dir")
+	}
 	defer os.RemoveAll(tmpDir)
 
 	// write files
@@ -53,14 +71,13 @@ func FormatAndZipHandler(c *gin.Context) {
 			continue
 		}
 		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to write files"})
-			return
+			return nil, errors.New("failed to write files")
 		}
 	}
 
 	// run terraform fmt if terraform binary exists
 	if _, err := exec.LookPath("terraform"); err == nil {
-		fmt.Printf("Running terraform fmt in directory: %s\n", tmpDir)
+		log.Printf("Running terraform fmt in directory: %s", tmpDir)
 		// run with a timeout context to avoid hanging processes
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
@@ -69,19 +86,17 @@ func FormatAndZipHandler(c *gin.Context) {
 
 		// Capture output for debugging
 		output, err := cmd.CombinedOutput()
-		fmt.Printf("Terraform fmt output: %s\n", string(output))
+		log.Printf("Terraform fmt output: %s", string(output))
 
 		if err != nil {
 			// If context deadline exceeded, return a clear error
 			if ctx.Err() == context.DeadlineExceeded {
-				c.JSON(http.StatusGatewayTimeout, gin.H{"error": "terraform fmt timed out"})
-				return
+				return nil, ErrTerraformTimeout
 			}
-			fmt.Printf("Terraform fmt error: %v\n", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("terraform fmt failed: %v - %s", err, string(output))})
-			return
+			log.Printf("Terraform fmt error: %v", err)
+			return nil, fmt.Errorf("terraform fmt failed: %v - %s", err, string(output))
 		}
-		fmt.Println("Terraform fmt completed successfully")
+		log.Println("Terraform fmt completed successfully")
 	} else {
 		// terraform not present; include a NOTICE file in the zip to inform user
 		notice := "NOTICE: terraform binary not found in backend container; files are included unformatted. Install terraform in the backend image to enable formatting.\n"
@@ -119,27 +134,25 @@ func FormatAndZipHandler(c *gin.Context) {
 		return nil
 	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to zip files", "details": err.Error()})
-		return
+		return nil, fmt.Errorf("failed to zip files: %w", err)
 	}
 	if err := zw.Close(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to finalize zip"})
-		return
+		return nil, errors.New("failed to finalize zip")
 	}
+	return buf.Bytes(), nil
+}
 
-	// Sanitize requested name to avoid header injection or path issues.
-	zipName := "module.zip"
-	if req.Name != "" {
-		// allow letters, numbers, '-', '_' and '.' only; replace others with '-'
-		re := regexp.MustCompile(`[^A-Za-z0-9._-]`)
-		safe := re.ReplaceAllString(req.Name, "-")
-		if safe == "" {
-			safe = "module"
-		}
-		zipName = fmt.Sprintf("%s.zip", safe)
+// ZipName sanitizes a requested module name into the archive file name, to
+// avoid header injection or path issues.
+func ZipName(name string) string {
+	if name == "" {
+		return "module.zip"
 	}
-
-	c.Header("Content-Type", "application/zip")
-	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", zipName))
-	c.Data(http.StatusOK, "application/zip", buf.Bytes())
+	// allow letters, numbers, '-', '_' and '.' only; replace others with '-'
+	re := regexp.MustCompile(`[^A-Za-z0-9._-]`)
+	safe := re.ReplaceAllString(name, "-")
+	if safe == "" {
+		safe = "module"
+	}
+	return fmt.Sprintf("%s.zip", safe)
 }
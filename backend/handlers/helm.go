@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"devformat/backend/internal/types"
+	"devformat/backend/pkg/devformat"
+)
+
+// HelmLintHandler lints an uploaded chart archive and reports problems per
+// chart file.
+func HelmLintHandler(c *gin.Context) {
+	var req types.HelmLintRequest
+	// Enforce maximum payload size to avoid resource exhaustion
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, getMaxPayloadBytes())
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	res, err := devformat.LintChart(c.Request.Context(), devformat.ChartRequest{
+		Archive:           req.Archive,
+		KubernetesVersion: req.KubernetesVersion,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.HelmLintResponse{
+		APIVersion: types.APIVersion,
+		IsValid:    res.Valid,
+		Chart:      res.Name,
+		Version:    res.Version,
+		Files:      fileErrors(res.Files),
+	})
+}
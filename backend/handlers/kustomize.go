@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"devformat/backend/internal/types"
+	"devformat/backend/pkg/devformat"
+)
+
+// KustomizeHandler builds an uploaded kustomization and validates the
+// output, reporting problems in the files that caused them.
+func KustomizeHandler(c *gin.Context) {
+	var req types.KustomizeRequest
+	// Enforce maximum payload size to avoid resource exhaustion
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, getMaxPayloadBytes())
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	res, err := devformat.BuildKustomization(c.Request.Context(), devformat.KustomizeRequest{
+		Archive:           req.Archive,
+		Path:              req.Path,
+		KubernetesVersion: req.KubernetesVersion,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.KustomizeResponse{
+		APIVersion: types.APIVersion,
+		IsValid:    res.Valid,
+		Content:    res.Content,
+		Files:      fileErrors(res.Files),
+	})
+}
@@ -0,0 +1,14 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"devformat/backend/internal/openapi"
+)
+
+// OpenAPIHandler serves the OpenAPI document describing the JSON contract.
+func OpenAPIHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, openapi.Document())
+}
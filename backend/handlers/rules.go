@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"devformat/backend/internal/lint"
+	"devformat/backend/internal/types"
+)
+
+// RulesHandler lists the registered lint rules so clients can offer them for
+// selection in ValidateRequest.Rules.
+func RulesHandler(c *gin.Context) {
+	rules := []types.RuleInfo{}
+	for _, r := range lint.Rules() {
+		rules = append(rules, types.RuleInfo{ID: r.ID, Severity: r.Severity, Description: r.Description})
+	}
+	c.JSON(http.StatusOK, types.RulesResponse{APIVersion: types.APIVersion, Rules: rules})
+}
@@ -0,0 +1,525 @@
+// Package actions validates GitHub Actions workflow files: the bundled
+// subset of the workflow schema, then what GitHub checks when it loads a
+// workflow, such as jobs needing undefined jobs, needs cycles, the syntax
+// and contexts of ${{ }} expressions, the format of uses references and
+// the size of matrices. Actions referenced by tag or branch are reported
+// with a recommendation to pin a full commit SHA.
+package actions
+
+import (
+	_ "embed"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"devformat/backend/internal/schema"
+)
+
+//go:embed schema.yaml
+var schemaYAML []byte
+
+var (
+	specOnce sync.Once
+	spec     *schema.Schema
+)
+
+// MaxMatrixJobs is the number of jobs a matrix may generate per workflow run.
+const MaxMatrixJobs = 256
+
+// Issue is a problem found by Check. Lines and columns are 1-based and
+// relative to the document.
+type Issue struct {
+	Line     int
+	Column   int
+	Message  string
+	Severity string
+	// Path is the JSON pointer of the offending value.
+	Path string
+}
+
+// Validate checks a workflow against the bundled workflow schema.
+func Validate(node *yaml.Node) []schema.Violation {
+	specOnce.Do(func() { spec = schema.MustCompile(schemaYAML) })
+	return spec.Validate(node)
+}
+
+// Check reports the problems of a workflow that its schema cannot express:
+// needs naming undefined jobs or forming a cycle, steps without or with
+// both uses and run, repeated step IDs, malformed uses references and
+// actions not pinned to a commit SHA, matrices generating more than
+// MaxMatrixJobs jobs, and invalid ${{ }} expressions, including references
+// to unknown contexts, to jobs outside needs and to undefined step IDs.
+func Check(node *yaml.Node) []Issue {
+	root := node
+	if root != nil && root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		root = root.Content[0]
+	}
+	if root == nil || root.Kind != yaml.MappingNode {
+		return nil
+	}
+	c := &checker{}
+	var jobs []schema.Member
+	if j := member(root, "jobs"); j != nil {
+		jobs = schema.Members(j)
+	}
+	c.jobs = map[string]bool{}
+	for _, j := range jobs {
+		c.jobs[j.Name] = true
+	}
+
+	for _, m := range schema.Members(root) {
+		if m.Name != "jobs" {
+			c.expressions(m.Value, "/"+escape(m.Name), nil, false)
+		}
+	}
+	for _, j := range jobs {
+		c.job(j)
+	}
+	c.cycles(jobs)
+
+	sort.SliceStable(c.issues, func(i, j int) bool {
+		if c.issues[i].Line != c.issues[j].Line {
+			return c.issues[i].Line < c.issues[j].Line
+		}
+		return c.issues[i].Column < c.issues[j].Column
+	})
+	return c.issues
+}
+
+type checker struct {
+	jobs   map[string]bool
+	issues []Issue
+}
+
+func (c *checker) add(line, column int, ptr, severity, format string, args ...any) {
+	c.issues = append(c.issues, Issue{Line: line, Column: column, Message: fmt.Sprintf(format, args...), Severity: severity, Path: ptr})
+}
+
+func (c *checker) addAt(n *yaml.Node, ptr, severity, format string, args ...any) {
+	c.add(n.Line, n.Column, ptr, severity, format, args...)
+}
+
+// jobScope is what expressions inside a job may refer to.
+type jobScope struct {
+	name  string
+	needs map[string]bool
+	steps map[string]bool
+}
+
+// job checks one job: its needs, steps, uses and matrix, and the
+// expressions anywhere in it.
+func (c *checker) job(j schema.Member) {
+	ptr := pointer("jobs", j.Name)
+	scope := &jobScope{name: j.Name, needs: map[string]bool{}, steps: map[string]bool{}}
+	if n := member(j.Value, "needs"); n != nil {
+		for _, ref := range references(n, ptr+"/needs") {
+			scope.needs[ref.name] = true
+			if !c.jobs[ref.name] {
+				c.addAt(ref.node, ref.ptr, "error", "job %q needs undefined job %q", j.Name, ref.name)
+			}
+		}
+	}
+	if u := member(j.Value, "uses"); u != nil && u.Kind == yaml.ScalarNode {
+		c.workflowRef(u, ptr+"/uses")
+	}
+	if s := member(j.Value, "strategy"); s != nil {
+		if m := member(s, "matrix"); m != nil && m.Kind == yaml.MappingNode {
+			c.matrix(j.Name, m, ptr+"/strategy/matrix")
+		}
+	}
+
+	if steps := member(j.Value, "steps"); steps != nil && steps.Kind == yaml.SequenceNode {
+		ids := map[string]int{}
+		for i, step := range steps.Content {
+			sptr := ptr + "/steps/" + strconv.Itoa(i)
+			if step.Kind != yaml.MappingNode {
+				continue
+			}
+			uses, run := member(step, "uses"), member(step, "run")
+			switch {
+			case uses != nil && run != nil:
+				c.addAt(step, sptr, "error", "step %d of job %q sets both uses and run", i+1, j.Name)
+			case uses == nil && run == nil:
+				c.addAt(step, sptr, "error", "step %d of job %q has neither uses nor run", i+1, j.Name)
+			case uses != nil && uses.Kind == yaml.ScalarNode:
+				c.actionRef(uses, sptr+"/uses")
+			}
+			if id := member(step, "id"); id != nil && id.Kind == yaml.ScalarNode {
+				if first, ok := ids[id.Value]; ok {
+					c.addAt(id, sptr+"/id", "error", "step ID %q of job %q is already used by step %d", id.Value, j.Name, first)
+				} else {
+					ids[id.Value] = i + 1
+				}
+				scope.steps[id.Value] = true
+			}
+		}
+	}
+
+	for _, m := range schema.Members(j.Value) {
+		c.expressions(m.Value, ptr+"/"+escape(m.Name), scope, m.Name == "if")
+	}
+}
+
+// cycles reports every needs cycle once, at the entry that closes it.
+func (c *checker) cycles(jobs []schema.Member) {
+	needs := map[string][]reference{}
+	for _, j := range jobs {
+		if n := member(j.Value, "needs"); n != nil {
+			needs[j.Name] = references(n, pointer("jobs", j.Name, "needs"))
+		}
+	}
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := map[string]int{}
+	reported := map[string]bool{}
+	var stack []string
+	var visit func(name string)
+	visit = func(name string) {
+		state[name] = visiting
+		stack = append(stack, name)
+		for _, ref := range needs[name] {
+			switch state[ref.name] {
+			case visiting:
+				var cycle []string
+				for i := len(stack) - 1; i >= 0; i-- {
+					if stack[i] == ref.name {
+						cycle = append(cycle, stack[i:]...)
+						break
+					}
+				}
+				members := append([]string(nil), cycle...)
+				sort.Strings(members)
+				if key := strings.Join(members, ","); !reported[key] {
+					reported[key] = true
+					c.addAt(ref.node, ref.ptr, "error", "circular needs: %s -> %s", strings.Join(cycle, " -> "), ref.name)
+				}
+			case 0:
+				if c.jobs[ref.name] {
+					visit(ref.name)
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[name] = done
+	}
+	for _, j := range jobs {
+		if state[j.Name] == 0 {
+			visit(j.Name)
+		}
+	}
+}
+
+var (
+	actionRe   = regexp.MustCompile(`^[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+(/[^@\s]+)?@[^@\s]+$`)
+	workflowRe = regexp.MustCompile(`^[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+/\.github/workflows/[^/@\s]+\.ya?ml@[^@\s]+$`)
+	localRe    = regexp.MustCompile(`^\./\.github/workflows/[^/@\s]+\.ya?ml$`)
+	shaRe      = regexp.MustCompile(`^[0-9a-f]{40}$`)
+	shortShaRe = regexp.MustCompile(`^[0-9a-f]{7,39}$`)
+)
+
+// actionRef checks the uses of a step: "owner/repo[/path]@ref", a local
+// "./path" or "docker://image".
+func (c *checker) actionRef(n *yaml.Node, ptr string) {
+	uses := n.Value
+	switch {
+	case strings.Contains(uses, "${{"):
+		c.addAt(n, ptr, "error", "uses %q cannot contain expressions", uses)
+	case strings.HasPrefix(uses, "./"):
+	case strings.HasPrefix(uses, "docker://"):
+		if strings.TrimPrefix(uses, "docker://") == "" {
+			c.addAt(n, ptr, "error", "uses %q names no image", uses)
+		}
+	case !strings.Contains(uses, "@"):
+		c.addAt(n, ptr, "error", "action %q has no version; use owner/repo@ref", uses)
+	case !actionRe.MatchString(uses):
+		c.addAt(n, ptr, "error", "uses %q is not owner/repo[/path]@ref, ./path or docker://image", uses)
+	default:
+		c.pinned(n, ptr, uses)
+	}
+}
+
+// workflowRef checks the uses of a job calling a reusable workflow.
+func (c *checker) workflowRef(n *yaml.Node, ptr string) {
+	uses := n.Value
+	switch {
+	case strings.Contains(uses, "${{"):
+		c.addAt(n, ptr, "error", "uses %q cannot contain expressions", uses)
+	case localRe.MatchString(uses):
+	case !workflowRe.MatchString(uses):
+		c.addAt(n, ptr, "error", "reusable workflow %q is not owner/repo/.github/workflows/file.yml@ref or ./.github/workflows/file.yml", uses)
+	default:
+		c.pinned(n, ptr, uses)
+	}
+}
+
+// pinned recommends referring to a remote action or workflow by full
+// commit SHA: tags and branches can be moved to different code.
+func (c *checker) pinned(n *yaml.Node, ptr, uses string) {
+	name, ref, _ := strings.Cut(uses, "@")
+	switch {
+	case shaRe.MatchString(ref):
+	case shortShaRe.MatchString(ref) && strings.ContainsAny(ref, "abcdef"):
+		c.addAt(n, ptr, "warning", "%s is referenced by the short SHA %q, which GitHub does not resolve; use the full 40-character commit SHA", name, ref)
+	default:
+		c.addAt(n, ptr, "info", "%s is referenced by %q, which can be moved; pin it to a full-length commit SHA", name, ref)
+	}
+}
+
+// matrix reports matrices that generate more than MaxMatrixJobs jobs, or
+// none at all. Dimensions given as expressions are evaluated at run time,
+// so such matrices are not counted.
+func (c *checker) matrix(job string, m *yaml.Node, ptr string) {
+	var keys []string
+	var values [][]*yaml.Node
+	var include, exclude []*yaml.Node
+	for _, d := range schema.Members(m) {
+		if d.Value.Kind != yaml.SequenceNode {
+			return
+		}
+		switch d.Name {
+		case "include":
+			include = d.Value.Content
+		case "exclude":
+			exclude = d.Value.Content
+		default:
+			keys = append(keys, d.Name)
+			values = append(values, d.Value.Content)
+		}
+	}
+
+	total := 0
+	if len(keys) > 0 {
+		total = 1
+		for _, v := range values {
+			total *= len(v)
+			if total > MaxMatrixJobs*256 {
+				c.addAt(m, ptr, "error", "matrix of job %q generates more than %d jobs; a matrix may generate at most %d", job, MaxMatrixJobs*256, MaxMatrixJobs)
+				return
+			}
+		}
+	}
+
+	// every combination, as the values of keys in order
+	combos := make([][]string, 0, total)
+	if total > 0 {
+		combos = append(combos, nil)
+		for _, v := range values {
+			var next [][]string
+			for _, combo := range combos {
+				for _, item := range v {
+					next = append(next, append(append([]string(nil), combo...), valueKey(item)))
+				}
+			}
+			combos = next
+		}
+	}
+	matches := func(entry *yaml.Node, combo []string) bool {
+		for _, e := range schema.Members(entry) {
+			for i, k := range keys {
+				if k == e.Name && combo[i] != valueKey(e.Value) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	kept := combos[:0]
+	for _, combo := range combos {
+		excluded := false
+		for _, e := range exclude {
+			if matches(e, combo) {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			kept = append(kept, combo)
+		}
+	}
+	count := len(kept)
+	// an include that fits no combination adds one of its own
+	for _, e := range include {
+		fits := false
+		for _, combo := range kept {
+			if matches(e, combo) {
+				fits = true
+				break
+			}
+		}
+		if !fits {
+			count++
+		}
+	}
+
+	switch {
+	case count > MaxMatrixJobs:
+		c.addAt(m, ptr, "error", "matrix of job %q generates %d jobs; a matrix may generate at most %d", job, count, MaxMatrixJobs)
+	case count == 0:
+		c.addAt(m, ptr, "error", "matrix of job %q generates no jobs", job)
+	}
+}
+
+// valueKey identifies a matrix value for comparison with include and
+// exclude entries.
+func valueKey(n *yaml.Node) string {
+	var parsed any
+	if err := n.Decode(&parsed); err != nil {
+		return n.Value
+	}
+	return fmt.Sprintf("%#v", parsed)
+}
+
+// expressions checks the ${{ }} expressions in the values below n. cond
+// marks the value of an if, which is an expression even without ${{ }}.
+// scope is the job the values belong to, nil at the workflow level.
+func (c *checker) expressions(n *yaml.Node, ptr string, scope *jobScope, cond bool) {
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key := n.Content[i].Value
+			c.expressions(n.Content[i+1], ptr+"/"+escape(key), scope, key == "if")
+		}
+	case yaml.SequenceNode:
+		for i, item := range n.Content {
+			c.expressions(item, ptr+"/"+strconv.Itoa(i), scope, false)
+		}
+	case yaml.ScalarNode:
+		if n.ShortTag() != "!!str" {
+			return
+		}
+		if cond && !strings.Contains(n.Value, "${{") {
+			c.expression(n, ptr, scope, n.Value, 0)
+			return
+		}
+		for off := 0; ; {
+			start := strings.Index(n.Value[off:], "${{")
+			if start < 0 {
+				return
+			}
+			start += off
+			end := closing(n.Value, start+3)
+			if end < 0 {
+				line, col := position(n, start)
+				c.add(line, col, ptr, "error", "expression %q is not closed with }}", n.Value[start:])
+				return
+			}
+			c.expression(n, ptr, scope, n.Value[start+3:end], start+3)
+			off = end + 2
+		}
+	}
+}
+
+// expression checks one expression found at byte offset off of n's value.
+func (c *checker) expression(n *yaml.Node, ptr string, scope *jobScope, expr string, off int) {
+	refs, err := parseExpression(expr)
+	if err != nil {
+		at := off
+		if e, ok := err.(*exprError); ok {
+			at += e.offset
+		}
+		line, col := position(n, at)
+		c.add(line, col, ptr, "error", "invalid expression %q: %v", strings.TrimSpace(expr), err)
+		return
+	}
+	if scope == nil {
+		return
+	}
+	for _, r := range refs {
+		if r.property == "" || r.property == "*" {
+			continue
+		}
+		line, col := position(n, off+r.offset)
+		switch {
+		case r.context == "needs" && !scope.needs[r.property]:
+			c.add(line, col, ptr, "error", "needs.%s: job %q does not need job %q", r.property, scope.name, r.property)
+		case r.context == "steps" && !scope.steps[r.property]:
+			c.add(line, col, ptr, "error", "steps.%s: job %q has no step with ID %q", r.property, scope.name, r.property)
+		}
+	}
+}
+
+// closing returns the index of the "}}" ending an expression that starts
+// at i, skipping string literals, or -1.
+func closing(s string, i int) int {
+	for ; i+1 < len(s); i++ {
+		switch {
+		case s[i] == '\'':
+			j := strings.IndexByte(s[i+1:], '\'')
+			if j < 0 {
+				return -1
+			}
+			i += j + 1
+		case s[i] == '}' && s[i+1] == '}':
+			return i
+		}
+	}
+	return -1
+}
+
+// position returns the line and column of byte offset off of a scalar's
+// value. Offsets are exact on single-line scalars; in block scalars only
+// the line is.
+func position(n *yaml.Node, off int) (int, int) {
+	before := n.Value[:off]
+	switch {
+	case n.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0:
+		return n.Line + 1 + strings.Count(before, "\n"), n.Column
+	case strings.Contains(n.Value, "\n"):
+		return n.Line, n.Column
+	case n.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0:
+		return n.Line, n.Column + 1 + off
+	}
+	return n.Line, n.Column + off
+}
+
+// reference is a job name given in needs, as a string or a list item.
+type reference struct {
+	name string
+	node *yaml.Node
+	ptr  string
+}
+
+func references(n *yaml.Node, ptr string) []reference {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		return []reference{{n.Value, n, ptr}}
+	case yaml.SequenceNode:
+		var out []reference
+		for i, item := range n.Content {
+			if item.Kind == yaml.ScalarNode {
+				out = append(out, reference{item.Value, item, ptr + "/" + strconv.Itoa(i)})
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func member(n *yaml.Node, key string) *yaml.Node {
+	for _, m := range schema.Members(n) {
+		if m.Name == key {
+			return m.Value
+		}
+	}
+	return nil
+}
+
+func pointer(segs ...string) string {
+	var b strings.Builder
+	for _, s := range segs {
+		b.WriteString("/")
+		b.WriteString(escape(s))
+	}
+	return b.String()
+}
+
+func escape(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
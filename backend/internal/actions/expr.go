@@ -0,0 +1,344 @@
+package actions
+
+import (
+	"fmt"
+	"strings"
+)
+
+// contexts are the context names expressions may refer to.
+var contexts = []string{"github", "env", "vars", "job", "jobs", "steps", "runner", "secrets", "strategy", "matrix", "needs", "inputs"}
+
+// functions maps the built-in functions, in lower case, to their minimum
+// and maximum number of arguments; -1 means any number.
+var functions = map[string][2]int{
+	"contains":   {2, 2},
+	"startswith": {2, 2},
+	"endswith":   {2, 2},
+	"format":     {1, -1},
+	"join":       {1, 2},
+	"tojson":     {1, 1},
+	"fromjson":   {1, 1},
+	"hashfiles":  {1, -1},
+	"success":    {0, 0},
+	"always":     {0, 0},
+	"cancelled":  {0, 0},
+	"failure":    {0, 0},
+	"case":       {3, -1},
+}
+
+// exprError is an error in an expression at the byte offset of the
+// offending token.
+type exprError struct {
+	offset  int
+	message string
+}
+
+func (e *exprError) Error() string {
+	return e.message
+}
+
+// contextRef is a use of a context in an expression: "needs.build.result"
+// refers to context "needs" with property "build".
+type contextRef struct {
+	context  string
+	property string
+	offset   int
+}
+
+// parseExpression checks the syntax of an expression, the text between
+// "${{" and "}}", and returns the contexts it refers to. Unknown contexts
+// and functions and calls with the wrong number of arguments are errors.
+func parseExpression(src string) ([]contextRef, error) {
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{toks: toks}
+	if p.peek().kind == tokEOF {
+		return nil, &exprError{0, "empty expression"}
+	}
+	if err := p.or(); err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.unexpected(t)
+	}
+	return p.refs, nil
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokPunct
+)
+
+type token struct {
+	kind   tokenKind
+	text   string
+	offset int
+}
+
+// punctuation lists the operators and delimiters, longest first.
+var punctuation = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")", "[", "]", ".", ",", "*"}
+
+func tokenize(src string) ([]token, error) {
+	var toks []token
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '\'':
+			j := i + 1
+			for {
+				if j >= len(src) {
+					return nil, &exprError{i, "unterminated string literal"}
+				}
+				if src[j] == '\'' {
+					if j+1 < len(src) && src[j+1] == '\'' {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			toks = append(toks, token{tokString, src[i : j+1], i})
+			i = j + 1
+		case c == '"':
+			return nil, &exprError{i, "strings in expressions use single quotes"}
+		case isDigit(c) || (c == '-' && i+1 < len(src) && isDigit(src[i+1])):
+			j := i + 1
+			for j < len(src) && (isIdentChar(src[j]) || src[j] == '.' || ((src[j] == '+' || src[j] == '-') && (src[j-1] == 'e' || src[j-1] == 'E'))) {
+				j++
+			}
+			toks = append(toks, token{tokNumber, src[i:j], i})
+			i = j
+		case isIdentStart(c):
+			j := i + 1
+			for j < len(src) && (isIdentChar(src[j]) || src[j] == '-') {
+				j++
+			}
+			toks = append(toks, token{tokIdent, src[i:j], i})
+			i = j
+		default:
+			matched := false
+			for _, p := range punctuation {
+				if strings.HasPrefix(src[i:], p) {
+					toks = append(toks, token{tokPunct, p, i})
+					i += len(p)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, &exprError{i, fmt.Sprintf("unexpected character %q", c)}
+			}
+		}
+	}
+	return append(toks, token{tokEOF, "", len(src)}), nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || isDigit(c)
+}
+
+// exprParser is a recursive descent parser over the expression grammar,
+// from the loosest operator (||) to property access.
+type exprParser struct {
+	toks []token
+	pos  int
+	refs []contextRef
+}
+
+func (p *exprParser) peek() token {
+	return p.toks[p.pos]
+}
+
+func (p *exprParser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *exprParser) accept(ops ...string) bool {
+	t := p.peek()
+	if t.kind != tokPunct {
+		return false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			p.pos++
+			return true
+		}
+	}
+	return false
+}
+
+func (p *exprParser) unexpected(t token) error {
+	if t.kind == tokEOF {
+		return &exprError{t.offset, "unexpected end of expression"}
+	}
+	return &exprError{t.offset, fmt.Sprintf("unexpected %q", t.text)}
+}
+
+// binary parses operands joined by any of ops.
+func (p *exprParser) binary(operand func() error, ops ...string) error {
+	if err := operand(); err != nil {
+		return err
+	}
+	for p.accept(ops...) {
+		if err := operand(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *exprParser) or() error {
+	return p.binary(p.and, "||")
+}
+
+func (p *exprParser) and() error {
+	return p.binary(p.equality, "&&")
+}
+
+func (p *exprParser) equality() error {
+	return p.binary(p.comparison, "==", "!=")
+}
+
+func (p *exprParser) comparison() error {
+	return p.binary(p.unary, "<", "<=", ">", ">=")
+}
+
+func (p *exprParser) unary() error {
+	if p.accept("!") {
+		return p.unary()
+	}
+	return p.postfix()
+}
+
+// postfix parses a primary value followed by property accesses, indexes
+// and filters (".*").
+func (p *exprParser) postfix() error {
+	ref, err := p.primary()
+	if err != nil {
+		return err
+	}
+	// only the first property names a job, step or secret
+	first := true
+	for {
+		switch {
+		case p.accept("."):
+			t := p.next()
+			if t.kind == tokPunct && t.text == "*" {
+				first = false
+				continue
+			}
+			if t.kind != tokIdent {
+				return p.unexpected(t)
+			}
+			if ref >= 0 && first {
+				p.refs[ref].property = t.text
+			}
+		case p.accept("["):
+			if err := p.or(); err != nil {
+				return err
+			}
+			if !p.accept("]") {
+				return p.unexpected(p.peek())
+			}
+		default:
+			return nil
+		}
+		first = false
+	}
+}
+
+// primary parses a literal, a parenthesized expression, a function call or
+// a context name. For a context it returns the index of the reference
+// recorded, so that postfix can add the property, and -1 otherwise.
+func (p *exprParser) primary() (int, error) {
+	t := p.next()
+	switch t.kind {
+	case tokString, tokNumber:
+		return -1, nil
+	case tokPunct:
+		if t.text == "(" {
+			if err := p.or(); err != nil {
+				return -1, err
+			}
+			if !p.accept(")") {
+				return -1, p.unexpected(p.peek())
+			}
+			return -1, nil
+		}
+		return -1, p.unexpected(t)
+	case tokIdent:
+		switch t.text {
+		case "true", "false", "null", "NaN", "Infinity":
+			return -1, nil
+		}
+		if p.accept("(") {
+			return -1, p.call(t)
+		}
+		name := strings.ToLower(t.text)
+		for _, c := range contexts {
+			if c == name {
+				p.refs = append(p.refs, contextRef{context: name, offset: t.offset})
+				return len(p.refs) - 1, nil
+			}
+		}
+		return -1, &exprError{t.offset, fmt.Sprintf("unknown context %q; available contexts are %s", t.text, strings.Join(contexts, ", "))}
+	}
+	return -1, p.unexpected(t)
+}
+
+// call parses the arguments of a function call after its "(".
+func (p *exprParser) call(name token) error {
+	arity, ok := functions[strings.ToLower(name.text)]
+	if !ok {
+		return &exprError{name.offset, fmt.Sprintf("unknown function %q", name.text)}
+	}
+	n := 0
+	if !p.accept(")") {
+		for {
+			if err := p.or(); err != nil {
+				return err
+			}
+			n++
+			if p.accept(")") {
+				break
+			}
+			if !p.accept(",") {
+				return p.unexpected(p.peek())
+			}
+		}
+	}
+	if n < arity[0] || (arity[1] >= 0 && n > arity[1]) {
+		want := fmt.Sprint(arity[0])
+		switch {
+		case arity[1] < 0:
+			want = fmt.Sprintf("at least %d", arity[0])
+		case arity[1] != arity[0]:
+			want = fmt.Sprintf("%d to %d", arity[0], arity[1])
+		}
+		return &exprError{name.offset, fmt.Sprintf("%s takes %s arguments, got %d", name.text, want, n)}
+	}
+	return nil
+}
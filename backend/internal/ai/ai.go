@@ -9,12 +9,14 @@ import (
 	"os"
 	"strings"
 	"time"
+
+	"devformat/backend/internal/types"
 )
 
 // CallGeminiSuggest contacts configured Gemini/Generative endpoints and
 // attempts to extract a short YAML snippet suggestion. It returns nil when
 // no suggestion could be obtained.
-func CallGeminiSuggest(content string) []map[string]any {
+func CallGeminiSuggest(content string) []types.Suggestion {
 	explicitEndpoint := strings.TrimSpace(getEnv("GEMINI_ENDPOINT", ""))
 	apiKey := strings.TrimSpace(getEnv("GEMINI_API_KEY", getEnv("GOOGLE_API_KEY", "")))
 	model := strings.TrimSpace(getEnv("GEMINI_MODEL", "gemini-2.5-flash"))
@@ -71,7 +73,7 @@ func CallGeminiSuggest(content string) []map[string]any {
 					break
 				}
 			}
-			return []map[string]any{{"shortDescription": "AI suggested fix (Gemini)", "confidence": "high", "fixedSnippet": suggested, "startLine": first, "endLine": first + len(strings.Split(suggested, "\n")) - 1}}
+			return []types.Suggestion{aiSuggestion(first, suggested)}
 		}
 	}
 
@@ -127,7 +129,7 @@ func CallGeminiSuggest(content string) []map[string]any {
 											break
 										}
 									}
-									return []map[string]any{{"shortDescription": "AI suggested fix (Gemini)", "confidence": "high", "fixedSnippet": suggested, "startLine": firstLine, "endLine": firstLine + len(strings.Split(suggested, "\n")) - 1}}
+									return []types.Suggestion{aiSuggestion(firstLine, suggested)}
 								}
 							}
 						}
@@ -143,7 +145,7 @@ func CallGeminiSuggest(content string) []map[string]any {
 								break
 							}
 						}
-						return []map[string]any{{"shortDescription": "AI suggested fix (Gemini)", "confidence": "high", "fixedSnippet": suggested, "startLine": first, "endLine": first + len(strings.Split(suggested, "\n")) - 1}}
+						return []types.Suggestion{aiSuggestion(first, suggested)}
 					}
 					if txt, ok := m["text"].(string); ok && strings.TrimSpace(txt) != "" {
 						suggested := strings.TrimSpace(txt)
@@ -156,7 +158,7 @@ func CallGeminiSuggest(content string) []map[string]any {
 								break
 							}
 						}
-						return []map[string]any{{"shortDescription": "AI suggested fix (Gemini)", "confidence": "high", "fixedSnippet": suggested, "startLine": first, "endLine": first + len(strings.Split(suggested, "\n")) - 1}}
+						return []types.Suggestion{aiSuggestion(first, suggested)}
 					}
 				}
 			}
@@ -172,7 +174,7 @@ func CallGeminiSuggest(content string) []map[string]any {
 						break
 					}
 				}
-				return []map[string]any{{"shortDescription": "AI suggested fix (Gemini)", "confidence": "high", "fixedSnippet": suggested, "startLine": first, "endLine": first + len(strings.Split(suggested, "\n")) - 1}}
+				return []types.Suggestion{aiSuggestion(first, suggested)}
 			}
 		}
 	}
@@ -180,6 +182,12 @@ func CallGeminiSuggest(content string) []map[string]any {
 	return nil
 }
 
+// aiSuggestion wraps a snippet returned by the model, placed at the line
+// where its first line was found in the input.
+func aiSuggestion(first int, suggested string) types.Suggestion {
+	return types.NewSuggestion(types.SourceAI, "AI suggested fix (Gemini)", types.ConfidenceHigh, first, first+len(strings.Split(suggested, "\n"))-1, suggested)
+}
+
 // getEnv reads environment variable or returns default
 func getEnv(k, def string) string {
 	if v, ok := os.LookupEnv(k); ok {
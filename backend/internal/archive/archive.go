@@ -0,0 +1,127 @@
+// Package archive unpacks the .tgz and .zip uploads of charts and
+// kustomizations into memory, with limits on what a small upload may expand
+// into and without letting entries escape the archive root.
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// Limits on what Unpack reads, so a small upload cannot expand into an
+// arbitrarily large tree.
+const (
+	MaxFiles = 5000
+	MaxBytes = 32 << 20
+)
+
+// Unpack returns the regular files of a gzipped tar or zip archive by their
+// cleaned slash-separated paths, e.g. "templates/service.yaml".
+func Unpack(data []byte) (map[string]string, error) {
+	files := map[string]string{}
+	total := 0
+	add := func(name string, r io.Reader) error {
+		name = path.Clean(strings.TrimPrefix(strings.ReplaceAll(name, "\\", "/"), "/"))
+		if name == "." || name == ".." || strings.HasPrefix(name, "../") {
+			return fmt.Errorf("archive entry %q leaves the archive", name)
+		}
+		if len(files) >= MaxFiles {
+			return fmt.Errorf("archive has more than %d files", MaxFiles)
+		}
+		content, err := io.ReadAll(io.LimitReader(r, int64(MaxBytes-total)+1))
+		if err != nil {
+			return err
+		}
+		total += len(content)
+		if total > MaxBytes {
+			return fmt.Errorf("archive expands to more than %d MiB", MaxBytes>>20)
+		}
+		files[name] = string(content)
+		return nil
+	}
+
+	switch {
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		tr := tar.NewReader(gz)
+		for {
+			hdr, err := tr.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			if hdr.Typeflag != tar.TypeReg {
+				continue
+			}
+			if err := add(hdr.Name, tr); err != nil {
+				return nil, err
+			}
+		}
+	case bytes.HasPrefix(data, []byte("PK")):
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range zr.File {
+			if f.FileInfo().IsDir() {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			err = add(f.Name, rc)
+			rc.Close()
+			if err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, errors.New("not a .tgz or .zip archive")
+	}
+	return files, nil
+}
+
+// Root returns the directory of the shallowest file with one of the given
+// base names, "" for the archive root. Ties go to the first directory in
+// name order.
+func Root(files map[string]string, names ...string) (string, bool) {
+	depth := func(dir string) int {
+		if dir == "" {
+			return -1
+		}
+		return strings.Count(dir, "/")
+	}
+	best, found := "", false
+	for name := range files {
+		match := false
+		for _, n := range names {
+			if path.Base(name) == n {
+				match = true
+			}
+		}
+		if !match {
+			continue
+		}
+		dir := path.Dir(name)
+		if dir == "." {
+			dir = ""
+		}
+		if !found || depth(dir) < depth(best) || depth(dir) == depth(best) && dir < best {
+			best, found = dir, true
+		}
+	}
+	return best, found
+}
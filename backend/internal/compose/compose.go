@@ -0,0 +1,441 @@
+// Package compose validates Docker Compose files the way docker compose
+// loads them: ${VAR} references are interpolated from a .env file, the
+// result is checked against a bundled subset of the Compose Specification
+// schema, and the project is checked for what the schema cannot express,
+// such as services using networks, volumes, secrets or configs that are not
+// defined, depends_on cycles and host ports published twice.
+package compose
+
+import (
+	_ "embed"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"devformat/backend/internal/schema"
+)
+
+//go:embed schema.yaml
+var schemaYAML []byte
+
+var (
+	specOnce sync.Once
+	spec     *schema.Schema
+)
+
+// Issue is a problem found by Check or Interpolate. Lines and columns are
+// 1-based and relative to the document.
+type Issue struct {
+	Line     int
+	Column   int
+	Message  string
+	Severity string
+	// Path is the JSON pointer of the offending value.
+	Path string
+}
+
+// Validate checks a compose file against the bundled Compose Specification
+// schema. Interpolate the file first: values are checked as docker compose
+// sees them.
+func Validate(node *yaml.Node) []schema.Violation {
+	specOnce.Do(func() { spec = schema.MustCompile(schemaYAML) })
+	return spec.Validate(node)
+}
+
+// Check reports the problems of a compose file that its schema cannot
+// express: references to undefined networks, volumes, secrets, configs and
+// services, circular depends_on, host ports and container names used by
+// more than one service, and the obsolete top-level version.
+func Check(node *yaml.Node) []Issue {
+	root := node
+	if root != nil && root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		root = root.Content[0]
+	}
+	if root == nil || root.Kind != yaml.MappingNode {
+		return nil
+	}
+	c := &checker{
+		networks: names(nil),
+		volumes:  names(nil),
+		secrets:  names(nil),
+		configs:  names(nil),
+	}
+	var services []schema.Member
+	for _, m := range schema.Members(root) {
+		switch m.Name {
+		case "version":
+			c.add(m.Key, "/version", "info", "the top-level version is obsolete and ignored by docker compose")
+		case "services":
+			services = schema.Members(m.Value)
+		case "networks":
+			c.networks = names(m.Value)
+		case "volumes":
+			c.volumes = names(m.Value)
+		case "secrets":
+			c.secrets = names(m.Value)
+		case "configs":
+			c.configs = names(m.Value)
+		}
+	}
+	c.services = names(nil)
+	for _, s := range services {
+		c.services[s.Name] = true
+	}
+
+	containers := map[string]string{}
+	for _, s := range services {
+		c.service(s)
+		if n := member(s.Value, "container_name"); n != nil && n.Kind == yaml.ScalarNode {
+			if other, ok := containers[n.Value]; ok {
+				c.add(n, pointer("services", s.Name, "container_name"), "error", "container name %q of service %q is already used by service %q", n.Value, s.Name, other)
+			} else {
+				containers[n.Value] = s.Name
+			}
+		}
+	}
+	c.cycles(services)
+	c.ports(services)
+
+	sort.SliceStable(c.issues, func(i, j int) bool {
+		if c.issues[i].Line != c.issues[j].Line {
+			return c.issues[i].Line < c.issues[j].Line
+		}
+		return c.issues[i].Column < c.issues[j].Column
+	})
+	return c.issues
+}
+
+type checker struct {
+	services, networks, volumes, secrets, configs map[string]bool
+	issues                                        []Issue
+}
+
+func (c *checker) add(n *yaml.Node, ptr, severity, format string, args ...any) {
+	c.issues = append(c.issues, Issue{Line: n.Line, Column: n.Column, Message: fmt.Sprintf(format, args...), Severity: severity, Path: ptr})
+}
+
+// service checks the references of one service to the project's networks,
+// volumes, secrets, configs and other services.
+func (c *checker) service(s schema.Member) {
+	for _, m := range schema.Members(s.Value) {
+		ptr := pointer("services", s.Name, m.Name)
+		switch m.Name {
+		case "networks":
+			for _, ref := range references(m.Value, ptr) {
+				if ref.name != "default" && !c.networks[ref.name] {
+					c.add(ref.node, ref.ptr, "error", "service %q uses undefined network %q", s.Name, ref.name)
+				}
+			}
+			if member(s.Value, "network_mode") != nil {
+				c.add(m.Key, ptr, "error", "service %q sets both network_mode and networks", s.Name)
+			}
+		case "network_mode":
+			if target, ok := strings.CutPrefix(m.Value.Value, "service:"); ok && !c.services[target] {
+				c.add(m.Value, ptr, "error", "network_mode of service %q refers to undefined service %q", s.Name, target)
+			}
+		case "volumes":
+			for i, v := range m.Value.Content {
+				if name, ok := namedVolume(v); ok && !c.volumes[name] {
+					c.add(v, pointer("services", s.Name, "volumes", strconv.Itoa(i)), "error", "service %q uses undefined volume %q", s.Name, name)
+				}
+			}
+		case "volumes_from":
+			for i, v := range m.Value.Content {
+				if strings.HasPrefix(v.Value, "container:") {
+					continue
+				}
+				target, _, _ := strings.Cut(strings.TrimPrefix(v.Value, "service:"), ":")
+				if !c.services[target] {
+					c.add(v, pointer("services", s.Name, "volumes_from", strconv.Itoa(i)), "error", "volumes_from of service %q refers to undefined service %q", s.Name, target)
+				}
+			}
+		case "secrets", "configs":
+			defined := c.secrets
+			if m.Name == "configs" {
+				defined = c.configs
+			}
+			for i, v := range m.Value.Content {
+				name := v.Value
+				if v.Kind == yaml.MappingNode {
+					src := member(v, "source")
+					if src == nil {
+						continue
+					}
+					name = src.Value
+				}
+				if !defined[name] {
+					c.add(v, pointer("services", s.Name, m.Name, strconv.Itoa(i)), "error", "service %q uses undefined %s %q", s.Name, strings.TrimSuffix(m.Name, "s"), name)
+				}
+			}
+		case "depends_on":
+			for _, ref := range references(m.Value, ptr) {
+				if !c.services[ref.name] {
+					c.add(ref.node, ref.ptr, "error", "service %q depends on undefined service %q", s.Name, ref.name)
+				}
+			}
+		case "links":
+			for i, v := range m.Value.Content {
+				target, _, _ := strings.Cut(v.Value, ":")
+				if !c.services[target] {
+					c.add(v, pointer("services", s.Name, "links", strconv.Itoa(i)), "error", "service %q links to undefined service %q", s.Name, target)
+				}
+			}
+		case "extends":
+			target := m.Value
+			if m.Value.Kind == yaml.MappingNode {
+				if member(m.Value, "file") != nil {
+					continue
+				}
+				if target = member(m.Value, "service"); target == nil {
+					continue
+				}
+			}
+			if !c.services[target.Value] {
+				c.add(target, ptr, "error", "service %q extends undefined service %q", s.Name, target.Value)
+			}
+		}
+	}
+}
+
+// cycles reports every depends_on cycle once, at the entry that closes it.
+func (c *checker) cycles(services []schema.Member) {
+	deps := map[string][]reference{}
+	for _, s := range services {
+		if d := member(s.Value, "depends_on"); d != nil {
+			deps[s.Name] = references(d, pointer("services", s.Name, "depends_on"))
+		}
+	}
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := map[string]int{}
+	reported := map[string]bool{}
+	var stack []string
+	var visit func(name string)
+	visit = func(name string) {
+		state[name] = visiting
+		stack = append(stack, name)
+		for _, ref := range deps[name] {
+			switch state[ref.name] {
+			case visiting:
+				var cycle []string
+				for i := len(stack) - 1; i >= 0; i-- {
+					if stack[i] == ref.name {
+						cycle = append(cycle, stack[i:]...)
+						break
+					}
+				}
+				members := append([]string(nil), cycle...)
+				sort.Strings(members)
+				if key := strings.Join(members, ","); !reported[key] {
+					reported[key] = true
+					c.add(ref.node, ref.ptr, "error", "circular depends_on: %s -> %s", strings.Join(cycle, " -> "), ref.name)
+				}
+			case 0:
+				if c.services[ref.name] {
+					visit(ref.name)
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[name] = done
+	}
+	for _, s := range services {
+		if state[s.Name] == 0 {
+			visit(s.Name)
+		}
+	}
+}
+
+// ports reports host ports published more than once, within a service or
+// across services. A port bound to all interfaces collides with the same
+// port on any address.
+func (c *checker) ports(services []schema.Member) {
+	type binding struct {
+		service, ip string
+	}
+	published := map[string][]binding{}
+	for _, s := range services {
+		list := member(s.Value, "ports")
+		if list == nil || list.Kind != yaml.SequenceNode {
+			continue
+		}
+		for i, item := range list.Content {
+			ptr := pointer("services", s.Name, "ports", strconv.Itoa(i))
+			ip, ports, protocol, err := hostPorts(item)
+			if err != nil {
+				c.add(item, ptr, "error", "invalid port of service %q: %v", s.Name, err)
+				continue
+			}
+			for _, port := range ports {
+				key := fmt.Sprintf("%d/%s", port, protocol)
+				for _, b := range published[key] {
+					if b.ip != ip && !wildcard(b.ip) && !wildcard(ip) {
+						continue
+					}
+					if b.service == s.Name {
+						c.add(item, ptr, "error", "host port %s is published twice by service %q", key, s.Name)
+					} else {
+						c.add(item, ptr, "error", "host port %s of service %q is already published by service %q", key, s.Name, b.service)
+					}
+					break
+				}
+				published[key] = append(published[key], binding{s.Name, ip})
+			}
+		}
+	}
+}
+
+func wildcard(ip string) bool {
+	return ip == "" || ip == "0.0.0.0" || ip == "::"
+}
+
+// hostPorts returns the host address, the host ports and the protocol a
+// ports entry publishes, in short ("127.0.0.1:8000-8001:80/udp") or long
+// syntax. Entries without a host port publish none.
+func hostPorts(n *yaml.Node) (ip string, ports []int, protocol string, err error) {
+	protocol = "tcp"
+	var published string
+	switch n.Kind {
+	case yaml.MappingNode:
+		if p := member(n, "published"); p != nil {
+			published = p.Value
+		}
+		if h := member(n, "host_ip"); h != nil {
+			ip = h.Value
+		}
+		if p := member(n, "protocol"); p != nil && p.Value != "" {
+			protocol = p.Value
+		}
+	case yaml.ScalarNode:
+		spec := n.Value
+		if rest, proto, ok := strings.Cut(spec, "/"); ok {
+			spec, protocol = rest, proto
+		}
+		if strings.HasPrefix(spec, "[") {
+			end := strings.Index(spec, "]:")
+			if end < 0 {
+				return "", nil, "", fmt.Errorf("%q has an unterminated IPv6 address", n.Value)
+			}
+			ip, spec = spec[1:end], spec[end+2:]
+			if strings.Count(spec, ":") != 1 {
+				return "", nil, "", fmt.Errorf("%q is not [HOST_IP:][HOST_PORT:]CONTAINER_PORT", n.Value)
+			}
+			published, _, _ = strings.Cut(spec, ":")
+			break
+		}
+		parts := strings.Split(spec, ":")
+		switch len(parts) {
+		case 1:
+		case 2:
+			published = parts[0]
+		case 3:
+			ip, published = parts[0], parts[1]
+		default:
+			return "", nil, "", fmt.Errorf("%q is not [HOST_IP:][HOST_PORT:]CONTAINER_PORT", n.Value)
+		}
+	default:
+		return "", nil, "", nil
+	}
+	if published == "" {
+		return ip, nil, protocol, nil
+	}
+	from, to, isRange := strings.Cut(published, "-")
+	if !isRange {
+		to = from
+	}
+	lo, err1 := strconv.Atoi(from)
+	hi, err2 := strconv.Atoi(to)
+	if err1 != nil || err2 != nil || lo < 1 || hi > 65535 || lo > hi {
+		return "", nil, "", fmt.Errorf("%q is not a port or port range", published)
+	}
+	for p := lo; p <= hi; p++ {
+		ports = append(ports, p)
+	}
+	return ip, ports, protocol, nil
+}
+
+// namedVolume returns the named volume a volumes entry mounts, if any.
+// Short syntax sources starting with ".", "/" or "~", or with a drive
+// letter, are host paths; a lone target is an anonymous volume.
+func namedVolume(n *yaml.Node) (string, bool) {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		source, _, ok := strings.Cut(n.Value, ":")
+		if !ok || source == "" || strings.ContainsAny(source[:1], "./~$") {
+			return "", false
+		}
+		if len(source) == 1 && len(n.Value) > 2 && (n.Value[2] == '\\' || n.Value[2] == '/') {
+			return "", false // C:\data:/data
+		}
+		return source, true
+	case yaml.MappingNode:
+		typ, src := member(n, "type"), member(n, "source")
+		if typ == nil || typ.Value != "volume" || src == nil || src.Value == "" {
+			return "", false
+		}
+		return src.Value, true
+	}
+	return "", false
+}
+
+// reference is a name given in a list ("- db") or as a mapping key
+// ("db: {condition: ...}").
+type reference struct {
+	name string
+	node *yaml.Node
+	ptr  string
+}
+
+func references(n *yaml.Node, ptr string) []reference {
+	var out []reference
+	switch n.Kind {
+	case yaml.SequenceNode:
+		for i, item := range n.Content {
+			if item.Kind == yaml.ScalarNode {
+				out = append(out, reference{item.Value, item, ptr + "/" + strconv.Itoa(i)})
+			}
+		}
+	case yaml.MappingNode:
+		for _, m := range schema.Members(n) {
+			out = append(out, reference{m.Name, m.Key, ptr + "/" + escape(m.Name)})
+		}
+	}
+	return out
+}
+
+// names returns the keys of a top-level section such as networks.
+func names(n *yaml.Node) map[string]bool {
+	out := map[string]bool{}
+	for _, m := range schema.Members(n) {
+		out[m.Name] = true
+	}
+	return out
+}
+
+func member(n *yaml.Node, key string) *yaml.Node {
+	for _, m := range schema.Members(n) {
+		if m.Name == key {
+			return m.Value
+		}
+	}
+	return nil
+}
+
+func pointer(segs ...string) string {
+	var b strings.Builder
+	for _, s := range segs {
+		b.WriteString("/")
+		b.WriteString(escape(s))
+	}
+	return b.String()
+}
+
+func escape(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
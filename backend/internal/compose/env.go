@@ -0,0 +1,84 @@
+package compose
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseEnv reads the content of a .env file: KEY=VALUE lines, optionally
+// prefixed with "export". Blank lines and lines starting with "#" are
+// skipped. Single-quoted values are taken literally, double-quoted values
+// understand \n, \t, \" and \\, and unquoted values end at " #".
+func ParseEnv(content string) (map[string]string, error) {
+	env := map[string]string{}
+	for n, raw := range strings.Split(content, "\n") {
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !validName(key) {
+			return nil, fmt.Errorf("line %d: %q is not KEY=VALUE", n+1, line)
+		}
+		value = strings.TrimSpace(value)
+		switch {
+		case value == "":
+		case value[0] == '\'':
+			end := strings.IndexByte(value[1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated quoted value of %s", n+1, key)
+			}
+			value = value[1 : end+1]
+		case value[0] == '"':
+			var b strings.Builder
+			i := 1
+			for ; i < len(value) && value[i] != '"'; i++ {
+				if value[i] == '\\' && i+1 < len(value) {
+					i++
+					switch value[i] {
+					case 'n':
+						b.WriteByte('\n')
+					case 't':
+						b.WriteByte('\t')
+					case '"', '\\':
+						b.WriteByte(value[i])
+					default:
+						b.WriteByte('\\')
+						b.WriteByte(value[i])
+					}
+					continue
+				}
+				b.WriteByte(value[i])
+			}
+			if i == len(value) {
+				return nil, fmt.Errorf("line %d: unterminated quoted value of %s", n+1, key)
+			}
+			value = b.String()
+		default:
+			if i := strings.Index(value, " #"); i >= 0 {
+				value = strings.TrimSpace(value[:i])
+			}
+		}
+		env[key] = value
+	}
+	return env, nil
+}
+
+// validName reports whether s is a variable name: a letter or underscore
+// followed by letters, digits and underscores.
+func validName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		switch {
+		case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,204 @@
+package compose
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Interpolate substitutes variable references in the values (not the keys)
+// of a compose file the way docker compose does, and returns the
+// interpolated copy of node with the problems found. It understands $VAR,
+// ${VAR}, ${VAR:-default}, ${VAR-default}, ${VAR:?error}, ${VAR?error},
+// ${VAR:+replacement}, ${VAR+replacement} and $$ for a literal "$". A
+// variable missing from env without a default is a warning and becomes an
+// empty string; a failing "?" form and a malformed reference are errors.
+// Plain scalars are re-typed after substitution, so "${PORT}" may become
+// an integer.
+func Interpolate(node *yaml.Node, env map[string]string) (*yaml.Node, []Issue) {
+	in := &interpolator{env: env, copies: map[*yaml.Node]*yaml.Node{}}
+	out := in.copy(node)
+	in.walk(out, "")
+	return out, in.issues
+}
+
+type interpolator struct {
+	env    map[string]string
+	copies map[*yaml.Node]*yaml.Node
+	issues []Issue
+}
+
+// copy deep-copies a node tree, keeping aliases pointing at the copies of
+// their anchors.
+func (in *interpolator) copy(n *yaml.Node) *yaml.Node {
+	if n == nil {
+		return nil
+	}
+	if c, ok := in.copies[n]; ok {
+		return c
+	}
+	c := *n
+	in.copies[n] = &c
+	c.Alias = in.copy(n.Alias)
+	c.Content = make([]*yaml.Node, len(n.Content))
+	for i, child := range n.Content {
+		c.Content[i] = in.copy(child)
+	}
+	return &c
+}
+
+func (in *interpolator) walk(n *yaml.Node, ptr string) {
+	switch n.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for i, child := range n.Content {
+			if n.Kind == yaml.SequenceNode {
+				in.walk(child, ptr+"/"+strconv.Itoa(i))
+			} else {
+				in.walk(child, ptr)
+			}
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			in.walk(n.Content[i+1], ptr+"/"+escape(n.Content[i].Value))
+		}
+	case yaml.ScalarNode:
+		if !strings.Contains(n.Value, "$") {
+			return
+		}
+		value, ok := in.substitute(n.Value, n, ptr)
+		if !ok || value == n.Value {
+			return
+		}
+		n.Value = value
+		if n.Style == 0 {
+			n.Tag = "" // resolved again from the new value
+		}
+	}
+}
+
+// substitute expands the references in s. It reports false after an error,
+// leaving the value as written.
+func (in *interpolator) substitute(s string, n *yaml.Node, ptr string) (string, bool) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' {
+			b.WriteByte(s[i])
+			continue
+		}
+		rest := s[i+1:]
+		switch {
+		case strings.HasPrefix(rest, "$"):
+			b.WriteByte('$')
+			i++
+		case strings.HasPrefix(rest, "{"):
+			end := closingBrace(rest)
+			if end < 0 {
+				in.add(n, ptr, "error", "invalid interpolation format %q: missing closing brace", s)
+				return s, false
+			}
+			value, ok := in.expand(rest[1:end], s, n, ptr)
+			if !ok {
+				return s, false
+			}
+			b.WriteString(value)
+			i += end + 1
+		default:
+			end := 0
+			for end < len(rest) && validName(rest[:end+1]) {
+				end++
+			}
+			if end == 0 {
+				in.add(n, ptr, "error", "invalid interpolation format %q: use $$ for a literal $", s)
+				return s, false
+			}
+			b.WriteString(in.lookup(rest[:end], n, ptr))
+			i += end
+		}
+	}
+	return b.String(), true
+}
+
+// expand evaluates the inside of a ${...} reference.
+func (in *interpolator) expand(ref, s string, n *yaml.Node, ptr string) (string, bool) {
+	end := 0
+	for end < len(ref) && validName(ref[:end+1]) {
+		end++
+	}
+	name, op := ref[:end], ref[end:]
+	if name == "" {
+		in.add(n, ptr, "error", "invalid interpolation format %q: ${%s} does not start with a variable name", s, ref)
+		return "", false
+	}
+	if op == "" {
+		return in.lookup(name, n, ptr), true
+	}
+	value, set := in.env[name]
+	for _, form := range []string{":-", ":?", ":+", "-", "?", "+"} {
+		word, ok := strings.CutPrefix(op, form)
+		if !ok {
+			continue
+		}
+		// the colon forms treat an empty value like a missing one
+		present := set && (value != "" || form[0] != ':')
+		switch form[len(form)-1] {
+		case '-':
+			if present {
+				return value, true
+			}
+			return in.substitute(word, n, ptr)
+		case '+':
+			if !present {
+				return "", true
+			}
+			return in.substitute(word, n, ptr)
+		default:
+			if present {
+				return value, true
+			}
+			msg, ok := in.substitute(word, n, ptr)
+			if !ok {
+				return "", false
+			}
+			if msg == "" {
+				in.add(n, ptr, "error", "required variable %s is missing a value", name)
+			} else {
+				in.add(n, ptr, "error", "required variable %s is missing a value: %s", name, msg)
+			}
+			return "", false
+		}
+	}
+	in.add(n, ptr, "error", "invalid interpolation format %q: unknown modifier in ${%s}", s, ref)
+	return "", false
+}
+
+func (in *interpolator) lookup(name string, n *yaml.Node, ptr string) string {
+	value, ok := in.env[name]
+	if !ok {
+		in.add(n, ptr, "warning", "the %s variable is not set; defaulting to a blank string", name)
+	}
+	return value
+}
+
+func (in *interpolator) add(n *yaml.Node, ptr, severity, format string, args ...any) {
+	in.issues = append(in.issues, Issue{Line: n.Line, Column: n.Column, Message: fmt.Sprintf(format, args...), Severity: severity, Path: ptr})
+}
+
+// closingBrace returns the index of the brace closing the one s starts
+// with, allowing nested references in defaults, or -1.
+func closingBrace(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,296 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"devformat/backend/internal/lint"
+)
+
+// FileName is the repository-level configuration file looked up by the CLI
+// and the server.
+const FileName = ".devformat.yaml"
+
+// DefaultIndentation is the indentation width used when none is configured.
+const DefaultIndentation = 2
+
+// Config is the parsed content of a .devformat.yaml file:
+//
+//	extends: default          # enable every built-in lint rule
+//	rules:
+//	  truthy: disable
+//	  line-length:
+//	    max: 120
+//	    level: warning
+//	indentation: 2
+//	kubernetesVersion: "1.28"
+//	ignore:
+//	  - vendor/**
+//	schemas:
+//	  k8s/**/*.yaml: kubernetes
+type Config struct {
+	// Extends "default" enables every registered lint rule.
+	Extends           string         `yaml:"extends"`
+	Rules             map[string]any `yaml:"rules"`
+	Indentation       int            `yaml:"indentation"`
+	KubernetesVersion string         `yaml:"kubernetesVersion"`
+	Ignore            []string       `yaml:"ignore"`
+	Schemas           SchemaMappings `yaml:"schemas"`
+}
+
+// SchemaMapping assigns a default schema to files matching Glob.
+type SchemaMapping struct {
+	Glob   string
+	Schema string
+}
+
+// SchemaMappings keeps the mappings in file order so the first matching glob wins.
+type SchemaMappings []SchemaMapping
+
+// UnmarshalYAML decodes a mapping of glob -> schema while preserving its order.
+func (m *SchemaMappings) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: schemas must be a mapping of glob to schema name", n.Line)
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		*m = append(*m, SchemaMapping{Glob: n.Content[i].Value, Schema: n.Content[i+1].Value})
+	}
+	return nil
+}
+
+// Parse decodes configuration content and checks rule names and levels.
+func Parse(data []byte) (*Config, error) {
+	cfg := &Config{}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", FileName, err)
+	}
+	if cfg.Extends != "" && cfg.Extends != "default" {
+		return nil, fmt.Errorf("invalid %s: unknown extends %q (only \"default\" is supported)", FileName, cfg.Extends)
+	}
+	if cfg.Indentation < 0 {
+		return nil, fmt.Errorf("invalid %s: indentation must be positive", FileName)
+	}
+	for id, v := range cfg.Rules {
+		if _, ok := lint.Lookup(id); !ok {
+			return nil, fmt.Errorf("invalid %s: unknown rule %q", FileName, id)
+		}
+		if _, _, _, err := ruleSetting(v); err != nil {
+			return nil, fmt.Errorf("invalid %s: rule %q: %w", FileName, id, err)
+		}
+	}
+	for _, g := range append(append([]string{}, cfg.Ignore...), cfg.Schemas.globs()...) {
+		if _, err := globRegexp(g); err != nil {
+			return nil, fmt.Errorf("invalid %s: bad glob %q: %w", FileName, g, err)
+		}
+	}
+	return cfg, nil
+}
+
+// Load reads and parses a configuration file.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Find looks for FileName in dir and its parents and returns its path.
+func Find(dir string) (string, bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+	for {
+		p := filepath.Join(dir, FileName)
+		if st, err := os.Stat(p); err == nil && !st.IsDir() {
+			return p, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// IndentWidth returns the configured indentation or DefaultIndentation.
+func (c *Config) IndentWidth() int {
+	if c == nil || c.Indentation == 0 {
+		return DefaultIndentation
+	}
+	return c.Indentation
+}
+
+// Ignored reports whether path matches one of the ignore globs. Paths are
+// matched in slash form relative to the repository root.
+func (c *Config) Ignored(path string) bool {
+	if c == nil || path == "" {
+		return false
+	}
+	for _, g := range c.Ignore {
+		if Match(g, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// SchemaFor returns the schema of the first glob matching path, or "".
+func (c *Config) SchemaFor(path string) string {
+	if c == nil || path == "" {
+		return ""
+	}
+	for _, m := range c.Schemas {
+		if Match(m.Glob, path) {
+			return m.Schema
+		}
+	}
+	return ""
+}
+
+// LintRules resolves the effective lint rules. Requested IDs take priority;
+// without any, the rules enabled by the configuration are used. Rules the
+// configuration disables are always dropped, and configured levels and
+// options are applied to the rest.
+func (c *Config) LintRules(requested []string) ([]lint.Rule, error) {
+	ids := requested
+	if len(ids) == 0 && c != nil {
+		if c.Extends == "default" {
+			ids = []string{"all"}
+		} else {
+			for id, v := range c.Rules {
+				if disabled, _, _, _ := ruleSetting(v); !disabled {
+					ids = append(ids, id)
+				}
+			}
+		}
+	}
+	rules, err := lint.Select(ids)
+	if err != nil || c == nil {
+		return rules, err
+	}
+
+	out := rules[:0]
+	for _, r := range rules {
+		disabled, level, opts, _ := ruleSetting(c.Rules[r.ID])
+		if disabled {
+			continue
+		}
+		if level != "" {
+			r.Severity = level
+		}
+		if len(opts) > 0 {
+			merged := lint.Options{}
+			for k, v := range r.Options {
+				merged[k] = v
+			}
+			for k, v := range opts {
+				merged[k] = v
+			}
+			r.Options = merged
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+// ruleSetting interprets a rule entry: "enable", "disable", or a mapping with
+// an optional "level" and rule-specific options.
+func ruleSetting(v any) (disabled bool, level string, opts lint.Options, err error) {
+	switch x := v.(type) {
+	case nil:
+		return false, "", nil, nil
+	case string:
+		switch x {
+		case "enable":
+			return false, "", nil, nil
+		case "disable":
+			return true, "", nil, nil
+		}
+		return false, "", nil, fmt.Errorf("expected \"enable\", \"disable\" or a mapping, got %q", x)
+	case map[string]any:
+		opts = lint.Options{}
+		for k, val := range x {
+			switch k {
+			case "level":
+				level, _ = val.(string)
+				if level != lint.SeverityError && level != lint.SeverityWarning && level != lint.SeverityInfo {
+					return false, "", nil, fmt.Errorf("level must be error, warning or info")
+				}
+			case "enabled":
+				if b, ok := val.(bool); ok && !b {
+					disabled = true
+				}
+			default:
+				opts[k] = val
+			}
+		}
+		return disabled, level, opts, nil
+	}
+	return false, "", nil, fmt.Errorf("unsupported setting %v", v)
+}
+
+func (m SchemaMappings) globs() []string {
+	out := make([]string, len(m))
+	for i, s := range m {
+		out[i] = s.Glob
+	}
+	return out
+}
+
+// Match reports whether path matches a glob where "*" and "?" stay within a
+// path segment and "**" spans any number of segments. A glob without a slash
+// matches the base name anywhere in the tree, as in .gitignore.
+func Match(glob, path string) bool {
+	path = strings.TrimPrefix(filepath.ToSlash(path), "./")
+	re, err := globRegexp(glob)
+	if err != nil {
+		return false
+	}
+	if !strings.Contains(strings.TrimSuffix(glob, "/"), "/") {
+		return re.MatchString(path) || re.MatchString(pathBase(path))
+	}
+	return re.MatchString(path)
+}
+
+func pathBase(p string) string {
+	if i := strings.LastIndex(p, "/"); i >= 0 {
+		return p[i+1:]
+	}
+	return p
+}
+
+func globRegexp(glob string) (*regexp.Regexp, error) {
+	glob = strings.TrimSuffix(strings.TrimPrefix(glob, "./"), "/")
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		ch := glob[i]
+		switch {
+		case ch == '*' && i+1 < len(glob) && glob[i+1] == '*':
+			i++
+			if i+1 < len(glob) && glob[i+1] == '/' {
+				// "**/" matches zero or more directories
+				i++
+				b.WriteString("(?:.*/)?")
+			} else {
+				b.WriteString(".*")
+			}
+		case ch == '*':
+			b.WriteString("[^/]*")
+		case ch == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	// a directory glob such as "vendor/" or "vendor" also covers its contents
+	b.WriteString("(?:/.*)?$")
+	return regexp.Compile(b.String())
+}
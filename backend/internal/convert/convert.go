@@ -0,0 +1,480 @@
+// Package convert translates documents between YAML, JSON and TOML. Every
+// format is read into yaml.Node trees so that key order survives the trip,
+// and every loss the target format forces (comments, non-string keys, nulls
+// in TOML, extra YAML documents) is reported as a Warning rather than
+// silently dropped.
+package convert
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"devformat/backend/internal/dupkeys"
+	"devformat/backend/internal/json5"
+)
+
+// Supported formats.
+const (
+	YAML = "yaml"
+	JSON = "json"
+	TOML = "toml"
+)
+
+// Formats lists the supported formats.
+var Formats = []string{YAML, JSON, TOML}
+
+// Options controls a conversion.
+type Options struct {
+	From string
+	To   string
+	// Indent is the number of spaces per level of YAML and JSON output; 0
+	// means 2.
+	Indent int
+	// NDJSON writes JSON output as one compact line per document instead of
+	// an array when the source holds several YAML documents.
+	NDJSON bool
+}
+
+// Warning is a loss caused by the conversion. Line is 1-based in the source,
+// or 0 when the loss is not tied to a position.
+type Warning struct {
+	Line    int
+	Message string
+}
+
+// ErrUnknownFormat is returned for a format outside Formats.
+var ErrUnknownFormat = errors.New("unknown format")
+
+// CheckFormat returns an error wrapping ErrUnknownFormat when format is not
+// supported.
+func CheckFormat(format string) error {
+	for _, f := range Formats {
+		if f == format {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w %q (want yaml, json or toml)", ErrUnknownFormat, format)
+}
+
+// Convert reads content as opts.From and writes it as opts.To.
+func Convert(content string, opts Options) (string, []Warning, error) {
+	if err := CheckFormat(opts.From); err != nil {
+		return "", nil, err
+	}
+	if err := CheckFormat(opts.To); err != nil {
+		return "", nil, err
+	}
+	if opts.Indent <= 0 {
+		opts.Indent = 2
+	}
+
+	c := &converter{to: opts.To}
+	docs, err := c.read(content, opts.From)
+	if err != nil {
+		return "", nil, err
+	}
+	if opts.To != YAML {
+		for _, doc := range docs {
+			if err := checkAliases(doc); err != nil {
+				return "", nil, err
+			}
+		}
+	}
+	if opts.From != YAML || opts.To != YAML {
+		for _, doc := range docs {
+			if err := c.prepare(doc); err != nil {
+				return "", nil, err
+			}
+		}
+	}
+
+	var out string
+	switch opts.To {
+	case YAML:
+		out, err = writeYAML(docs, opts.Indent, opts.From != YAML)
+	case JSON:
+		out, err = writeJSON(docs, opts.Indent, opts.NDJSON)
+	case TOML:
+		switch {
+		case len(docs) == 0:
+			out = ""
+		case len(docs) > 1:
+			return "", nil, fmt.Errorf("TOML holds a single document but the content has %d; convert them one at a time", len(docs))
+		default:
+			out, err = writeTOML(docs[0])
+		}
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	sort.SliceStable(c.warnings, func(i, j int) bool { return c.warnings[i].Line < c.warnings[j].Line })
+	return out, c.warnings, nil
+}
+
+type converter struct {
+	to       string
+	warnings []Warning
+}
+
+func (c *converter) warn(line int, format string, args ...any) {
+	c.warnings = append(c.warnings, Warning{Line: line, Message: fmt.Sprintf(format, args...)})
+}
+
+// read parses content into the root nodes of its documents. Documents
+// without content, such as a YAML stream ending in "---", are skipped.
+func (c *converter) read(content, from string) ([]*yaml.Node, error) {
+	switch from {
+	case JSON:
+		doc, repairs, err := json5.Parse(content)
+		if err != nil {
+			return nil, fmt.Errorf("JSON syntax error: %w", err)
+		}
+		if c.to != JSON {
+			comments := 0
+			for _, r := range repairs {
+				if r.Kind == json5.RepairComment {
+					comments++
+				}
+			}
+			c.commentsDropped(firstCommentLine(repairs), comments)
+		}
+		return []*yaml.Node{doc.Content[0]}, nil
+	case TOML:
+		root, err := c.readTOML(content)
+		if err != nil {
+			return nil, err
+		}
+		return []*yaml.Node{root}, nil
+	}
+
+	var roots []*yaml.Node
+	dec := yaml.NewDecoder(strings.NewReader(content))
+	lines := strings.Split(content, "\n")
+	comments, firstLine := 0, 0
+	for {
+		var doc yaml.Node
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("YAML syntax error: %w", err)
+		}
+		if c.to != YAML {
+			walk(&doc, func(n *yaml.Node) {
+				for i, comment := range []string{n.HeadComment, n.LineComment, n.FootComment} {
+					if comment == "" {
+						continue
+					}
+					line := n.Line
+					switch i {
+					case 0:
+						line = commentLine(lines, comment, n.Line, -1)
+					case 2:
+						line = commentLine(lines, comment, n.Line, 1)
+					}
+					if comments == 0 || line < firstLine {
+						firstLine = line
+					}
+					comments++
+				}
+			})
+		}
+		if len(doc.Content) > 0 && !isEmpty(doc.Content[0]) {
+			roots = append(roots, doc.Content[0])
+		}
+	}
+	c.commentsDropped(firstLine, comments)
+	return roots, nil
+}
+
+// commentLine returns the 1-based line on which a comment of the node at
+// line node starts: a head comment (step -1) is searched for above the node,
+// a foot comment (step 1) below it. It falls back to the node's line.
+func commentLine(lines []string, comment string, node, step int) int {
+	first := strings.TrimSpace(strings.SplitN(comment, "\n", 2)[0])
+	start := node + step
+	if step < 0 {
+		// the comment's last line is at best right above the node
+		start -= strings.Count(comment, "\n")
+	}
+	for l := start; l >= 1 && l <= len(lines); l += step {
+		if strings.TrimSpace(lines[l-1]) == first {
+			return l
+		}
+	}
+	return node
+}
+
+func (c *converter) commentsDropped(line, count int) {
+	switch {
+	case count == 1:
+		c.warn(line, "1 comment is dropped; %s output cannot carry it over", c.to)
+	case count > 1:
+		c.warn(line, "%d comments are dropped; %s output cannot carry them over", count, c.to)
+	}
+}
+
+func firstCommentLine(repairs []json5.Repair) int {
+	for _, r := range repairs {
+		if r.Kind == json5.RepairComment {
+			return r.Line
+		}
+	}
+	return 0
+}
+
+// prepare rewrites a tree into what the target format can express: repeated
+// keys keep their last value, merge keys ("<<") are expanded, keys become
+// strings, and values the target cannot hold are dropped or replaced. Each
+// rewrite that loses information is reported.
+func (c *converter) prepare(root *yaml.Node) error {
+	for _, d := range dupkeys.FindYAML(root) {
+		c.warn(d.Second.Line, "key %q is repeated in its mapping (first at line %d); the last value is kept", d.Key, d.First.Line)
+	}
+	dupkeys.Resolve(root, dupkeys.KeepLast)
+
+	var err error
+	walk(root, func(n *yaml.Node) {
+		if err != nil {
+			return
+		}
+		switch n.Kind {
+		case yaml.MappingNode:
+			expandMerges(n)
+			var kept []*yaml.Node
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				k, v := n.Content[i], n.Content[i+1]
+				if k.Kind != yaml.ScalarNode {
+					err = fmt.Errorf("line %d: a %s cannot be used as a key in %s", k.Line, kindName(k), c.to)
+					return
+				}
+				if tag := k.ShortTag(); tag != "!!str" {
+					c.warn(k.Line, "key %s (%s) is written as the string %q", k.Value, strings.TrimPrefix(tag, "!!"), k.Value)
+					k.Tag, k.Style = "!!str", 0
+				}
+				if c.to == TOML && isNull(v) {
+					c.warn(v.Line, "null value of key %q is dropped; TOML has no null", k.Value)
+					continue
+				}
+				kept = append(kept, k, v)
+			}
+			n.Content = kept
+		case yaml.SequenceNode:
+			if c.to != TOML {
+				return
+			}
+			var kept []*yaml.Node
+			for i, item := range n.Content {
+				if isNull(item) {
+					c.warn(item.Line, "null array element %d is dropped; TOML has no null", i)
+					continue
+				}
+				kept = append(kept, item)
+			}
+			n.Content = kept
+		case yaml.ScalarNode:
+			if c.to == JSON && n.ShortTag() == "!!float" {
+				var f float64
+				if n.Decode(&f) == nil && (math.IsInf(f, 0) || math.IsNaN(f)) {
+					c.warn(n.Line, "%s is written as null; JSON has no infinity or NaN", n.Value)
+					n.Tag, n.Value, n.Style = "!!null", "null", 0
+				}
+			}
+		}
+	})
+	return err
+}
+
+// checkAliases rejects a tree JSON or TOML output cannot hold: an alias
+// inside the node it refers to, which would expand forever, or aliases that
+// expand to more than json5.MaxNodes values. Sizes are computed once per
+// node, so a "billion laughs" document is rejected without expanding it.
+func checkAliases(root *yaml.Node) error {
+	sizes := map[*yaml.Node]int{}
+	active := map[*yaml.Node]bool{}
+	var size func(n *yaml.Node) (int, error)
+	size = func(n *yaml.Node) (int, error) {
+		if s, ok := sizes[n]; ok {
+			return s, nil
+		}
+		active[n] = true
+		defer delete(active, n)
+		children := n.Content
+		if n.Kind == yaml.AliasNode {
+			if active[n.Alias] {
+				return 0, fmt.Errorf("line %d: alias *%s refers to a value that contains it", n.Line, n.Value)
+			}
+			children = []*yaml.Node{n.Alias}
+		}
+		total := 1
+		for _, c := range children {
+			s, err := size(c)
+			if err != nil {
+				return 0, err
+			}
+			if total += s; total > json5.MaxNodes {
+				return 0, fmt.Errorf("line %d: aliases expand the document to more than %d values", n.Line, json5.MaxNodes)
+			}
+		}
+		sizes[n] = total
+		return total, nil
+	}
+	_, err := size(root)
+	return err
+}
+
+// expandMerges replaces the merge keys of a mapping with the pairs they
+// merge in. Keys written in the mapping itself take precedence, then earlier
+// merged mappings over later ones, as the YAML merge key type specifies.
+func expandMerges(n *yaml.Node) {
+	has := false
+	own := map[string]bool{}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].ShortTag() == "!!merge" {
+			has = true
+		} else {
+			own[n.Content[i].Value] = true
+		}
+	}
+	if !has {
+		return
+	}
+
+	var out []*yaml.Node
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+		if k.ShortTag() != "!!merge" {
+			out = append(out, k, v)
+			continue
+		}
+		sources := []*yaml.Node{v}
+		if resolve(v).Kind == yaml.SequenceNode {
+			sources = resolve(v).Content
+		}
+		for _, src := range sources {
+			src = resolve(src)
+			if src.Kind != yaml.MappingNode {
+				continue
+			}
+			expandMerges(src)
+			for j := 0; j+1 < len(src.Content); j += 2 {
+				if key := src.Content[j].Value; !own[key] {
+					own[key] = true
+					out = append(out, src.Content[j], src.Content[j+1])
+				}
+			}
+		}
+	}
+	n.Content = out
+}
+
+func resolve(n *yaml.Node) *yaml.Node {
+	for n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	return n
+}
+
+// isEmpty reports whether a document root stands for no content at all, as
+// opposed to an explicit null.
+func isEmpty(n *yaml.Node) bool {
+	return n.Kind == yaml.ScalarNode && n.ShortTag() == "!!null" && n.Value == "" && n.Style == 0
+}
+
+func isNull(n *yaml.Node) bool {
+	n = resolve(n)
+	return n.Kind == yaml.ScalarNode && n.ShortTag() == "!!null"
+}
+
+func kindName(n *yaml.Node) string {
+	switch n.Kind {
+	case yaml.MappingNode:
+		return "mapping"
+	case yaml.SequenceNode:
+		return "sequence"
+	case yaml.AliasNode:
+		return "alias"
+	}
+	return "scalar"
+}
+
+// walk visits every node of the tree once, parents before children, without
+// following aliases.
+func walk(n *yaml.Node, visit func(n *yaml.Node)) {
+	visit(n)
+	for _, c := range n.Content {
+		walk(c, visit)
+	}
+}
+
+// yaml11Bools are the strings YAML 1.1 parsers read as booleans. The encoder
+// follows YAML 1.2 and leaves them unquoted.
+var yaml11Bools = map[string]bool{"yes": true, "no": true, "on": true, "off": true, "y": true, "n": true}
+
+// writeYAML encodes the documents as a YAML stream. Trees read from JSON or
+// TOML (restyle) are switched to block style and plain scalars first; the
+// encoder still quotes strings that would otherwise read as another type.
+func writeYAML(docs []*yaml.Node, indent int, restyle bool) (string, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(indent)
+	for _, root := range docs {
+		if restyle {
+			walk(root, func(n *yaml.Node) {
+				n.Style = 0
+				if n.Kind != yaml.ScalarNode || n.ShortTag() != "!!str" {
+					return
+				}
+				switch {
+				case strings.Contains(strings.TrimRight(n.Value, "\n"), "\n"):
+					n.Style = yaml.LiteralStyle
+				case yaml11Bools[strings.ToLower(n.Value)]:
+					n.Style = yaml.DoubleQuotedStyle
+				}
+			})
+		}
+		if err := enc.Encode(root); err != nil {
+			return "", err
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// writeJSON encodes one document as an indented JSON value and several as
+// an array, or as newline-delimited JSON when ndjson is set.
+func writeJSON(docs []*yaml.Node, indent int, ndjson bool) (string, error) {
+	if !ndjson {
+		switch len(docs) {
+		case 0:
+			return "null\n", nil
+		case 1:
+			return json5.Encode(docs[0], indent)
+		}
+		return json5.Encode(&yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: docs}, indent)
+	}
+
+	var buf bytes.Buffer
+	for _, root := range docs {
+		out, err := json5.Encode(root, indent)
+		if err != nil {
+			return "", err
+		}
+		if err := json.Compact(&buf, []byte(out)); err != nil {
+			return "", err
+		}
+		buf.WriteByte('\n')
+	}
+	return buf.String(), nil
+}
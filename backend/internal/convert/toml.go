@@ -0,0 +1,441 @@
+package convert
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/pelletier/go-toml/v2/unstable"
+	"gopkg.in/yaml.v3"
+)
+
+// pathSep joins the keys of a TOML key path in the order index.
+const pathSep = "\x00"
+
+// readTOML decodes content and rebuilds it as a mapping node whose keys keep
+// the order in which they first appear in the source. Decoding into Go maps
+// validates the document and types its values; the order is recovered from
+// a second pass over the syntax tree.
+func (c *converter) readTOML(content string) (*yaml.Node, error) {
+	var v map[string]any
+	if err := toml.Unmarshal([]byte(content), &v); err != nil {
+		var de *toml.DecodeError
+		if errors.As(err, &de) {
+			line, col := de.Position()
+			return nil, fmt.Errorf("TOML syntax error: line %d, column %d: %s", line, col, strings.TrimPrefix(de.Error(), "toml: "))
+		}
+		return nil, fmt.Errorf("TOML syntax error: %w", err)
+	}
+
+	if c.to != TOML {
+		lines := tomlCommentLines(content)
+		if len(lines) > 0 {
+			c.commentsDropped(lines[0], len(lines))
+		}
+	}
+	b := &tomlBuilder{order: tomlKeyOrder([]byte(content))}
+	root := b.node(v, "")
+	if c.to == JSON && b.times > 0 {
+		c.warn(0, "%d TOML date/time value(s) are written as JSON strings", b.times)
+	}
+	return root, nil
+}
+
+// tomlKeyOrder numbers every key path of a TOML document in order of first
+// appearance. Elements of arrays share the path of the array, so keys of an
+// array of tables are ordered across all its tables.
+func tomlKeyOrder(data []byte) map[string]int {
+	order := map[string]int{}
+	see := func(path []string) {
+		key := strings.Join(path, pathSep)
+		if _, ok := order[key]; !ok {
+			order[key] = len(order)
+		}
+	}
+
+	var keyValue func(kv *unstable.Node, parent []string)
+	var value func(v *unstable.Node, path []string)
+	keyValue = func(kv *unstable.Node, parent []string) {
+		path := append([]string{}, parent...)
+		it := kv.Key()
+		for it.Next() {
+			path = append(path, string(it.Node().Data))
+			see(path)
+		}
+		value(kv.Value(), path)
+	}
+	value = func(v *unstable.Node, path []string) {
+		it := v.Children()
+		switch v.Kind {
+		case unstable.InlineTable:
+			for it.Next() {
+				keyValue(it.Node(), path)
+			}
+		case unstable.Array:
+			for it.Next() {
+				value(it.Node(), path)
+			}
+		}
+	}
+
+	var p unstable.Parser
+	p.Reset(data)
+	var table []string
+	for p.NextExpression() {
+		e := p.Expression()
+		switch e.Kind {
+		case unstable.Table, unstable.ArrayTable:
+			table = nil
+			it := e.Key()
+			for it.Next() {
+				table = append(table, string(it.Node().Data))
+				see(table)
+			}
+		case unstable.KeyValue:
+			keyValue(e, table)
+		}
+	}
+	return order
+}
+
+// tomlCommentLines returns the lines holding a comment, skipping "#" inside
+// basic, literal and multi-line strings.
+func tomlCommentLines(content string) []int {
+	var lines []int
+	line := 1
+	quote := ""
+	for i := 0; i < len(content); i++ {
+		ch := content[i]
+		switch {
+		case ch == '\n':
+			line++
+			if len(quote) == 1 {
+				quote = ""
+			}
+		case quote == "":
+			switch {
+			case ch == '#':
+				lines = append(lines, line)
+				for i+1 < len(content) && content[i+1] != '\n' {
+					i++
+				}
+			case strings.HasPrefix(content[i:], `"""`) || strings.HasPrefix(content[i:], "'''"):
+				quote = content[i : i+3]
+				i += 2
+			case ch == '"' || ch == '\'':
+				quote = string(ch)
+			}
+		case ch == '\\' && quote[0] == '"':
+			i++
+			if i < len(content) && content[i] == '\n' {
+				line++
+			}
+		case strings.HasPrefix(content[i:], quote):
+			i += len(quote) - 1
+			quote = ""
+		}
+	}
+	return lines
+}
+
+// tomlBuilder turns decoded TOML values into nodes. times counts the
+// date/time values, which only YAML can keep typed.
+type tomlBuilder struct {
+	order map[string]int
+	times int
+}
+
+func (b *tomlBuilder) node(v any, path string) *yaml.Node {
+	switch v := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		childPath := func(k string) string {
+			if path == "" {
+				return k
+			}
+			return path + pathSep + k
+		}
+		rank := func(k string) int {
+			if i, ok := b.order[childPath(k)]; ok {
+				return i
+			}
+			return math.MaxInt
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			ri, rj := rank(keys[i]), rank(keys[j])
+			if ri != rj {
+				return ri < rj
+			}
+			return keys[i] < keys[j]
+		})
+		n := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, k := range keys {
+			n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: k}, b.node(v[k], childPath(k)))
+		}
+		return n
+	case []any:
+		n := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, item := range v {
+			n.Content = append(n.Content, b.node(item, path))
+		}
+		return n
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v}
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(v)}
+	case int64:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.FormatInt(v, 10)}
+	case float64:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: yamlFloat(v)}
+	case time.Time:
+		b.times++
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!timestamp", Value: v.Format(time.RFC3339Nano)}
+	case toml.LocalDate, toml.LocalDateTime:
+		b.times++
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!timestamp", Value: fmt.Sprint(v)}
+	case toml.LocalTime:
+		b.times++
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v.String()}
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: fmt.Sprint(v)}
+}
+
+func yamlFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return ".nan"
+	case math.IsInf(f, 1):
+		return ".inf"
+	case math.IsInf(f, -1):
+		return "-.inf"
+	}
+	return finiteFloat(f)
+}
+
+// finiteFloat formats f so that it still reads as a float, with a decimal
+// point or an exponent.
+func finiteFloat(f float64) string {
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eE") {
+		s += ".0"
+	}
+	return s
+}
+
+// writeTOML encodes a mapping as a TOML document. Nested mappings become
+// tables and sequences of mappings arrays of tables; mappings anywhere else
+// are written as inline tables.
+func writeTOML(root *yaml.Node) (string, error) {
+	root = resolve(root)
+	if root.Kind != yaml.MappingNode {
+		return "", fmt.Errorf("line %d: TOML needs a mapping at the top level, not a %s", root.Line, kindName(root))
+	}
+	w := &tomlWriter{}
+	if err := w.table(root, nil, "", false); err != nil {
+		return "", err
+	}
+	return w.buf.String(), nil
+}
+
+type tomlWriter struct {
+	buf bytes.Buffer
+}
+
+// table writes the pairs of m under header. Key/value pairs come first,
+// since every pair after a header belongs to that header's table. An empty
+// header is written only when the table would otherwise leave no trace, or
+// when force is set for an element of an array of tables.
+func (w *tomlWriter) table(m *yaml.Node, path []string, header string, force bool) error {
+	var values, tables, arrays []*yaml.Node
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		k, v := m.Content[i], resolve(m.Content[i+1])
+		switch {
+		case v.Kind == yaml.MappingNode:
+			tables = append(tables, k, v)
+		case isTableArray(v):
+			arrays = append(arrays, k, v)
+		default:
+			values = append(values, k, v)
+		}
+	}
+
+	if header != "" && (force || len(values) > 0 || len(tables)+len(arrays) == 0) {
+		if w.buf.Len() > 0 {
+			w.buf.WriteByte('\n')
+		}
+		w.buf.WriteString(header + "\n")
+	}
+	for i := 0; i < len(values); i += 2 {
+		s, err := tomlValue(values[i+1])
+		if err != nil {
+			return err
+		}
+		w.buf.WriteString(tomlKey(values[i].Value) + " = " + s + "\n")
+	}
+	for i := 0; i < len(tables); i += 2 {
+		p := append(append([]string{}, path...), tables[i].Value)
+		if err := w.table(tables[i+1], p, "["+dottedKey(p)+"]", false); err != nil {
+			return err
+		}
+	}
+	for i := 0; i < len(arrays); i += 2 {
+		p := append(append([]string{}, path...), arrays[i].Value)
+		for _, item := range arrays[i+1].Content {
+			if err := w.table(resolve(item), p, "[["+dottedKey(p)+"]]", true); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isTableArray reports whether n is a non-empty sequence of mappings.
+func isTableArray(n *yaml.Node) bool {
+	if n.Kind != yaml.SequenceNode || len(n.Content) == 0 {
+		return false
+	}
+	for _, item := range n.Content {
+		if resolve(item).Kind != yaml.MappingNode {
+			return false
+		}
+	}
+	return true
+}
+
+func tomlValue(n *yaml.Node) (string, error) {
+	n = resolve(n)
+	switch n.Kind {
+	case yaml.MappingNode:
+		if len(n.Content) == 0 {
+			return "{}", nil
+		}
+		parts := make([]string, 0, len(n.Content)/2)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			s, err := tomlValue(n.Content[i+1])
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, tomlKey(n.Content[i].Value)+" = "+s)
+		}
+		return "{ " + strings.Join(parts, ", ") + " }", nil
+	case yaml.SequenceNode:
+		parts := make([]string, 0, len(n.Content))
+		for _, item := range n.Content {
+			s, err := tomlValue(item)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, s)
+		}
+		return "[" + strings.Join(parts, ", ") + "]", nil
+	}
+
+	switch n.ShortTag() {
+	case "!!bool":
+		var b bool
+		if err := n.Decode(&b); err != nil {
+			return "", err
+		}
+		return strconv.FormatBool(b), nil
+	case "!!int":
+		var v any
+		if err := n.Decode(&v); err != nil {
+			return "", err
+		}
+		if u, ok := v.(uint64); ok && u > math.MaxInt64 {
+			return "", fmt.Errorf("line %d: integer %s does not fit in a TOML integer", n.Line, n.Value)
+		}
+		return fmt.Sprint(v), nil
+	case "!!float":
+		var f float64
+		if err := n.Decode(&f); err != nil {
+			return "", err
+		}
+		switch {
+		case math.IsNaN(f):
+			return "nan", nil
+		case math.IsInf(f, 1):
+			return "inf", nil
+		case math.IsInf(f, -1):
+			return "-inf", nil
+		}
+		return finiteFloat(f), nil
+	case "!!timestamp":
+		var t time.Time
+		if err := n.Decode(&t); err != nil {
+			return "", err
+		}
+		if len(n.Value) == len("2006-01-02") {
+			return n.Value, nil
+		}
+		return t.Format(time.RFC3339Nano), nil
+	case "!!null":
+		return "", fmt.Errorf("line %d: TOML has no null", n.Line)
+	}
+	return tomlString(n.Value), nil
+}
+
+var bareKeyRe = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func tomlKey(k string) string {
+	if bareKeyRe.MatchString(k) {
+		return k
+	}
+	return tomlString(k)
+}
+
+func dottedKey(path []string) string {
+	parts := make([]string, len(path))
+	for i, k := range path {
+		parts[i] = tomlKey(k)
+	}
+	return strings.Join(parts, ".")
+}
+
+// tomlString quotes s as a basic string, or as a multi-line basic string
+// when it spans several lines.
+func tomlString(s string) string {
+	multiline := strings.Contains(strings.TrimRight(s, "\n"), "\n")
+	var sb strings.Builder
+	if multiline {
+		sb.WriteString("\"\"\"\n")
+	} else {
+		sb.WriteByte('"')
+	}
+	for _, r := range s {
+		switch {
+		case r == '"':
+			sb.WriteString(`\"`)
+		case r == '\\':
+			sb.WriteString(`\\`)
+		case r == '\n' && multiline:
+			sb.WriteByte('\n')
+		case r == '\n':
+			sb.WriteString(`\n`)
+		case r == '\t':
+			sb.WriteString(`\t`)
+		case r == '\r':
+			sb.WriteString(`\r`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&sb, `\u%04X`, r)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	if multiline {
+		sb.WriteString(`"""`)
+	} else {
+		sb.WriteByte('"')
+	}
+	return sb.String()
+}
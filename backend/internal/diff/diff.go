@@ -0,0 +1,276 @@
+// Package diff computes line-based differences between two texts and renders
+// them as unified diffs.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultContext is the number of unchanged lines shown around each change,
+// as in diff -u.
+const DefaultContext = 3
+
+// maxEditDistance bounds the Myers search; texts that differ by more lines
+// are reported as a single replacement.
+const maxEditDistance = 4000
+
+// Op is the kind of a line in a hunk.
+type Op byte
+
+const (
+	Equal  Op = ' '
+	Delete Op = '-'
+	Insert Op = '+'
+)
+
+// Line is one line of a hunk, without its trailing newline.
+type Line struct {
+	Op   Op
+	Text string
+}
+
+// Hunk is a group of nearby changes with surrounding context. Starts are
+// 1-based; a start refers to the line before the hunk when its count is 0,
+// matching the unified diff header.
+type Hunk struct {
+	OldStart, OldLines int
+	NewStart, NewLines int
+	Lines              []Line
+}
+
+// Hunks compares a and b line by line and groups the changes into hunks with
+// context unchanged lines around them.
+func Hunks(a, b string, context int) []Hunk {
+	return group(Lines(SplitLines(a), SplitLines(b)), context)
+}
+
+// Unified renders a unified diff of a and b, labelled with the given names.
+// It returns "" when the texts are equal.
+func Unified(fromName, toName, a, b string) string {
+	return Format(fromName, toName, Hunks(a, b, DefaultContext))
+}
+
+// Format renders hunks as a unified diff.
+func Format(fromName, toName string, hunks []Hunk) string {
+	if len(hunks) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+	for _, h := range hunks {
+		sb.WriteString(h.Header())
+		sb.WriteByte('\n')
+		for _, l := range h.Lines {
+			sb.WriteByte(byte(l.Op))
+			sb.WriteString(l.Text)
+			sb.WriteByte('\n')
+		}
+	}
+	return sb.String()
+}
+
+// Header returns the "@@ -l,s +l,s @@" line of the hunk.
+func (h Hunk) Header() string {
+	return fmt.Sprintf("@@ -%s +%s @@", span(h.OldStart, h.OldLines), span(h.NewStart, h.NewLines))
+}
+
+func span(start, n int) string {
+	if n == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, n)
+}
+
+// SplitLines splits text into lines, dropping the newline that terminates
+// the last line.
+func SplitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// Lines returns the edit script turning a into b.
+func Lines(a, b []string) []Line {
+	// common prefix and suffix are cheap to strip and keep the search small
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+
+	var out []Line
+	for _, s := range a[:pre] {
+		out = append(out, Line{Equal, s})
+	}
+	out = append(out, myers(a[pre:len(a)-suf], b[pre:len(b)-suf])...)
+	for _, s := range a[len(a)-suf:] {
+		out = append(out, Line{Equal, s})
+	}
+	return out
+}
+
+// myers implements the O((N+M)D) shortest edit script search.
+func myers(a, b []string) []Line {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		return replace(a, b)
+	}
+	max := n + m
+	if max > 2*maxEditDistance {
+		max = 2 * maxEditDistance
+	}
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	var trace [][]int
+	for d := 0; d <= max; d++ {
+		snapshot := make([]int, len(v))
+		copy(snapshot, v)
+		trace = append(trace, snapshot)
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, a, b, offset, d)
+			}
+		}
+	}
+	return replace(a, b)
+}
+
+func backtrack(trace [][]int, a, b []string, offset, d int) []Line {
+	var rev []Line
+	x, y := len(a), len(b)
+	for ; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			rev = append(rev, Line{Equal, a[x]})
+		}
+		if x == prevX {
+			y--
+			rev = append(rev, Line{Insert, b[y]})
+		} else {
+			x--
+			rev = append(rev, Line{Delete, a[x]})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		rev = append(rev, Line{Equal, a[x]})
+	}
+	out := make([]Line, len(rev))
+	for i, l := range rev {
+		out[len(rev)-1-i] = l
+	}
+	return out
+}
+
+func replace(a, b []string) []Line {
+	out := make([]Line, 0, len(a)+len(b))
+	for _, s := range a {
+		out = append(out, Line{Delete, s})
+	}
+	for _, s := range b {
+		out = append(out, Line{Insert, s})
+	}
+	return out
+}
+
+// group splits an edit script into hunks separated by more than 2*context
+// unchanged lines.
+func group(lines []Line, context int) []Hunk {
+	var hunks []Hunk
+	oldLine, newLine := 1, 1
+	i := 0
+	for i < len(lines) {
+		if lines[i].Op == Equal {
+			i++
+			oldLine++
+			newLine++
+			continue
+		}
+		// back up over the leading context
+		start := i
+		for start > 0 && i-start < context && lines[start-1].Op == Equal {
+			start--
+		}
+		h := Hunk{OldStart: oldLine - (i - start), NewStart: newLine - (i - start)}
+		// extend until a run of unchanged lines longer than 2*context
+		end := i
+		for end < len(lines) {
+			if lines[end].Op != Equal {
+				end++
+				continue
+			}
+			run := end
+			for run < len(lines) && lines[run].Op == Equal {
+				run++
+			}
+			if run == len(lines) || run-end > 2*context {
+				end += min(context, run-end)
+				break
+			}
+			end = run
+		}
+		for _, l := range lines[start:end] {
+			h.Lines = append(h.Lines, l)
+			if l.Op != Insert {
+				h.OldLines++
+			}
+			if l.Op != Delete {
+				h.NewLines++
+			}
+		}
+		// advance the counters over the hunk body
+		for _, l := range lines[i:end] {
+			if l.Op != Insert {
+				oldLine++
+			}
+			if l.Op != Delete {
+				newLine++
+			}
+		}
+		if h.OldLines == 0 {
+			h.OldStart--
+		}
+		if h.NewLines == 0 {
+			h.NewStart--
+		}
+		hunks = append(hunks, h)
+		i = end
+	}
+	return hunks
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,292 @@
+// Package dupkeys finds keys repeated within one mapping of a YAML or JSON
+// document and resolves them. Decoding into Go maps hides duplicates (JSON
+// silently keeps the last value, YAML refuses the document), so detection
+// works on yaml.Node trees and on a streaming JSON token scan instead.
+package dupkeys
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Position is a 1-based line and column.
+type Position struct {
+	Line   int
+	Column int
+}
+
+// Duplicate is a key defined again in the mapping where it first appeared.
+type Duplicate struct {
+	// Path is the JSON pointer of the key, e.g. "/metadata/labels/app".
+	Path  string
+	Key   string
+	First Position
+	// Second is the position of the repeated key.
+	Second Position
+}
+
+// Mode selects how Resolve handles a duplicated key.
+type Mode string
+
+const (
+	// KeepFirst keeps the first occurrence and drops the others.
+	KeepFirst Mode = "keep-first"
+	// KeepLast keeps the last occurrence, matching what JSON decoders do.
+	KeepLast Mode = "keep-last"
+	// Merge merges mapping values recursively at the first occurrence; for
+	// any other value the last occurrence wins.
+	Merge Mode = "merge"
+)
+
+// Modes lists the accepted modes.
+var Modes = []Mode{KeepFirst, KeepLast, Merge}
+
+// ParseMode validates a mode name; "" selects KeepLast.
+func ParseMode(s string) (Mode, error) {
+	if s == "" {
+		return KeepLast, nil
+	}
+	for _, m := range Modes {
+		if string(m) == s {
+			return m, nil
+		}
+	}
+	return "", fmt.Errorf("unknown duplicate-key mode %q (want keep-first, keep-last or merge)", s)
+}
+
+// FindYAML returns every duplicated key in the tree, in document order.
+// Aliases are not followed, and merge keys ("<<") are not keys.
+func FindYAML(node *yaml.Node) []Duplicate {
+	var out []Duplicate
+	var walk func(n *yaml.Node, path string)
+	walk = func(n *yaml.Node, path string) {
+		if n == nil {
+			return
+		}
+		switch n.Kind {
+		case yaml.MappingNode:
+			seen := map[string]*yaml.Node{}
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				k := n.Content[i]
+				child := path + "/" + escape(k.Value)
+				if isKey(k) {
+					if first, ok := seen[k.Value]; ok {
+						out = append(out, Duplicate{
+							Path:   child,
+							Key:    k.Value,
+							First:  Position{first.Line, first.Column},
+							Second: Position{k.Line, k.Column},
+						})
+					} else {
+						seen[k.Value] = k
+					}
+				}
+				walk(n.Content[i+1], child)
+			}
+		case yaml.SequenceNode:
+			for i, c := range n.Content {
+				walk(c, path+"/"+strconv.Itoa(i))
+			}
+		default:
+			for _, c := range n.Content {
+				walk(c, path)
+			}
+		}
+	}
+	walk(node, "")
+	return out
+}
+
+// FindJSON scans JSON content token by token and returns every duplicated
+// object key in document order. The error is that of the JSON decoder when
+// the content is not valid JSON.
+func FindJSON(content string) ([]Duplicate, error) {
+	data := []byte(content)
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	// frame is an open object or array
+	type frame struct {
+		object bool
+		path   string
+		seen   map[string]Position
+		index  int
+		key    string
+		// expectKey is true in an object when the next token is a key
+		expectKey bool
+	}
+	var stack []*frame
+	var out []Duplicate
+
+	// childPath names the value about to be read in the innermost container
+	childPath := func() string {
+		if len(stack) == 0 {
+			return ""
+		}
+		top := stack[len(stack)-1]
+		if top.object {
+			return top.path + "/" + escape(top.key)
+		}
+		return top.path + "/" + strconv.Itoa(top.index)
+	}
+	// valueDone advances the innermost container past a complete value
+	valueDone := func() {
+		if len(stack) == 0 {
+			return
+		}
+		top := stack[len(stack)-1]
+		if top.object {
+			top.expectKey = true
+		} else {
+			top.index++
+		}
+	}
+
+	for {
+		before := dec.InputOffset()
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if len(stack) > 0 && stack[len(stack)-1].object && stack[len(stack)-1].expectKey {
+			if key, ok := tok.(string); ok {
+				top := stack[len(stack)-1]
+				pos := offsetPosition(data, keyStart(data, before))
+				if first, dup := top.seen[key]; dup {
+					out = append(out, Duplicate{Path: top.path + "/" + escape(key), Key: key, First: first, Second: pos})
+				} else {
+					top.seen[key] = pos
+				}
+				top.key = key
+				top.expectKey = false
+				continue
+			}
+		}
+
+		switch tok {
+		case json.Delim('{'):
+			stack = append(stack, &frame{object: true, path: childPath(), seen: map[string]Position{}, expectKey: true})
+		case json.Delim('['):
+			stack = append(stack, &frame{path: childPath()})
+		case json.Delim('}'), json.Delim(']'):
+			stack = stack[:len(stack)-1]
+			valueDone()
+		default:
+			valueDone()
+		}
+	}
+	return out, nil
+}
+
+// keyStart returns the offset of the opening quote of the key read after
+// offset: the decoder's offset sits before any separator and whitespace.
+func keyStart(data []byte, offset int64) int64 {
+	for offset < int64(len(data)) && data[offset] != '"' {
+		offset++
+	}
+	return offset
+}
+
+func offsetPosition(data []byte, offset int64) Position {
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	lineStart := bytes.LastIndexByte(before, '\n') + 1
+	return Position{Line: line, Column: len([]rune(string(before[lineStart:]))) + 1}
+}
+
+// Resolve removes duplicated keys from every mapping of the tree according
+// to mode and returns how many occurrences it removed. A head comment above a
+// removed key moves to the next key that is kept.
+func Resolve(node *yaml.Node, mode Mode) int {
+	if node == nil {
+		return 0
+	}
+	removed := 0
+	if node.Kind == yaml.MappingNode {
+		removed += resolveMapping(node, mode)
+	}
+	// children are visited after their parent so that mappings joined by
+	// Merge are resolved too
+	for _, c := range node.Content {
+		removed += Resolve(c, mode)
+	}
+	return removed
+}
+
+func resolveMapping(n *yaml.Node, mode Mode) int {
+	last := map[string]int{}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if isKey(n.Content[i]) {
+			last[n.Content[i].Value] = i
+		}
+	}
+
+	var kept []*yaml.Node
+	at := map[string]int{} // key -> index of its pair in kept
+	comment := ""
+	removed := 0
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+		if isKey(k) {
+			j, seen := at[k.Value]
+			drop := false
+			switch mode {
+			case KeepFirst:
+				drop = seen
+			case Merge:
+				if seen {
+					prev := kept[j+1]
+					if prev.Kind == yaml.MappingNode && v.Kind == yaml.MappingNode {
+						prev.Content = append(prev.Content, v.Content...)
+					} else {
+						kept[j+1] = v
+					}
+					drop = true
+				}
+			default:
+				drop = last[k.Value] != i
+			}
+			if drop {
+				comment = joinComments(comment, k.HeadComment)
+				removed++
+				continue
+			}
+			at[k.Value] = len(kept)
+		}
+		k.HeadComment = joinComments(comment, k.HeadComment)
+		comment = ""
+		kept = append(kept, k, v)
+	}
+	if comment != "" {
+		n.FootComment = joinComments(n.FootComment, comment)
+	}
+	n.Content = kept
+	return removed
+}
+
+func isKey(k *yaml.Node) bool {
+	return k.Kind == yaml.ScalarNode && k.ShortTag() != "!!merge"
+}
+
+func joinComments(a, b string) string {
+	if a == "" || b == "" {
+		return a + b
+	}
+	return a + "\n" + b
+}
+
+// escape encodes a key as a JSON pointer token.
+func escape(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
@@ -1,14 +1,12 @@
 package fixer
 
 import (
-	"encoding/json"
 	"fmt"
-	"log"
 	"regexp"
-	"strconv"
 	"strings"
 
-	"gopkg.in/yaml.v3"
+	"devformat/backend/internal/json5"
+	"devformat/backend/internal/parser"
 )
 
 // CanAutoFixContent checks if content is safe for auto-fix.
@@ -30,211 +28,199 @@ func CanAutoFixContent(content string) (bool, string) {
 	return true, ""
 }
 
-// TryFixYAML attempts to fix YAML formatting and returns a parsed map on success.
-func TryFixYAML(content string) (map[string]any, error) {
-	var m map[string]any
-	if err := yaml.Unmarshal([]byte(content), &m); err == nil {
-		return m, nil
-	}
+// TryFixYAML attempts to fix YAML formatting and returns the parsed value on
+// success. The root may be a mapping, a sequence or a scalar.
+func TryFixYAML(content string) (any, error) {
+	return TryFixYAMLIndent(content, 2)
+}
 
-	content = preprocessYAML(content)
-	lines := strings.Split(content, "\n")
-	var fixed []string
+// TryFixYAMLIndent is TryFixYAML for content indented by indent spaces per
+// level, as configured by a repository's .devformat.yaml.
+func TryFixYAMLIndent(content string, indent int) (any, error) {
+	repaired, err := RepairYAML(content, indent)
+	if err != nil {
+		return nil, err
+	}
+	node, err := parser.ParseYAMLNode(repaired)
+	if err != nil {
+		return nil, err
+	}
+	var v any
+	err = node.Decode(&v)
+	return v, err
+}
 
-	for _, raw := range lines {
-		if strings.TrimSpace(raw) == "" {
-			fixed = append(fixed, "")
-			continue
-		}
+// maxRepairs bounds the number of lines RepairYAML rewrites.
+const maxRepairs = 100
+
+// RepairYAML re-indents the lines that keep content from parsing until it
+// parses and returns the repaired text. Each round finds the first line the
+// content stops parsing at and rewrites its indentation, or that of the line
+// before it, keeping every other line as it is: tabs, trailing spaces and
+// block scalars included. Content that already parses is returned
+// unchanged.
+func RepairYAML(content string, indent int) (string, error) {
+	if indent <= 0 {
+		indent = 2
+	}
+	err := parsesYAML(content)
+	if err == nil {
+		return content, nil
+	}
 
-		raw = strings.TrimRight(raw, " \t")
-		leading := len(raw) - len(strings.TrimLeft(raw, " "))
-		if leading%2 != 0 {
-			leading--
-		}
-		if leading < 0 {
-			leading = 0
-		}
-		trimmed := strings.TrimSpace(raw)
-
-		// If previous line ends with ':', ensure +2 indent
-		if len(fixed) > 0 {
-			prev := fixed[len(fixed)-1]
-			prevLead := len(prev) - len(strings.TrimLeft(prev, " "))
-			if strings.HasSuffix(strings.TrimSpace(prev), ":") && leading < prevLead+2 {
-				leading = prevLead + 2
-			}
+	lines := strings.Split(content, "\n")
+	for n := 0; n < maxRepairs; n++ {
+		i, line, ok := repairLine(lines, indent, err)
+		if !ok {
+			break
 		}
-
-		// For list items, align under parent if needed
-		if strings.HasPrefix(trimmed, "- ") && len(fixed) > 0 {
-			prev := fixed[len(fixed)-1]
-			prevLead := len(prev) - len(strings.TrimLeft(prev, " "))
-			if strings.HasSuffix(strings.TrimSpace(prev), ":") && leading < prevLead+2 {
-				leading = prevLead + 2
-			}
+		lines[i] = line
+		fixed := strings.Join(lines, "\n")
+		if parsesYAML(fixed) == nil {
+			return fixed, nil
 		}
-
-		fixed = append(fixed, strings.Repeat(" ", leading)+trimmed)
 	}
+	return "", err
+}
 
-	fixedContent := strings.Join(fixed, "\n")
-	var result map[string]any
-	err := yaml.Unmarshal([]byte(fixedContent), &result)
-	if err == nil {
-		return result, nil
-	}
-
-	// targeted heuristic: if parser complains about missing '-' indicator,
-	// try inserting a list marker at the reported line aligned under parent
-	if strings.Contains(err.Error(), "did not find expected '-' indicator") {
-		lineRe := regexp.MustCompile(`line (\d+)`)
-		m := lineRe.FindStringSubmatch(err.Error())
-		if len(m) == 2 {
-			if idx, perr := strconv.Atoi(m[1]); perr == nil {
-				i := idx - 1
-				if i >= 0 && i < len(lines) {
-					prev := i - 1
-					for prev >= 0 && strings.TrimSpace(lines[prev]) == "" {
-						prev--
-					}
-					prevLead := 0
-					if prev >= 0 {
-						prevLead = len(lines[prev]) - len(strings.TrimLeft(lines[prev], " "))
-					}
-					newLead := prevLead + 2
-					if !strings.HasPrefix(strings.TrimSpace(lines[i]), "-") {
-						lines[i] = strings.Repeat(" ", newLead) + "- " + strings.TrimSpace(lines[i])
-						fixed2 := strings.Join(lines, "\n")
-						var result2 map[string]any
-						if err2 := yaml.Unmarshal([]byte(fixed2), &result2); err2 == nil {
-							return result2, nil
-						}
-					}
-				}
-			}
+// repairLine finds the first line the content stops parsing at and returns
+// a rewrite of it, or of the line before it, that makes the content parse,
+// else one that makes the lines up to it parse. It reports false when there
+// is none.
+func repairLine(lines []string, indent int, err error) (int, string, bool) {
+	// the empty prefix parses and the whole content does not; bisect for
+	// the line in between
+	lo, hi := 0, len(lines)
+	for hi-lo > 1 {
+		mid := (lo + hi) / 2
+		if parsesYAML(strings.Join(lines[:mid], "\n")) == nil {
+			lo = mid
+		} else {
+			hi = mid
 		}
 	}
+	at := hi - 1
 
-	// FINAL SOLUTION: Smart YAML indentation fixer for "did not find expected key"
-	if strings.Contains(err.Error(), "did not find expected key") || strings.Contains(err.Error(), "mapping values are not allowed in this context") {
-		log.Printf("TryFixYAML: parser error: %s", err.Error())
-		lineRe := regexp.MustCompile(`line (\d+)`)
-		m := lineRe.FindStringSubmatch(err.Error())
-		reported := -1
-		if len(m) == 2 {
-			if idx, perr := strconv.Atoi(m[1]); perr == nil {
-				reported = idx - 1
-			}
-		}
+	targets := []int{at}
+	prev := at - 1
+	for prev >= 0 && strings.TrimSpace(lines[prev]) == "" {
+		prev--
+	}
+	if prev >= 0 {
+		targets = append(targets, prev)
+	}
+	inBlock := blockScalarLines(lines)
 
-		candidatesIdx := []int{}
-		if reported >= 0 {
-			for j := reported - 2; j <= reported+2; j++ {
-				if j >= 0 && j < len(lines) {
-					candidatesIdx = append(candidatesIdx, j)
-				}
-			}
-		} else {
-			for i := range lines {
-				if strings.Contains(lines[i], ":") {
-					candidatesIdx = append(candidatesIdx, i)
-				}
-			}
+	found, foundLine := -1, ""
+	for _, i := range targets {
+		if inBlock[i] || strings.TrimSpace(lines[i]) == "" {
+			continue
 		}
-
-		computeCandidates := func(i int) []int {
-			res := []int{0, 2, 4, 6}
-			parent := i - 1
-			for parent >= 0 && strings.TrimSpace(lines[parent]) == "" {
-				parent--
+		orig := lines[i]
+		for _, candidate := range lineCandidates(lines, i, indent, err) {
+			if candidate == orig {
+				continue
 			}
-			if parent >= 0 {
-				pLead := len(lines[parent]) - len(strings.TrimLeft(lines[parent], " "))
-				res = append(res, pLead+2)
-				if strings.HasPrefix(strings.TrimSpace(lines[parent]), "- ") {
-					res = append(res, pLead+2)
-					res = append(res, pLead+4)
-				}
+			lines[i] = candidate
+			whole := parsesYAML(strings.Join(lines, "\n")) == nil
+			prefix := whole || parsesYAML(strings.Join(lines[:at+1], "\n")) == nil
+			lines[i] = orig
+			if whole {
+				return i, candidate, true
 			}
-
-			for k := i - 5; k <= i+5; k++ {
-				if k < 0 || k >= len(lines) || k == i {
-					continue
-				}
-				if strings.TrimSpace(lines[k]) == "" {
-					continue
-				}
-				if strings.Contains(lines[k], ":") {
-					sLead := len(lines[k]) - len(strings.TrimLeft(lines[k], " "))
-					res = append(res, sLead)
-					res = append(res, sLead+2)
-				}
+			if prefix && found < 0 {
+				found, foundLine = i, candidate
 			}
+		}
+	}
+	return found, foundLine, found >= 0
+}
 
-			seen := map[int]bool{}
-			out := []int{}
-			for _, v := range res {
-				if v < 0 || v > 40 {
-					continue
-				}
-				if !seen[v] {
-					seen[v] = true
-					out = append(out, v)
-				}
-			}
-			return out
+// lineCandidates returns rewrites of line i that only change its leading
+// whitespace, most likely first: its own indentation with tabs expanded and
+// rounded to the indent width, one level below its parent, the indentation
+// of nearby keys and the first few levels. When the parser expects a list
+// item, the line is also offered as one.
+func lineCandidates(lines []string, i, indent int, err error) []string {
+	raw := lines[i]
+	body := strings.TrimLeft(raw, " \t")
+	width := 0
+	for _, r := range raw[:len(raw)-len(body)] {
+		if r == '\t' {
+			width += indent
+		} else {
+			width++
 		}
+	}
+	lead := width - width%indent
 
-		for _, idx := range candidatesIdx {
-			trimmed := strings.TrimSpace(lines[idx])
-			if trimmed == "" || !strings.Contains(trimmed, ":") {
-				continue
-			}
-			candidates := computeCandidates(idx)
-			log.Printf("TryFixYAML: trying line %d candidates: %v (trimmed=%q)", idx+1, candidates, trimmed)
-			for _, sp := range candidates {
-				linesCopy := make([]string, len(lines))
-				copy(linesCopy, lines)
-				linesCopy[idx] = strings.Repeat(" ", sp) + trimmed
-				testYAML := strings.Join(linesCopy, "\n")
-				var testResult map[string]any
-				if yaml.Unmarshal([]byte(testYAML), &testResult) == nil {
-					log.Printf("TryFixYAML: success on line %d with indent %d", idx+1, sp)
-					return testResult, nil
-				}
+	parent := i - 1
+	for parent >= 0 && (strings.TrimSpace(lines[parent]) == "" || strings.HasPrefix(strings.TrimSpace(lines[parent]), "#")) {
+		parent--
+	}
+	parentLead := 0
+	var leads []int
+	if parent >= 0 {
+		parentLead = leadingSpaces(lines[parent])
+		trimmed := strings.TrimSpace(lines[parent])
+		if strings.HasSuffix(trimmed, ":") {
+			// a key without a value opens a level
+			if lead < parentLead+indent {
+				lead = parentLead + indent
 			}
+			leads = append(leads, parentLead+indent)
 		}
+		if strings.HasPrefix(trimmed, "- ") {
+			leads = append(leads, parentLead+2, parentLead+2+indent)
+		}
+		leads = append(leads, parentLead)
 	}
+	for k := i - 5; k <= i+5; k++ {
+		if k < 0 || k >= len(lines) || k == i || !strings.Contains(lines[k], ":") {
+			continue
+		}
+		leads = append(leads, leadingSpaces(lines[k]), leadingSpaces(lines[k])+indent)
+	}
+	leads = append([]int{lead}, append(leads, 0, indent, 2*indent, 3*indent)...)
 
-	return result, err
-}
-
-// TryFixJSON attempts to fix JSON formatting
-func TryFixJSON(content string) (map[string]any, error) {
-	var m map[string]any
-	if err := json.Unmarshal([]byte(content), &m); err == nil {
-		return m, nil
+	var out []string
+	seen := map[int]bool{}
+	for _, n := range leads {
+		if n < 0 || n > 40 || seen[n] {
+			continue
+		}
+		seen[n] = true
+		out = append(out, strings.Repeat(" ", n)+body)
 	}
+	if strings.Contains(err.Error(), "did not find expected '-' indicator") && !strings.HasPrefix(body, "-") {
+		out = append(out, strings.Repeat(" ", parentLead+indent)+"- "+body)
+	}
+	return out
+}
 
-	// Basic JSON fixes: remove trailing commas, fix quotes
-	content = strings.ReplaceAll(content, ",}", "}")
-	content = strings.ReplaceAll(content, ",]", "]")
-	content = regexp.MustCompile(`'([^']*)':`).ReplaceAllString(content, `"$1":`)
+// leadingSpaces counts the spaces that start line.
+func leadingSpaces(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
 
-	var result map[string]any
-	err := json.Unmarshal([]byte(content), &result)
-	return result, err
+// parsesYAML reports why content does not parse as YAML, or nil when it
+// does. Any root type is accepted, and so are duplicate keys; they are a
+// separate fix.
+func parsesYAML(content string) error {
+	_, err := parser.ParseYAMLNode(content)
+	return err
 }
 
-// preprocessYAML normalizes whitespace (internal helper)
-func preprocessYAML(s string) string {
-	s = strings.ReplaceAll(s, "\r\n", "\n")
-	s = strings.ReplaceAll(s, "\r", "\n")
-	s = strings.ReplaceAll(s, "\t", "  ")
-	lines := strings.Split(s, "\n")
-	for i := range lines {
-		lines[i] = strings.TrimRight(lines[i], " \t")
+// TryFixJSON repairs JSON leniently (comments, trailing commas, single
+// quotes, unquoted keys, hex numbers; see package json5) and decodes it. The
+// root keeps its type: an object decodes to map[string]any, an array to
+// []any and a scalar to its Go value.
+func TryFixJSON(content string) (any, error) {
+	node, _, err := json5.Parse(content)
+	if err != nil {
+		return nil, err
 	}
-	return strings.Join(lines, "\n")
+	var v any
+	err = Root(node).Decode(&v)
+	return v, err
 }
@@ -0,0 +1,52 @@
+package fixer
+
+import "testing"
+
+func TestRepairYAML(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr bool
+	}{
+		{"valid is unchanged", "a: 1\nb:\n   c: 2\n", "a: 1\nb:\n   c: 2\n", false},
+		{"dedented sibling", "a:\n  b: 1\n c: 2\n", "a:\n  b: 1\nc: 2\n", false},
+		{"over-indented sibling", "a:\n  b: 1\n   c: 2\n", "a:\n  b: 1\n  c: 2\n", false},
+		{"tab indentation", "a:\n\tb: 1\n", "a:\n  b: 1\n", false},
+		{"sequence item key", "spec:\n  containers:\n  - name: x\n     image: y\n", "spec:\n  containers:\n  - name: x\n    image: y\n", false},
+		{"block scalar kept", "a: |\n  text\n   more\nb: 1\n", "a: |\n  text\n   more\nb: 1\n", false},
+		{"other lines kept", "# c\na:   1 \nb:\n  c: 1\n d: 2\n", "# c\na:   1 \nb:\n  c: 1\nd: 2\n", false},
+		{"unclosed flow sequence", "a: [1, 2\n", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RepairYAML(tt.content, 2)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RepairYAML() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("RepairYAML() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanAutoFixContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    bool
+	}{
+		{"one pair per line", "a: 1\nb: 2\n", true},
+		{"colon in value", "image: nginx:1.14.2\n", true},
+		{"pairs in comment", "# a: 1 b: 2\nc: 3\n", true},
+		{"two pairs on a line", "a: 1 b: 2\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, reason := CanAutoFixContent(tt.content); got != tt.want {
+				t.Errorf("CanAutoFixContent() = %v (%s), want %v", got, reason, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package fixer
+
+import (
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"devformat/backend/internal/dupkeys"
+)
+
+// Built-in fixers, named after the fix types of the /api/fix request. Text
+// fixers run first so that the node fixers see a document that parses.
+func init() {
+	Register(Fixer{
+		ID:          "trailing-spaces",
+		Description: "Remove spaces and tabs at the end of lines.",
+		Order:       10,
+		Text:        fixTrailingSpaces,
+	})
+	Register(Fixer{
+		ID:          "empty-lines",
+		Description: "Drop blank lines at the start and end of a document and collapse runs of more than two.",
+		Order:       20,
+		Text:        fixEmptyLines,
+	})
+	Register(Fixer{
+		ID:          "indentation",
+		Description: "Re-indent the lines that keep a document from parsing.",
+		Order:       30,
+		Text:        func(content string, s Settings) (string, error) { return RepairYAML(content, s.Indent) },
+	})
+	Register(Fixer{
+		ID:          "duplicate-keys",
+		Description: "Resolve keys repeated in the same mapping by keeping the first or last occurrence or merging them (default keep-last).",
+		Order:       40,
+		Node:        fixDuplicateKeys,
+	})
+	Register(Fixer{
+		ID:          "quotes",
+		Description: "Use double quotes for single-quoted strings.",
+		Order:       50,
+		Node:        fixQuotes,
+	})
+	Register(Fixer{
+		ID:          "boolean-format",
+		Description: "Rewrite YAML 1.1 truthy values such as yes/no/on/off as true or false.",
+		Order:       60,
+		Node:        fixBooleans,
+	})
+}
+
+// maxEmptyLines is the longest run of blank lines empty-lines keeps, as in
+// yamllint's default.
+const maxEmptyLines = 2
+
+// truthyValues maps plain scalars YAML 1.1 treats as booleans to their
+// canonical form.
+var truthyValues = map[string]string{
+	"YES": "true", "Yes": "true", "yes": "true", "NO": "false", "No": "false", "no": "false",
+	"TRUE": "true", "True": "true", "FALSE": "false", "False": "false",
+	"ON": "true", "On": "true", "on": "true", "OFF": "false", "Off": "false", "off": "false",
+}
+
+// blockHeaderRe matches a line that opens a literal or folded block scalar.
+var blockHeaderRe = regexp.MustCompile(`(^|\s)[|>][-+0-9]*\s*(#.*)?$`)
+
+// fixTrailingSpaces leaves the lines of block scalars alone, since their
+// trailing whitespace is part of the value.
+func fixTrailingSpaces(content string, _ Settings) (string, error) {
+	lines := strings.Split(content, "\n")
+	inBlock := blockScalarLines(lines)
+	for i := range lines {
+		if inBlock[i] {
+			continue
+		}
+		lines[i] = strings.TrimRight(lines[i], " \t")
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// fixEmptyLines leaves blank lines inside block scalars alone, since they are
+// part of the value.
+func fixEmptyLines(content string, _ Settings) (string, error) {
+	lines := strings.Split(content, "\n")
+	inBlock := blockScalarLines(lines)
+	var out []string
+	run := 0
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" || inBlock[i] {
+			run = 0
+			out = append(out, line)
+			continue
+		}
+		run++
+		if len(out) > 0 && run <= maxEmptyLines {
+			out = append(out, "")
+		}
+	}
+	for len(out) > 0 && out[len(out)-1] == "" {
+		out = out[:len(out)-1]
+	}
+	return strings.Join(out, "\n"), nil
+}
+
+// blockScalarLines marks the body lines of literal and folded block scalars:
+// the lines after a "|" or ">" header that are blank or indented deeper than
+// the header. Blank lines trailing a block are not part of it.
+func blockScalarLines(lines []string) []bool {
+	inBlock := make([]bool, len(lines))
+	for i := 0; i < len(lines); i++ {
+		if strings.HasPrefix(strings.TrimSpace(lines[i]), "#") || !blockHeaderRe.MatchString(lines[i]) {
+			continue
+		}
+		parent := len(lines[i]) - len(strings.TrimLeft(lines[i], " "))
+		j := i + 1
+		for ; j < len(lines); j++ {
+			if strings.TrimSpace(lines[j]) != "" && len(lines[j])-len(strings.TrimLeft(lines[j], " ")) <= parent {
+				break
+			}
+			inBlock[j] = true
+		}
+		for k := j - 1; k > i && strings.TrimSpace(lines[k]) == ""; k-- {
+			inBlock[k] = false
+		}
+		i = j - 1
+	}
+	return inBlock
+}
+
+func fixDuplicateKeys(doc *yaml.Node, s Settings) bool {
+	return dupkeys.Resolve(doc, s.DuplicateKeys) > 0
+}
+
+func fixQuotes(doc *yaml.Node, _ Settings) bool {
+	changed := false
+	walkNodes(doc, func(n *yaml.Node) {
+		if n.Kind == yaml.ScalarNode && n.Style&yaml.SingleQuotedStyle != 0 {
+			n.Style = n.Style&^yaml.SingleQuotedStyle | yaml.DoubleQuotedStyle
+			changed = true
+		}
+	})
+	return changed
+}
+
+// fixBooleans rewrites plain truthy values but never mapping keys, which
+// keeps keys such as GitHub Actions' "on" intact.
+func fixBooleans(doc *yaml.Node, _ Settings) bool {
+	changed := false
+	walkNodes(doc, func(n *yaml.Node) {
+		if n.Kind != yaml.MappingNode && n.Kind != yaml.SequenceNode && n.Kind != yaml.DocumentNode {
+			return
+		}
+		for i, c := range n.Content {
+			if n.Kind == yaml.MappingNode && i%2 == 0 {
+				continue
+			}
+			// explicitly tagged scalars carry a style and are left alone
+			canonical, ok := truthyValues[c.Value]
+			if c.Kind != yaml.ScalarNode || c.Style != 0 || !ok {
+				continue
+			}
+			c.Value, c.Tag = canonical, "!!bool"
+			changed = true
+		}
+	})
+	return changed
+}
+
+// walkNodes visits every node of the tree once without following aliases.
+func walkNodes(n *yaml.Node, visit func(n *yaml.Node)) {
+	if n == nil {
+		return
+	}
+	visit(n)
+	for _, c := range n.Content {
+		walkNodes(c, visit)
+	}
+}
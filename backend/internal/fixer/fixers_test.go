@@ -0,0 +1,60 @@
+package fixer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSelect(t *testing.T) {
+	tests := []struct {
+		name    string
+		ids     []string
+		want    string
+		wantErr bool
+	}{
+		{"default", nil, "indentation", false},
+		{"run order", []string{"quotes", "trailing-spaces", "quotes"}, "trailing-spaces,quotes", false},
+		{"all", []string{"quotes", "all"}, "trailing-spaces,empty-lines,indentation,duplicate-keys,quotes,boolean-format", false},
+		{"unknown", []string{"quotes", "tabs"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs, err := Select(tt.ids)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Select() error = %v, want error %v", err, tt.wantErr)
+			}
+			var ids []string
+			for _, f := range fs {
+				ids = append(ids, f.ID)
+			}
+			if got := strings.Join(ids, ","); got != tt.want {
+				t.Errorf("Select() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTextFixers(t *testing.T) {
+	tests := []struct {
+		fixer   string
+		content string
+		want    string
+	}{
+		{"trailing-spaces", "a: 1  \nb: 2\t\n", "a: 1\nb: 2\n"},
+		{"trailing-spaces", "a: |\n  keep  \nb: 1 \n", "a: |\n  keep  \nb: 1\n"},
+		{"empty-lines", "\n\na: 1\n\n\n\n\nb: 2\n\n\n", "a: 1\n\n\nb: 2"},
+		{"empty-lines", "a: |\n  x\n\n\n\n  y\n", "a: |\n  x\n\n\n\n  y"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixer, func(t *testing.T) {
+			f, _ := Lookup(tt.fixer)
+			got, err := f.Text(tt.content, Settings{Indent: 2})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("%s: got %q, want %q", tt.fixer, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package fixer
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EncodeYAMLNode renders node with indent spaces per level.
+func EncodeYAMLNode(node *yaml.Node, indent int) (string, error) {
+	if indent <= 0 {
+		indent = 2
+	}
+	var sb strings.Builder
+	enc := yaml.NewEncoder(&sb)
+	enc.SetIndent(indent)
+	if err := enc.Encode(node); err != nil {
+		return "", err
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// Root returns the top-level value of a parsed document, or nil for a
+// document without content (for example one holding only comments).
+func Root(doc *yaml.Node) *yaml.Node {
+	if doc == nil {
+		return nil
+	}
+	if doc.Kind == yaml.DocumentNode {
+		if len(doc.Content) == 0 {
+			return nil
+		}
+		return doc.Content[0]
+	}
+	if doc.Kind == 0 {
+		return nil
+	}
+	return doc
+}
+
+// MappingValue returns the value stored under key in a mapping node, or nil
+// when node is not a mapping or has no such key.
+func MappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// IsNull reports whether node is absent or an explicit null.
+func IsNull(node *yaml.Node) bool {
+	return node == nil || node.Kind == yaml.ScalarNode && node.Tag == "!!null"
+}
+
+// InsertMappingPair adds key: value to a mapping node directly after the key
+// named after, or at the end when after is "" or missing.
+func InsertMappingPair(node *yaml.Node, after, key string, value *yaml.Node) {
+	pair := []*yaml.Node{{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value}
+	at := len(node.Content)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if after != "" && node.Content[i].Value == after {
+			at = i + 2
+			break
+		}
+	}
+	node.Content = append(node.Content[:at], append(pair, node.Content[at:]...)...)
+}
+
+// StringMapping returns a block mapping node holding the given key/value
+// string pairs in order.
+func StringMapping(pairs ...string) *yaml.Node {
+	m := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		m.Content = append(m.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: pairs[i]},
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: pairs[i+1]})
+	}
+	return m
+}
@@ -0,0 +1,406 @@
+package fixer
+
+import (
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"devformat/backend/internal/parser"
+)
+
+// Snapshot records the nodes of a parsed document as they were before node
+// fixers edited the tree, so that Patch can carry the edits over to the
+// source text instead of re-encoding the whole document.
+type Snapshot map[*yaml.Node]yaml.Node
+
+// TakeSnapshot records doc and every node below it. Content slices are
+// copied; the nodes they hold are the live ones.
+func TakeSnapshot(doc *yaml.Node) Snapshot {
+	s := Snapshot{}
+	walkNodes(doc, func(n *yaml.Node) {
+		c := *n
+		c.Content = append([]*yaml.Node(nil), n.Content...)
+		s[n] = c
+	})
+	return s
+}
+
+// Patch applies the edits made to doc since the snapshot was taken to text,
+// the source doc was parsed from: changed scalars are replaced in place,
+// removed mapping pairs are cut and added ones are inserted at the
+// indentation of their siblings. Every other line is kept byte for byte,
+// comments and blank lines included. It reports false when an edit has no
+// such splice, for example inside a flow collection or a multi-line quoted
+// string; the caller then re-encodes the document.
+func (s Snapshot) Patch(text string, doc *yaml.Node, indent int) (string, bool) {
+	p := &patcher{snap: s, lines: strings.Split(text, "\n"), indent: indent, moved: map[*yaml.Node]bool{}}
+	if !p.collect(doc, false) {
+		return "", false
+	}
+	out, ok := p.apply()
+	if !ok {
+		return "", false
+	}
+	if _, err := parser.ParseYAMLNode(out); err != nil {
+		return "", false
+	}
+	return out, true
+}
+
+type patcher struct {
+	snap    Snapshot
+	lines   []string
+	indent  int
+	scalars []scalarEdit
+	cuts    []lineRange
+	inserts []pairInsert
+	// moved holds the nodes of inserted pairs that came from the source
+	moved map[*yaml.Node]bool
+}
+
+// scalarEdit replaces bytes start..end of a line.
+type scalarEdit struct {
+	line, start, end int
+	text             string
+}
+
+// lineRange is the half-open range of 0-based line indexes [start, end).
+type lineRange struct {
+	start, end int
+}
+
+// pairInsert adds a mapping pair before line index at, indented by col
+// spaces. A pair that came from elsewhere in the source is copied from
+// there; a new one is encoded.
+type pairInsert struct {
+	at, col    int
+	key, value *yaml.Node
+}
+
+// collect records the edits below n. Inside pairs that moved, only scalar
+// edits can be carried over, since the pair is copied from its old lines.
+func (p *patcher) collect(n *yaml.Node, moved bool) bool {
+	before, ok := p.snap[n]
+	if !ok {
+		return false
+	}
+	switch n.Kind {
+	case yaml.ScalarNode:
+		if n.Value != before.Value || n.Style != before.Style || n.Tag != before.Tag {
+			return p.replaceScalar(n, before)
+		}
+		return true
+	case yaml.MappingNode:
+		if !samePointers(n.Content, before.Content) && (moved || !p.diffMapping(n, before)) {
+			return false
+		}
+	default:
+		if !samePointers(n.Content, before.Content) {
+			return false
+		}
+	}
+	for _, c := range n.Content {
+		if _, existed := p.snap[c]; !existed {
+			// new pairs are encoded whole
+			continue
+		}
+		if !p.collect(c, moved || p.moved[c]) {
+			return false
+		}
+	}
+	return true
+}
+
+// diffMapping records the pairs removed from and added to a block mapping.
+// Kept pairs must keep their values and their order.
+func (p *patcher) diffMapping(n *yaml.Node, before yaml.Node) bool {
+	if n.Style&yaml.FlowStyle != 0 || len(before.Content) == 0 {
+		return false
+	}
+	col := before.Content[0].Column - 1
+	index := map[*yaml.Node]int{}
+	for i := 0; i+1 < len(before.Content); i += 2 {
+		index[before.Content[i]] = i
+	}
+
+	kept := map[*yaml.Node]bool{}
+	last := -1
+	at := -1 // where pairs added after the last kept pair go
+	var pending []*yaml.Node
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+		j, ok := index[k]
+		if !ok {
+			pending = append(pending, k, v)
+			continue
+		}
+		if j < last || before.Content[j+1] != v {
+			return false
+		}
+		last = j
+		kept[k] = true
+		if len(pending) > 0 {
+			if at < 0 {
+				// nothing kept precedes them: go above this key and
+				// the comments that belong to it
+				if k.Line == 0 || indentation(p.lines[k.Line-1]) != col {
+					return false
+				}
+				at = p.headComments(p.lines, k.Line-1)
+			}
+			p.addInserts(at, col, pending)
+			pending = nil
+		}
+		r, ok := p.extent(k)
+		if !ok {
+			return false
+		}
+		at = r.end
+	}
+	if len(pending) > 0 {
+		if at < 0 {
+			return false
+		}
+		p.addInserts(at, col, pending)
+	}
+
+	for i := 0; i+1 < len(before.Content); i += 2 {
+		k := before.Content[i]
+		if kept[k] {
+			continue
+		}
+		r, ok := p.extent(k)
+		if !ok || indentation(p.lines[r.start]) != col {
+			return false
+		}
+		p.cuts = append(p.cuts, r)
+	}
+	return true
+}
+
+func (p *patcher) addInserts(at, col int, pairs []*yaml.Node) {
+	for i := 0; i+1 < len(pairs); i += 2 {
+		p.inserts = append(p.inserts, pairInsert{at: at, col: col, key: pairs[i], value: pairs[i+1]})
+		if pairs[i].Line > 0 {
+			p.moved[pairs[i]], p.moved[pairs[i+1]] = true, true
+		}
+	}
+}
+
+// extent returns the lines of the pair keyed by key: its own line and the
+// following ones indented deeper, or at the same depth for the items of a
+// compact sequence. Trailing blank lines are not part of it.
+func (p *patcher) extent(key *yaml.Node) (lineRange, bool) {
+	if key.Line == 0 || key.Line > len(p.lines) {
+		return lineRange{}, false
+	}
+	col := key.Column - 1
+	start := key.Line - 1
+	end := start + 1
+	for i := end; i < len(p.lines); i++ {
+		trimmed := strings.TrimSpace(p.lines[i])
+		if trimmed == "" {
+			continue
+		}
+		ind := indentation(p.lines[i])
+		if ind < col || ind == col && trimmed != "-" && !strings.HasPrefix(trimmed, "- ") {
+			break
+		}
+		end = i + 1
+	}
+	return lineRange{start, end}, true
+}
+
+// headComments returns the first of the comment lines directly above line
+// i at its indentation, or i when there are none.
+func (p *patcher) headComments(lines []string, i int) int {
+	col := indentation(lines[i])
+	for i > 0 && indentation(lines[i-1]) == col && strings.HasPrefix(strings.TrimSpace(lines[i-1]), "#") {
+		i--
+	}
+	return i
+}
+
+// replaceScalar records the replacement of a single-line scalar token.
+func (p *patcher) replaceScalar(n *yaml.Node, before yaml.Node) bool {
+	if n.Line == 0 || n.Line > len(p.lines) {
+		return false
+	}
+	line := p.lines[n.Line-1]
+	start := runeOffset(line, n.Column-1)
+	if start < 0 {
+		return false
+	}
+	length := tokenLength(line[start:], before)
+	if length < 0 {
+		return false
+	}
+	out, err := EncodeYAMLNode(&yaml.Node{Kind: yaml.ScalarNode, Style: n.Style, Tag: n.Tag, Value: n.Value}, p.indent)
+	out = strings.TrimSuffix(out, "\n")
+	if err != nil || strings.Contains(out, "\n") {
+		return false
+	}
+	p.scalars = append(p.scalars, scalarEdit{line: n.Line - 1, start: start, end: start + length, text: out})
+	return true
+}
+
+// tokenLength returns the length in bytes of the plain or quoted scalar at
+// the start of s, or -1 when it is not there or does not end on the line.
+func tokenLength(s string, n yaml.Node) int {
+	switch {
+	case n.Style == 0:
+		if n.Value != "" && strings.HasPrefix(s, n.Value) {
+			return len(n.Value)
+		}
+	case n.Style&yaml.SingleQuotedStyle != 0 && strings.HasPrefix(s, "'"):
+		for i := 1; i < len(s); i++ {
+			if s[i] != '\'' {
+				continue
+			}
+			if i+1 < len(s) && s[i+1] == '\'' {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	case n.Style&yaml.DoubleQuotedStyle != 0 && strings.HasPrefix(s, `"`):
+		for i := 1; i < len(s); i++ {
+			switch s[i] {
+			case '\\':
+				i++
+			case '"':
+				return i + 1
+			}
+		}
+	}
+	return -1
+}
+
+// apply performs the recorded edits on a copy of the lines: scalars first,
+// which keeps line numbers, then cuts and inserts from the bottom up.
+func (p *patcher) apply() (string, bool) {
+	lines := append([]string(nil), p.lines...)
+	sort.Slice(p.scalars, func(i, j int) bool {
+		a, b := p.scalars[i], p.scalars[j]
+		if a.line != b.line {
+			return a.line < b.line
+		}
+		return a.start > b.start
+	})
+	for i, e := range p.scalars {
+		if i > 0 && p.scalars[i-1].line == e.line && p.scalars[i-1].start < e.end {
+			return "", false
+		}
+		lines[e.line] = lines[e.line][:e.start] + e.text + lines[e.line][e.end:]
+	}
+
+	type op struct {
+		at, end int
+		lines   []string
+		seq     int
+	}
+	var ops []op
+	for _, c := range p.cuts {
+		ops = append(ops, op{at: c.start, end: c.end})
+	}
+	for i, ins := range p.inserts {
+		text, ok := p.pairLines(lines, ins)
+		if !ok {
+			return "", false
+		}
+		ops = append(ops, op{at: ins.at, end: ins.at, lines: text, seq: i})
+	}
+	// bottom up; at the same line cuts go before inserts, and inserts in
+	// reverse so that they end up in order
+	sort.Slice(ops, func(i, j int) bool {
+		a, b := ops[i], ops[j]
+		if a.at != b.at {
+			return a.at > b.at
+		}
+		if (a.end > a.at) != (b.end > b.at) {
+			return a.end > a.at
+		}
+		return a.seq > b.seq
+	})
+	floor := len(lines)
+	for _, o := range ops {
+		if o.end > floor {
+			return "", false
+		}
+		floor = o.at
+		lines = append(lines[:o.at], append(o.lines, lines[o.end:]...)...)
+	}
+	return strings.Join(lines, "\n"), true
+}
+
+// pairLines returns the lines of an inserted pair: copied from the source
+// and shifted to the new depth when the pair moved, encoded otherwise.
+func (p *patcher) pairLines(lines []string, ins pairInsert) ([]string, bool) {
+	if ins.key.Line > 0 {
+		r, ok := p.extent(ins.key)
+		if !ok || indentation(lines[r.start]) != ins.key.Column-1 {
+			return nil, false
+		}
+		shift := ins.col - (ins.key.Column - 1)
+		out := make([]string, 0, r.end-r.start)
+		for _, line := range lines[p.headComments(lines, r.start):r.end] {
+			switch {
+			case strings.TrimSpace(line) == "":
+			case shift > 0:
+				line = strings.Repeat(" ", shift) + line
+			case shift < 0:
+				if indentation(line) < -shift {
+					return nil, false
+				}
+				line = line[-shift:]
+			}
+			out = append(out, line)
+		}
+		return out, true
+	}
+
+	text, err := EncodeYAMLNode(&yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{ins.key, ins.value}}, p.indent)
+	if err != nil {
+		return nil, false
+	}
+	out := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	for i, line := range out {
+		if line != "" {
+			out[i] = strings.Repeat(" ", ins.col) + line
+		}
+	}
+	return out, true
+}
+
+func samePointers(a, b []*yaml.Node) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// indentation counts the leading spaces of line.
+func indentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// runeOffset returns the byte offset of the col-th character (0-based) of
+// line, or -1 when the line is shorter.
+func runeOffset(line string, col int) int {
+	for i := range line {
+		if col == 0 {
+			return i
+		}
+		col--
+	}
+	if col == 0 {
+		return len(line)
+	}
+	return -1
+}
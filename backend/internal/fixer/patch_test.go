@@ -0,0 +1,98 @@
+package fixer
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"devformat/backend/internal/dupkeys"
+	"devformat/backend/internal/parser"
+)
+
+func TestPatch(t *testing.T) {
+	insertName := func(doc *yaml.Node) {
+		metadata := MappingValue(Root(doc), "metadata")
+		InsertMappingPair(metadata, "", "name", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "web"})
+	}
+	tests := []struct {
+		name    string
+		content string
+		fixer   string
+		mode    dupkeys.Mode
+		edit    func(doc *yaml.Node)
+		want    string
+		// spliced is false when Patch must give up
+		spliced bool
+	}{
+		{
+			name:    "quoted scalar",
+			content: "# header\na: 'x'  # keep\nb:    'it''s'\n",
+			fixer:   "quotes",
+			want:    "# header\na: \"x\"  # keep\nb:    \"it's\"\n",
+			spliced: true,
+		},
+		{
+			name:    "boolean",
+			content: "a: yes\nlist:\n  - on\n  - 'no'\n",
+			fixer:   "boolean-format",
+			want:    "a: true\nlist:\n  - true\n  - 'no'\n",
+			spliced: true,
+		},
+		{
+			name:    "duplicate keep-last",
+			content: "a:\n  x: 1\n  # about y\n  y: 2\nb: 3\na: 4\n",
+			fixer:   "duplicate-keys",
+			mode:    dupkeys.KeepLast,
+			want:    "b: 3\na: 4\n",
+			spliced: true,
+		},
+		{
+			name:    "duplicate keep-first",
+			content: "a: 1\nb: 2\n\na: 3\n",
+			fixer:   "duplicate-keys",
+			mode:    dupkeys.KeepFirst,
+			want:    "a: 1\nb: 2\n\n",
+			spliced: true,
+		},
+		{
+			name:    "inserted pair",
+			content: "metadata:\n    # labels\n    labels:\n        app: web\n",
+			edit:    insertName,
+			want:    "metadata:\n    # labels\n    labels:\n        app: web\n    name: web\n",
+			spliced: true,
+		},
+		{
+			name:    "flow mapping",
+			content: "metadata: {labels: {}}\n",
+			edit:    insertName,
+			spliced: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parser.ParseYAMLNode(tt.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			snap := TakeSnapshot(doc)
+			if tt.edit != nil {
+				tt.edit(doc)
+			} else {
+				f, ok := Lookup(tt.fixer)
+				if !ok {
+					t.Fatalf("no fixer %q", tt.fixer)
+				}
+				if !f.Node(doc, Settings{Indent: 2, DuplicateKeys: tt.mode}) {
+					t.Fatalf("fixer %q changed nothing", tt.fixer)
+				}
+			}
+			got, ok := snap.Patch(tt.content, doc, 2)
+			if ok != tt.spliced {
+				t.Fatalf("Patch() = %q, %v; want spliced %v", got, ok, tt.spliced)
+			}
+			if ok && got != tt.want {
+				t.Errorf("Patch() =\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
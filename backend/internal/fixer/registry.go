@@ -0,0 +1,115 @@
+package fixer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"devformat/backend/internal/dupkeys"
+)
+
+// Settings carries the options shared by every fixer.
+type Settings struct {
+	// Indent is the number of spaces per indentation level.
+	Indent int
+	// DuplicateKeys is how duplicate-keys resolves repeated keys; ""
+	// keeps the last occurrence.
+	DuplicateKeys dupkeys.Mode
+}
+
+// Fixer is a named, individually selectable repair. Text fixers rewrite the
+// raw document before it is parsed; node fixers edit the parsed tree. A fixer
+// sets exactly one of the two.
+type Fixer struct {
+	ID          string
+	Description string
+	// Order fixes the position of the fixer in a run; lower runs first.
+	Order int
+	// Text returns the rewritten document, or an error when it cannot be
+	// repaired.
+	Text func(content string, s Settings) (string, error)
+	// Node edits a DocumentNode in place and reports whether it changed it.
+	Node func(doc *yaml.Node, s Settings) bool
+}
+
+var (
+	mu       sync.RWMutex
+	registry = map[string]Fixer{}
+)
+
+// Register adds a fixer to the registry. Registering an ID twice replaces the
+// earlier fixer.
+func Register(f Fixer) {
+	mu.Lock()
+	defer mu.Unlock()
+	registry[f.ID] = f
+}
+
+// Lookup returns the fixer registered under id.
+func Lookup(id string) (Fixer, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	f, ok := registry[id]
+	return f, ok
+}
+
+// Fixers returns every registered fixer in the order they run.
+func Fixers() []Fixer {
+	mu.RLock()
+	defer mu.RUnlock()
+	out := make([]Fixer, 0, len(registry))
+	for _, f := range registry {
+		out = append(out, f)
+	}
+	sortFixers(out)
+	return out
+}
+
+// DefaultFixers are applied when a request selects none, matching the
+// behaviour of the fixer before fixers were selectable.
+var DefaultFixers = []string{"indentation"}
+
+// Select resolves requested fixer IDs into fixers in run order. "all" selects
+// every registered fixer, an empty list selects DefaultFixers and unknown IDs
+// are an error.
+func Select(ids []string) ([]Fixer, error) {
+	if len(ids) == 0 {
+		ids = DefaultFixers
+	}
+	var out []Fixer
+	seen := map[string]bool{}
+	var unknown []string
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		if id == "all" {
+			return Fixers(), nil
+		}
+		seen[id] = true
+		f, ok := Lookup(id)
+		if !ok {
+			unknown = append(unknown, id)
+			continue
+		}
+		out = append(out, f)
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown fix type(s): %s", strings.Join(unknown, ", "))
+	}
+	sortFixers(out)
+	return out, nil
+}
+
+func sortFixers(fs []Fixer) {
+	sort.Slice(fs, func(i, j int) bool {
+		if fs[i].Order != fs[j].Order {
+			return fs[i].Order < fs[j].Order
+		}
+		return fs[i].ID < fs[j].ID
+	})
+}
@@ -0,0 +1,107 @@
+package helm
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"devformat/backend/internal/archive"
+)
+
+// Package is a chart read from an archive.
+type Package struct {
+	// Files maps paths relative to the chart directory, e.g.
+	// "templates/service.yaml", to their content. Files of subcharts are
+	// not included.
+	Files map[string]string
+	// Subcharts are the charts vendored in charts/, as directories or
+	// archives, keyed by their directory or archive name.
+	Subcharts map[string]*Package
+}
+
+// ErrNoChart is returned by LoadArchive for an archive without Chart.yaml.
+var ErrNoChart = errors.New("archive holds no Chart.yaml")
+
+// LoadArchive reads a chart from a gzipped tar (as "helm package" writes)
+// or a zip archive. The chart may sit at the archive root or in a single
+// top-level directory.
+func LoadArchive(data []byte) (*Package, error) {
+	files, err := archive.Unpack(data)
+	if err != nil {
+		return nil, err
+	}
+	root, ok := archive.Root(files, "Chart.yaml")
+	if !ok {
+		return nil, ErrNoChart
+	}
+	return newPackage(files, root)
+}
+
+// newPackage builds the chart rooted at dir, recursing into charts/.
+func newPackage(files map[string]string, dir string) (*Package, error) {
+	prefix := ""
+	if dir != "" {
+		prefix = dir + "/"
+	}
+	p := &Package{Files: map[string]string{}, Subcharts: map[string]*Package{}}
+	subdirs := map[string]bool{}
+	for name, content := range files {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		rel := strings.TrimPrefix(name, prefix)
+		if !strings.HasPrefix(rel, "charts/") {
+			p.Files[rel] = content
+			continue
+		}
+		sub := strings.TrimPrefix(rel, "charts/")
+		switch i := strings.IndexByte(sub, '/'); {
+		case i > 0:
+			subdirs[sub[:i]] = true
+		case strings.HasSuffix(sub, ".tgz") || strings.HasSuffix(sub, ".tar.gz"):
+			chart, err := LoadArchive([]byte(content))
+			if err != nil {
+				return nil, fmt.Errorf("charts/%s: %w", sub, err)
+			}
+			p.Subcharts[sub] = chart
+		}
+	}
+	for sub := range subdirs {
+		if _, ok := files[prefix+"charts/"+sub+"/Chart.yaml"]; !ok {
+			continue
+		}
+		chart, err := newPackage(files, prefix+"charts/"+sub)
+		if err != nil {
+			return nil, err
+		}
+		p.Subcharts[sub] = chart
+	}
+	return p, nil
+}
+
+// Templates returns the files in templates/, sorted by name.
+func (p *Package) Templates() []File {
+	var out []File
+	for name, content := range p.Files {
+		if strings.HasPrefix(name, "templates/") {
+			out = append(out, File{Name: name, Content: content})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// OtherFiles returns the files templates see as .Files: everything outside
+// templates/ except Chart.yaml, values.yaml and values.schema.json.
+func (p *Package) OtherFiles() Files {
+	out := Files{}
+	for name, content := range p.Files {
+		switch {
+		case strings.HasPrefix(name, "templates/"), name == "Chart.yaml", name == "values.yaml", name == "values.schema.json":
+			continue
+		}
+		out[name] = content
+	}
+	return out
+}
@@ -0,0 +1,826 @@
+package helm
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// funcMap returns the functions available to chart templates: the subset of
+// the sprig library that charts commonly use plus Helm's own additions. The
+// functions that need the template set (include, tpl) are added by the
+// renderer.
+func funcMap() template.FuncMap {
+	return template.FuncMap{
+		// strings
+		"upper":      strings.ToUpper,
+		"lower":      strings.ToLower,
+		"title":      title,
+		"untitle":    untitle,
+		"trim":       strings.TrimSpace,
+		"trimAll":    func(cutset, s string) string { return strings.Trim(s, cutset) },
+		"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+		"trunc":      trunc,
+		"substr":     substr,
+		"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+		"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+		"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+		"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+		"repeat":     repeat,
+		"nospace":    func(s string) string { return strings.Join(strings.Fields(s), "") },
+		"quote":      quote,
+		"squote":     squote,
+		"cat":        cat,
+		"indent":     indent,
+		"nindent":    func(n int, s string) string { return "\n" + indent(n, s) },
+		"split":      split,
+		"splitList":  func(sep, s string) []string { return strings.Split(s, sep) },
+		"join":       join,
+		"toString":   toString,
+		"toStrings":  toStrings,
+		"regexMatch": func(re, s string) (bool, error) { return regexp.MatchString(re, s) },
+		"regexFind": func(re, s string) (string, error) {
+			r, err := regexp.Compile(re)
+			if err != nil {
+				return "", err
+			}
+			return r.FindString(s), nil
+		},
+		"regexReplaceAll": func(re, s, repl string) (string, error) {
+			r, err := regexp.Compile(re)
+			if err != nil {
+				return "", err
+			}
+			return r.ReplaceAllString(s, repl), nil
+		},
+
+		// encodings
+		"b64enc":        func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) },
+		"b64dec":        b64dec,
+		"sha1sum":       sha1sum,
+		"sha256sum":     sha256sum,
+		"toYaml":        toYAML,
+		"fromYaml":      fromYAML,
+		"fromYamlArray": fromYAMLArray,
+		"toJson":        toJSON,
+		"toPrettyJson":  toPrettyJSON,
+		"fromJson":      fromJSON,
+
+		// defaults and flow
+		"default":  defaultValue,
+		"empty":    empty,
+		"coalesce": coalesce,
+		"ternary":  ternary,
+		"required": required,
+		"fail":     func(msg string) (string, error) { return "", errors.New(msg) },
+
+		// types
+		"int":       func(v any) int { return int(toInt64(v)) },
+		"int64":     toInt64,
+		"float64":   toFloat64,
+		"atoi":      func(s string) int { n, _ := strconv.Atoi(s); return n },
+		"kindOf":    kindOf,
+		"kindIs":    func(kind string, v any) bool { return kindOf(v) == kind },
+		"typeOf":    func(v any) string { return fmt.Sprintf("%T", v) },
+		"typeIs":    func(typ string, v any) bool { return fmt.Sprintf("%T", v) == typ },
+		"deepEqual": reflect.DeepEqual,
+
+		// math
+		"add":   func(a ...any) int64 { return fold(a, func(x, y int64) int64 { return x + y }) },
+		"add1":  func(a any) int64 { return toInt64(a) + 1 },
+		"sub":   func(a, b any) int64 { return toInt64(a) - toInt64(b) },
+		"mul":   func(a ...any) int64 { return fold(a, func(x, y int64) int64 { return x * y }) },
+		"div":   div,
+		"mod":   mod,
+		"max":   func(a ...any) int64 { return fold(a, max64) },
+		"min":   func(a ...any) int64 { return fold(a, min64) },
+		"floor": func(a any) float64 { return math.Floor(toFloat64(a)) },
+		"ceil":  func(a any) float64 { return math.Ceil(toFloat64(a)) },
+		"round": round,
+
+		// lists
+		"list":      func(v ...any) []any { return v },
+		"append":    push,
+		"prepend":   prepend,
+		"first":     first,
+		"last":      last,
+		"rest":      rest,
+		"initial":   initial,
+		"has":       has,
+		"uniq":      uniq,
+		"compact":   compact,
+		"without":   without,
+		"concat":    concat,
+		"reverse":   reverse,
+		"sortAlpha": sortAlpha,
+		"until":     func(n int) ([]int, error) { return untilStep(0, n, 1) },
+		"untilStep": untilStep,
+
+		// dictionaries
+		"dict":           dict,
+		"get":            get,
+		"set":            set,
+		"unset":          unset,
+		"hasKey":         hasKey,
+		"keys":           keys,
+		"values":         values,
+		"pluck":          pluck,
+		"pick":           pick,
+		"omit":           omit,
+		"merge":          func(dst map[string]any, src ...map[string]any) map[string]any { return merge(dst, src, false) },
+		"mergeOverwrite": func(dst map[string]any, src ...map[string]any) map[string]any { return merge(dst, src, true) },
+		"deepCopy":       deepCopy,
+		"dig":            dig,
+
+		// misc
+		"semverCompare": SemverCompare,
+		"randAlphaNum":  randAlphaNum,
+		"lookup":        func(apiVersion, kind, namespace, name string) map[string]any { return map[string]any{} },
+	}
+}
+
+func title(s string) string {
+	prev := ' '
+	return strings.Map(func(r rune) rune {
+		defer func() { prev = r }()
+		if unicode.IsSpace(prev) {
+			return unicode.ToTitle(r)
+		}
+		return r
+	}, s)
+}
+
+func untitle(s string) string {
+	prev := ' '
+	return strings.Map(func(r rune) rune {
+		defer func() { prev = r }()
+		if unicode.IsSpace(prev) {
+			return unicode.ToLower(r)
+		}
+		return r
+	}, s)
+}
+
+func trunc(n int, s string) string {
+	switch {
+	case n >= 0 && len(s) > n:
+		return s[:n]
+	case n < 0 && len(s) > -n:
+		return s[len(s)+n:]
+	}
+	return s
+}
+
+func substr(start, end int, s string) string {
+	if start < 0 {
+		start = 0
+	}
+	if end < 0 || end > len(s) {
+		end = len(s)
+	}
+	if start > end {
+		return ""
+	}
+	return s[start:end]
+}
+
+func quote(v ...any) string {
+	out := make([]string, 0, len(v))
+	for _, s := range v {
+		if s != nil {
+			out = append(out, strconv.Quote(toString(s)))
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+func squote(v ...any) string {
+	out := make([]string, 0, len(v))
+	for _, s := range v {
+		if s != nil {
+			out = append(out, "'"+toString(s)+"'")
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+func cat(v ...any) string {
+	out := make([]string, 0, len(v))
+	for _, s := range v {
+		if s != nil {
+			out = append(out, toString(s))
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+func indent(n int, s string) string {
+	pad := strings.Repeat(" ", n)
+	return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+}
+
+func split(sep, s string) map[string]any {
+	out := map[string]any{}
+	for i, part := range strings.Split(s, sep) {
+		out["_"+strconv.Itoa(i)] = part
+	}
+	return out
+}
+
+func join(sep string, v any) string {
+	return strings.Join(toStrings(v), sep)
+}
+
+func toString(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	}
+	return fmt.Sprint(v)
+}
+
+func toStrings(v any) []string {
+	l, _ := toList(v)
+	out := make([]string, 0, len(l))
+	for _, s := range l {
+		if s != nil {
+			out = append(out, toString(s))
+		}
+	}
+	return out
+}
+
+func sha1sum(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func sha256sum(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func b64dec(s string) string {
+	out, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return err.Error()
+	}
+	return string(out)
+}
+
+// toYAML encodes v as Helm does: two-space indentation, without the final
+// newline.
+func toYAML(v any) string {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return ""
+	}
+	enc.Close()
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// fromYAML decodes a YAML mapping; a decoding error is returned in the
+// "Error" key, as Helm does.
+func fromYAML(s string) map[string]any {
+	m := map[string]any{}
+	if err := yaml.Unmarshal([]byte(s), &m); err != nil {
+		m["Error"] = err.Error()
+	}
+	return m
+}
+
+func fromYAMLArray(s string) []any {
+	var a []any
+	if err := yaml.Unmarshal([]byte(s), &a); err != nil {
+		return []any{err.Error()}
+	}
+	return a
+}
+
+func toJSON(v any) string {
+	out, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(out)
+}
+
+func toPrettyJSON(v any) string {
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return ""
+	}
+	return string(out)
+}
+
+func fromJSON(s string) map[string]any {
+	m := map[string]any{}
+	if err := json.Unmarshal([]byte(s), &m); err != nil {
+		m["Error"] = err.Error()
+	}
+	return m
+}
+
+// defaultValue returns the given value unless it is empty, else d. The
+// value is optional so that "default" can end a pipeline with no input.
+func defaultValue(d any, given ...any) any {
+	if len(given) == 0 || empty(given[0]) {
+		return d
+	}
+	return given[0]
+}
+
+// empty reports whether v is the zero value of its type, nil or an empty
+// collection.
+func empty(v any) bool {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() {
+		return true
+	}
+	switch rv.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return rv.Len() == 0
+	case reflect.Bool:
+		return !rv.Bool()
+	case reflect.Complex64, reflect.Complex128:
+		return rv.Complex() == 0
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return rv.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return rv.Float() == 0
+	case reflect.Interface, reflect.Pointer:
+		return rv.IsNil()
+	case reflect.Struct:
+		return rv.IsZero()
+	}
+	return false
+}
+
+func coalesce(v ...any) any {
+	for _, x := range v {
+		if !empty(x) {
+			return x
+		}
+	}
+	return nil
+}
+
+func ternary(t, f any, cond bool) any {
+	if cond {
+		return t
+	}
+	return f
+}
+
+func required(msg string, v any) (any, error) {
+	if v == nil {
+		return v, errors.New(msg)
+	}
+	if s, ok := v.(string); ok && s == "" {
+		return v, errors.New(msg)
+	}
+	return v, nil
+}
+
+func toInt64(v any) int64 {
+	switch v := v.(type) {
+	case string:
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return n
+		}
+		f, _ := strconv.ParseFloat(v, 64)
+		return int64(f)
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return int64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return int64(rv.Float())
+	}
+	return 0
+}
+
+func toFloat64(v any) float64 {
+	switch v := v.(type) {
+	case string:
+		f, _ := strconv.ParseFloat(v, 64)
+		return f
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	}
+	return 0
+}
+
+func kindOf(v any) string {
+	if v == nil {
+		return "invalid"
+	}
+	return reflect.ValueOf(v).Kind().String()
+}
+
+func fold(a []any, f func(x, y int64) int64) int64 {
+	if len(a) == 0 {
+		return 0
+	}
+	acc := toInt64(a[0])
+	for _, v := range a[1:] {
+		acc = f(acc, toInt64(v))
+	}
+	return acc
+}
+
+func max64(x, y int64) int64 {
+	if y > x {
+		return y
+	}
+	return x
+}
+
+func min64(x, y int64) int64 {
+	if y < x {
+		return y
+	}
+	return x
+}
+
+func div(a, b any) (int64, error) {
+	if toInt64(b) == 0 {
+		return 0, errors.New("division by zero")
+	}
+	return toInt64(a) / toInt64(b), nil
+}
+
+func mod(a, b any) (int64, error) {
+	if toInt64(b) == 0 {
+		return 0, errors.New("division by zero")
+	}
+	return toInt64(a) % toInt64(b), nil
+}
+
+func round(a any, places int) float64 {
+	p := math.Pow(10, float64(places))
+	return math.Round(toFloat64(a)*p) / p
+}
+
+// toList converts any slice or array to []any. nil is an empty list.
+func toList(v any) ([]any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if l, ok := v.([]any); ok {
+		return l, nil
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("cannot use %T as a list", v)
+	}
+	out := make([]any, rv.Len())
+	for i := range out {
+		out[i] = rv.Index(i).Interface()
+	}
+	return out, nil
+}
+
+func push(l, v any) ([]any, error) {
+	s, err := toList(l)
+	return append(s, v), err
+}
+
+func prepend(l, v any) ([]any, error) {
+	s, err := toList(l)
+	return append([]any{v}, s...), err
+}
+
+func first(l any) (any, error) {
+	s, err := toList(l)
+	if len(s) == 0 {
+		return nil, err
+	}
+	return s[0], nil
+}
+
+func last(l any) (any, error) {
+	s, err := toList(l)
+	if len(s) == 0 {
+		return nil, err
+	}
+	return s[len(s)-1], nil
+}
+
+func rest(l any) ([]any, error) {
+	s, err := toList(l)
+	if len(s) == 0 {
+		return nil, err
+	}
+	return s[1:], nil
+}
+
+func initial(l any) ([]any, error) {
+	s, err := toList(l)
+	if len(s) == 0 {
+		return nil, err
+	}
+	return s[:len(s)-1], nil
+}
+
+func has(needle, haystack any) (bool, error) {
+	l, err := toList(haystack)
+	for _, v := range l {
+		if reflect.DeepEqual(v, needle) {
+			return true, err
+		}
+	}
+	return false, err
+}
+
+func uniq(v any) ([]any, error) {
+	l, err := toList(v)
+	var out []any
+	for _, x := range l {
+		if ok, _ := has(x, out); !ok {
+			out = append(out, x)
+		}
+	}
+	return out, err
+}
+
+func compact(v any) ([]any, error) {
+	l, err := toList(v)
+	var out []any
+	for _, x := range l {
+		if !empty(x) {
+			out = append(out, x)
+		}
+	}
+	return out, err
+}
+
+func without(v any, omit ...any) ([]any, error) {
+	l, err := toList(v)
+	var out []any
+	for _, x := range l {
+		if ok, _ := has(x, omit); !ok {
+			out = append(out, x)
+		}
+	}
+	return out, err
+}
+
+func concat(lists ...any) ([]any, error) {
+	var out []any
+	for _, v := range lists {
+		l, err := toList(v)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, l...)
+	}
+	return out, nil
+}
+
+func reverse(v any) ([]any, error) {
+	l, err := toList(v)
+	out := make([]any, len(l))
+	for i, x := range l {
+		out[len(l)-1-i] = x
+	}
+	return out, err
+}
+
+func sortAlpha(v any) []string {
+	out := toStrings(v)
+	sort.Strings(out)
+	return out
+}
+
+// maxListLength bounds the lists until and untilStep make.
+const maxListLength = 100000
+
+func untilStep(start, stop, step int) ([]int, error) {
+	var out []int
+	if step == 0 {
+		return out, nil
+	}
+	if n := (stop - start) / step; n > maxListLength {
+		return nil, fmt.Errorf("list of %d elements is longer than %d", n, maxListLength)
+	}
+	for i := start; (step > 0 && i < stop) || (step < 0 && i > stop); i += step {
+		out = append(out, i)
+	}
+	return out, nil
+}
+
+func repeat(n int, s string) (string, error) {
+	if n > 0 && len(s) > 0 && n > MaxOutputBytes/len(s) {
+		return "", fmt.Errorf("repeat of %d times %d bytes is larger than %d bytes", n, len(s), MaxOutputBytes)
+	}
+	return strings.Repeat(s, n), nil
+}
+
+func dict(v ...any) map[string]any {
+	out := map[string]any{}
+	for i := 0; i < len(v); i += 2 {
+		var val any
+		if i+1 < len(v) {
+			val = v[i+1]
+		}
+		out[toString(v[i])] = val
+	}
+	return out
+}
+
+func get(d map[string]any, key string) any {
+	if v, ok := d[key]; ok {
+		return v
+	}
+	return ""
+}
+
+func set(d map[string]any, key string, v any) map[string]any {
+	d[key] = v
+	return d
+}
+
+func unset(d map[string]any, key string) map[string]any {
+	delete(d, key)
+	return d
+}
+
+func hasKey(d map[string]any, key string) bool {
+	_, ok := d[key]
+	return ok
+}
+
+func keys(dicts ...map[string]any) []string {
+	var out []string
+	for _, d := range dicts {
+		for k := range d {
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+func values(d map[string]any) []any {
+	out := make([]any, 0, len(d))
+	for _, v := range d {
+		out = append(out, v)
+	}
+	return out
+}
+
+func pluck(key string, dicts ...map[string]any) []any {
+	var out []any
+	for _, d := range dicts {
+		if v, ok := d[key]; ok {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func pick(d map[string]any, keys ...string) map[string]any {
+	out := map[string]any{}
+	for _, k := range keys {
+		if v, ok := d[k]; ok {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+func omit(d map[string]any, keys ...string) map[string]any {
+	out := map[string]any{}
+	for k, v := range d {
+		out[k] = v
+	}
+	for _, k := range keys {
+		delete(out, k)
+	}
+	return out
+}
+
+// merge merges src into dst recursively. Values already in dst win unless
+// overwrite is set.
+func merge(dst map[string]any, src []map[string]any, overwrite bool) map[string]any {
+	for _, s := range src {
+		for k, v := range s {
+			dv, ok := dst[k]
+			dm, dIsMap := dv.(map[string]any)
+			sm, sIsMap := v.(map[string]any)
+			switch {
+			case ok && dIsMap && sIsMap:
+				merge(dm, []map[string]any{sm}, overwrite)
+			case !ok || overwrite:
+				dst[k] = v
+			}
+		}
+	}
+	return dst
+}
+
+func deepCopy(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, x := range v {
+			out[k] = deepCopy(x)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, x := range v {
+			out[i] = deepCopy(x)
+		}
+		return out
+	}
+	return v
+}
+
+// dig follows a path of keys through nested dictionaries, returning the
+// default (the second-to-last argument) when a key is missing.
+func dig(args ...any) (any, error) {
+	if len(args) < 3 {
+		return nil, errors.New("dig needs at least a key, a default and a dictionary")
+	}
+	d, ok := args[len(args)-1].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("dig: last argument must be a dictionary, got %T", args[len(args)-1])
+	}
+	def := args[len(args)-2]
+	var cur any = d
+	for _, k := range args[:len(args)-2] {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return def, nil
+		}
+		if cur, ok = m[toString(k)]; !ok {
+			return def, nil
+		}
+	}
+	return cur, nil
+}
+
+const alphaNum = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+func randAlphaNum(n int) string {
+	out := make([]byte, n)
+	for i := range out {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(len(alphaNum))))
+		if err != nil {
+			return string(out[:i])
+		}
+		out[i] = alphaNum[j.Int64()]
+	}
+	return string(out)
+}
@@ -0,0 +1,471 @@
+// Package helm renders Helm chart templates offline so that their output can
+// be validated like any other manifest. Templates are Go text/template with
+// the sprig-style functions Helm charts rely on, include, tpl and required,
+// and see the usual .Values, .Release, .Chart, .Capabilities, .Template and
+// .Files objects. Every rendered line is traced back to the template line it
+// came from, so problems found in the output can be reported in the chart.
+package helm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+)
+
+// File is a named chart file, e.g. a template "templates/deployment.yaml".
+type File struct {
+	Name    string
+	Content string
+}
+
+// Release is the .Release object.
+type Release struct {
+	Name      string
+	Namespace string
+	Service   string
+	Revision  int
+	IsInstall bool
+	IsUpgrade bool
+}
+
+// Chart is the .Chart object, read from Chart.yaml.
+type Chart struct {
+	APIVersion   string            `yaml:"apiVersion"`
+	Name         string            `yaml:"name"`
+	Version      string            `yaml:"version"`
+	KubeVersion  string            `yaml:"kubeVersion"`
+	Description  string            `yaml:"description"`
+	Type         string            `yaml:"type"`
+	Keywords     []string          `yaml:"keywords"`
+	Home         string            `yaml:"home"`
+	Sources      []string          `yaml:"sources"`
+	Dependencies []Dependency      `yaml:"dependencies"`
+	Maintainers  []Maintainer      `yaml:"maintainers"`
+	Icon         string            `yaml:"icon"`
+	AppVersion   string            `yaml:"appVersion"`
+	Deprecated   bool              `yaml:"deprecated"`
+	Annotations  map[string]string `yaml:"annotations"`
+}
+
+// Dependency is an entry of Chart.yaml's dependencies.
+type Dependency struct {
+	Name       string `yaml:"name"`
+	Version    string `yaml:"version"`
+	Repository string `yaml:"repository"`
+	Condition  string `yaml:"condition"`
+	Alias      string `yaml:"alias"`
+}
+
+// Maintainer is an entry of Chart.yaml's maintainers.
+type Maintainer struct {
+	Name  string `yaml:"name"`
+	Email string `yaml:"email"`
+	URL   string `yaml:"url"`
+}
+
+// KubeVersion is .Capabilities.KubeVersion.
+type KubeVersion struct {
+	Version    string
+	Major      string
+	Minor      string
+	GitVersion string
+}
+
+// String returns the version, as Helm prints it.
+func (v KubeVersion) String() string { return v.Version }
+
+// VersionSet is .Capabilities.APIVersions.
+type VersionSet []string
+
+// Has reports whether the API version, e.g. "apps/v1", or resource, e.g.
+// "apps/v1/Deployment", is available.
+func (s VersionSet) Has(v string) bool {
+	for _, x := range s {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Capabilities is the .Capabilities object.
+type Capabilities struct {
+	KubeVersion KubeVersion
+	APIVersions VersionSet
+}
+
+// Files is the .Files object: the chart's files outside templates/.
+type Files map[string]string
+
+// Get returns the content of a file, or "" when the chart has no such file.
+func (f Files) Get(name string) string { return f[name] }
+
+// Glob returns the files whose names match pattern.
+func (f Files) Glob(pattern string) Files {
+	out := Files{}
+	for name, content := range f {
+		if ok, _ := path.Match(pattern, name); ok {
+			out[name] = content
+		}
+	}
+	return out
+}
+
+// Options are the inputs of a render.
+type Options struct {
+	Values  map[string]any
+	Release Release
+	Chart   Chart
+	// KubeVersion is the cluster version templates see, e.g. "v1.30".
+	KubeVersion string
+	// APIVersions lists the API versions templates see as available.
+	APIVersions []string
+	Files       Files
+}
+
+// Rendered is the output of one template.
+type Rendered struct {
+	Name    string
+	Content string
+	// Sources holds the template line of each line of Content.
+	Sources []Source
+	// Err is set when the template failed to parse or execute; Content is
+	// then empty.
+	Err *Error
+}
+
+// Source returns the template line a 1-based line of Content came from.
+func (r Rendered) Source(line int) Source {
+	if line < 1 || line > len(r.Sources) {
+		return Source{Template: r.Name}
+	}
+	return r.Sources[line-1]
+}
+
+// Error is a template that failed to parse or execute.
+type Error struct {
+	Template string
+	// Line and Column are 1-based, or 0 when unknown.
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %s", e.Template, e.Message)
+	}
+	return fmt.Sprintf("%s:%d: %s", e.Template, e.Line, e.Message)
+}
+
+// maxIncludeDepth bounds recursive include and tpl calls, as Helm does.
+const maxIncludeDepth = 1000
+
+// Limits of a render, so that a template cannot exhaust memory or time:
+// MaxOutputBytes bounds the output of all templates together, including
+// the text include and tpl return, and MaxRenderTime the time taken.
+const (
+	MaxOutputBytes = 16 << 20
+	MaxRenderTime  = 10 * time.Second
+)
+
+// maxIterations bounds the iterations of all range loops of a render.
+const maxIterations = 1000000
+
+// budget tracks the output written, the loop iterations run and the
+// deadline of a render.
+type budget struct {
+	ctx        context.Context
+	written    int
+	iterations int
+}
+
+// limitError is a render stopped by its budget. It is reported without a
+// position, since the limit is not the fault of one line.
+type limitError struct {
+	message string
+}
+
+func (e *limitError) Error() string { return e.message }
+
+func (b *budget) check() error {
+	err := b.ctx.Err()
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &limitError{fmt.Sprintf("rendering took longer than %s", MaxRenderTime)}
+	}
+	return err
+}
+
+// iterate is called at the start of every range iteration, so that loops
+// writing nothing still stop at the deadline.
+func (b *budget) iterate() (string, error) {
+	if b.iterations++; b.iterations > maxIterations {
+		return "", &limitError{fmt.Sprintf("range loops ran more than %d iterations", maxIterations)}
+	}
+	return "", b.check()
+}
+
+// guardFunc is the function of the action guardRanges inserts.
+const guardFunc = "devformatRangeGuard"
+
+// guardRanges makes guard, an action calling guardFunc, the first node of
+// the body of every range loop under n.
+func guardRanges(n parse.Node, guard parse.Node) {
+	switch n := n.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Nodes {
+			guardRanges(c, guard)
+		}
+	case *parse.IfNode:
+		guardRanges(n.List, guard)
+		guardRanges(n.ElseList, guard)
+	case *parse.WithNode:
+		guardRanges(n.List, guard)
+		guardRanges(n.ElseList, guard)
+	case *parse.RangeNode:
+		guardRanges(n.List, guard)
+		guardRanges(n.ElseList, guard)
+		n.List.Nodes = append([]parse.Node{guard}, n.List.Nodes...)
+	}
+}
+
+// limitedBuffer is a buffer that fails writes beyond the budget.
+type limitedBuffer struct {
+	bytes.Buffer
+	budget *budget
+}
+
+func (w *limitedBuffer) Write(p []byte) (int, error) {
+	if err := w.budget.check(); err != nil {
+		return 0, err
+	}
+	if w.budget.written += len(p); w.budget.written > MaxOutputBytes {
+		return 0, &limitError{fmt.Sprintf("rendered output is larger than %d bytes", MaxOutputBytes)}
+	}
+	return w.Buffer.Write(p)
+}
+
+// Render parses templates as one set, so each can include the named
+// templates defined by the others, and executes every template whose file
+// name does not start with "_". Outputs are returned in template name order,
+// with partials ("_helpers.tpl") only listed when they fail to parse. A
+// template that fails, also by exceeding MaxOutputBytes or MaxRenderTime or
+// by ctx being cancelled, does not stop the others from rendering.
+func Render(ctx context.Context, templates []File, opts Options) []Rendered {
+	ctx, cancel := context.WithTimeout(ctx, MaxRenderTime)
+	defer cancel()
+	b := &budget{ctx: ctx}
+	guardTmpl := template.Must(template.New("guard").Funcs(template.FuncMap{guardFunc: b.iterate}).Parse("{{" + guardFunc + "}}"))
+	guard := guardTmpl.Tree.Root.Nodes[0]
+	templates = append([]File(nil), templates...)
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	names := make([]string, len(templates))
+	shifts := map[string]map[int]int{}
+
+	root := template.New("gotpl").Option("missingkey=zero")
+	depth := 0
+	funcs := funcMap()
+	funcs["include"] = func(name string, data any) (string, error) {
+		if depth >= maxIncludeDepth {
+			return "", fmt.Errorf("rendering template has a nested reference name: %s", name)
+		}
+		if err := b.check(); err != nil {
+			return "", err
+		}
+		depth++
+		defer func() { depth-- }()
+		buf := &limitedBuffer{budget: b}
+		if err := root.ExecuteTemplate(buf, name, data); err != nil {
+			return "", err
+		}
+		return stripMarkers(buf.String()), nil
+	}
+	funcs["tpl"] = func(text string, data any) (string, error) {
+		if depth >= maxIncludeDepth {
+			return "", fmt.Errorf("rendering template has a nested reference to tpl")
+		}
+		if err := b.check(); err != nil {
+			return "", err
+		}
+		depth++
+		defer func() { depth-- }()
+		t, err := root.Clone()
+		if err != nil {
+			return "", err
+		}
+		if t, err = t.New("tpl").Parse(text); err != nil {
+			return "", err
+		}
+		guardRanges(t.Tree.Root, guard)
+		buf := &limitedBuffer{budget: b}
+		if err := t.Execute(buf, data); err != nil {
+			return "", err
+		}
+		return stripMarkers(strings.ReplaceAll(buf.String(), "<no value>", "")), nil
+	}
+	funcs[guardFunc] = b.iterate
+	root.Funcs(funcs)
+
+	failed := map[string]*Error{}
+	for i, f := range templates {
+		names[i] = f.Name
+		text, lineShifts := markLines(f.Content, i)
+		shifts[f.Name] = lineShifts
+		// a template that fails to parse is left out of the set, so the
+		// others still render
+		if _, err := root.New(f.Name).Parse(text); err != nil {
+			failed[f.Name] = templateError(err, f.Name, shifts)
+		}
+	}
+	for _, t := range root.Templates() {
+		if t.Tree != nil && failed[t.Name()] == nil {
+			guardRanges(t.Tree.Root, guard)
+		}
+	}
+
+	values := opts.Values
+	if values == nil {
+		values = map[string]any{}
+	}
+	var out []Rendered
+	for _, f := range templates {
+		if err := failed[f.Name]; err != nil {
+			out = append(out, Rendered{Name: f.Name, Err: err})
+			continue
+		}
+		if strings.HasPrefix(path.Base(f.Name), "_") {
+			continue
+		}
+		data := map[string]any{
+			"Values":       values,
+			"Release":      withReleaseDefaults(opts.Release),
+			"Chart":        withChartDefaults(opts.Chart),
+			"Capabilities": capabilities(opts),
+			"Template":     map[string]any{"Name": f.Name, "BasePath": path.Dir(f.Name)},
+			"Files":        opts.Files,
+		}
+		buf := &limitedBuffer{budget: b}
+		if err := root.ExecuteTemplate(buf, f.Name, data); err != nil {
+			e := templateError(err, f.Name, shifts)
+			var le *limitError
+			if errors.As(err, &le) {
+				e = &Error{Template: f.Name, Message: le.Error()}
+			}
+			out = append(out, Rendered{Name: f.Name, Err: e})
+			continue
+		}
+		content, sources := unmarkLines(strings.ReplaceAll(buf.String(), "<no value>", ""), names)
+		out = append(out, Rendered{Name: f.Name, Content: content, Sources: sources})
+	}
+	return out
+}
+
+func withReleaseDefaults(r Release) Release {
+	if r.Name == "" {
+		r.Name = "release-name"
+	}
+	if r.Namespace == "" {
+		r.Namespace = "default"
+	}
+	if r.Service == "" {
+		r.Service = "Helm"
+	}
+	if r.Revision == 0 {
+		r.Revision = 1
+	}
+	if !r.IsUpgrade {
+		r.IsInstall = true
+	}
+	return r
+}
+
+func withChartDefaults(c Chart) Chart {
+	if c.Name == "" {
+		c.Name = "chart"
+	}
+	if c.Version == "" {
+		c.Version = "0.1.0"
+	}
+	return c
+}
+
+func capabilities(opts Options) Capabilities {
+	v := strings.TrimPrefix(opts.KubeVersion, "v")
+	if v == "" {
+		v = "1.30"
+	}
+	parts := strings.SplitN(v, ".", 3)
+	if len(parts) == 2 {
+		v += ".0"
+	}
+	kv := KubeVersion{Version: "v" + v, Major: parts[0], GitVersion: "v" + v}
+	if len(parts) > 1 {
+		kv.Minor = parts[1]
+	}
+	apis := opts.APIVersions
+	if apis == nil {
+		apis = defaultAPIVersions
+	}
+	return Capabilities{KubeVersion: kv, APIVersions: apis}
+}
+
+// defaultAPIVersions are the built-in API groups of current Kubernetes
+// releases.
+var defaultAPIVersions = VersionSet{
+	"v1",
+	"admissionregistration.k8s.io/v1",
+	"apiextensions.k8s.io/v1",
+	"apps/v1",
+	"autoscaling/v1",
+	"autoscaling/v2",
+	"batch/v1",
+	"certificates.k8s.io/v1",
+	"coordination.k8s.io/v1",
+	"discovery.k8s.io/v1",
+	"events.k8s.io/v1",
+	"networking.k8s.io/v1",
+	"node.k8s.io/v1",
+	"policy/v1",
+	"rbac.authorization.k8s.io/v1",
+	"scheduling.k8s.io/v1",
+	"storage.k8s.io/v1",
+}
+
+// templateErrRe matches the position text/template puts in its errors:
+// "template: name:12: ..." or "template: name:12:7: ...".
+var templateErrRe = regexp.MustCompile(`template: ([^:\s]+):(\d+)(?::(\d+))?: `)
+
+// templateError converts a text/template error into an *Error at its
+// outermost position, correcting the column for the line's marker.
+func templateError(err error, name string, shifts map[string]map[int]int) *Error {
+	msg := err.Error()
+	e := &Error{Template: name, Message: msg}
+	m := templateErrRe.FindStringSubmatchIndex(msg)
+	if m == nil {
+		return e
+	}
+	e.Template = msg[m[2]:m[3]]
+	e.Line, _ = strconv.Atoi(msg[m[4]:m[5]])
+	if m[6] >= 0 {
+		// text/template reports a 0-based byte offset
+		col, _ := strconv.Atoi(msg[m[6]:m[7]])
+		e.Column = col + 1
+		if shift := shifts[e.Template][e.Line]; shift > 0 && e.Column > shift {
+			e.Column -= shift
+		}
+	}
+	e.Message = stripMarkers(msg[m[1]:])
+	return e
+}
@@ -0,0 +1,134 @@
+package helm
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Rendered output carries no positions, so before parsing, every template
+// line that starts outside an action gets a marker naming the template and
+// line, placed after the line's indentation. The markers are plain text and
+// travel into the output wherever the line's text does; afterwards each
+// output line is attributed to the first marker on it, or to the line above
+// when it has none (e.g. the lines of a multi-line value), and the markers
+// are removed.
+//
+// Markers never add newlines, so template parse and execution errors keep
+// their line numbers. Lines whose first action trims the whitespace before it
+// ("{{-") get no marker, since the marker would stop the trimming; their
+// output is attributed to the nearest marked line above. include and tpl drop
+// the markers of what they render, so values piped through text functions are
+// not altered; their output is attributed to the calling line.
+
+const markerDelim = "\x00"
+
+var markerRe = regexp.MustCompile("\x00([0-9]+):([0-9]+)\x00")
+
+// Source is the template line a rendered line came from.
+type Source struct {
+	Template string
+	// Line is 1-based, or 0 when the rendered line precedes any marked
+	// line.
+	Line int
+}
+
+// markLines inserts the position markers into the text of the template with
+// the given index. It also returns the length of the marker on each marked
+// line, so columns reported by the template engine can be corrected.
+func markLines(text string, index int) (string, map[int]int) {
+	var b strings.Builder
+	shifts := map[int]int{}
+	inAction := false
+	quote := byte(0)
+	inComment := false
+	lineNo := 1
+	atLineStart := true
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if atLineStart && !inAction {
+			atLineStart = false
+			ws := len(text[i:]) - len(strings.TrimLeft(text[i:], " \t"))
+			rest := text[i+ws:]
+			if !strings.HasPrefix(rest, "{{-") && !strings.HasPrefix(rest, "\n") && !strings.HasPrefix(rest, "\r\n") && rest != "" {
+				marker := markerDelim + strconv.Itoa(index) + ":" + strconv.Itoa(lineNo) + markerDelim
+				b.WriteString(text[i : i+ws])
+				b.WriteString(marker)
+				shifts[lineNo] = len(marker)
+				i += ws
+				if i >= len(text) {
+					break
+				}
+				c = text[i]
+			}
+		}
+		b.WriteByte(c)
+		switch {
+		case c == '\n':
+			lineNo++
+			atLineStart = !inAction
+		case !inAction:
+			if c == '{' && i+1 < len(text) && text[i+1] == '{' {
+				b.WriteByte('{')
+				i++
+				inAction = true
+				if strings.HasPrefix(text[i+1:], "/*") || strings.HasPrefix(text[i+1:], "- /*") {
+					inComment = true
+				}
+			}
+		case inComment:
+			if c == '*' && i+1 < len(text) && text[i+1] == '/' {
+				inComment = false
+			}
+		case quote != 0:
+			switch {
+			case c == '\\' && quote != '`' && i+1 < len(text):
+				i++
+				b.WriteByte(text[i])
+				if text[i] == '\n' {
+					lineNo++
+				}
+			case c == quote:
+				quote = 0
+			}
+		case c == '"' || c == '`' || c == '\'':
+			quote = c
+		case c == '}' && i+1 < len(text) && text[i+1] == '}':
+			b.WriteByte('}')
+			i++
+			inAction = false
+		}
+	}
+	return b.String(), shifts
+}
+
+// unmarkLines removes the markers from rendered output and returns it with
+// the source of each of its lines. names maps template indexes to names.
+func unmarkLines(out string, names []string) (string, []Source) {
+	lines := strings.Split(out, "\n")
+	sources := make([]Source, len(lines))
+	var cur Source
+	if len(names) > 0 {
+		cur.Template = names[0]
+	}
+	for i, line := range lines {
+		if m := markerRe.FindStringSubmatch(line); m != nil {
+			idx, _ := strconv.Atoi(m[1])
+			n, _ := strconv.Atoi(m[2])
+			if idx < len(names) {
+				cur = Source{Template: names[idx], Line: n}
+			}
+			lines[i] = markerRe.ReplaceAllString(line, "")
+		}
+		sources[i] = cur
+	}
+	return strings.Join(lines, "\n"), sources
+}
+
+// stripMarkers removes the markers from text rendered by include or tpl.
+func stripMarkers(s string) string {
+	if !strings.Contains(s, markerDelim) {
+		return s
+	}
+	return markerRe.ReplaceAllString(s, "")
+}
@@ -0,0 +1,135 @@
+package helm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// version is a parsed semantic version; pre-release and build metadata are
+// kept only to order pre-releases before their release.
+type version struct {
+	parts [3]int64
+	pre   string
+}
+
+// parseVersion reads "1.2.3", "v1.2" or "1.28.0-gke.100", filling missing
+// minor and patch numbers with 0.
+func parseVersion(s string) (version, error) {
+	var v version
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		s = s[:i]
+	}
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		s, v.pre = s[:i], s[i+1:]
+	}
+	fields := strings.Split(s, ".")
+	if len(fields) > 3 || s == "" {
+		return v, fmt.Errorf("invalid semantic version %q", s)
+	}
+	for i, f := range fields {
+		if f == "x" || f == "X" || f == "*" {
+			break
+		}
+		n, err := strconv.ParseInt(f, 10, 64)
+		if err != nil {
+			return v, fmt.Errorf("invalid semantic version %q", s)
+		}
+		v.parts[i] = n
+	}
+	return v, nil
+}
+
+func (v version) compare(o version) int {
+	for i := range v.parts {
+		switch {
+		case v.parts[i] < o.parts[i]:
+			return -1
+		case v.parts[i] > o.parts[i]:
+			return 1
+		}
+	}
+	switch {
+	case v.pre == o.pre:
+		return 0
+	case v.pre == "":
+		return 1
+	case o.pre == "":
+		return -1
+	case v.pre < o.pre:
+		return -1
+	}
+	return 1
+}
+
+// SemverCompare reports whether ver satisfies constraint. Constraints are
+// comparisons (=, !=, >, <, >=, <=, ~ and ^) joined by commas or spaces,
+// which must all hold, and alternatives separated by "||".
+func SemverCompare(constraint, ver string) (bool, error) {
+	v, err := parseVersion(ver)
+	if err != nil {
+		return false, err
+	}
+	// pre-releases such as the "-gke.100" of managed clusters do not stop
+	// ">=1.21" from matching, as Helm charts expect
+	v.pre = ""
+	for _, alt := range strings.Split(constraint, "||") {
+		ok, err := satisfiesAll(strings.FieldsFunc(alt, func(r rune) bool { return r == ',' || r == ' ' }), v)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func satisfiesAll(terms []string, v version) (bool, error) {
+	// an operator may be separated from its version by a space
+	for i := 0; i < len(terms); i++ {
+		if strings.Trim(terms[i], "=!<>~^") == "" && i+1 < len(terms) {
+			terms[i+1] = terms[i] + terms[i+1]
+			continue
+		}
+		ok, err := satisfies(terms[i], v)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func satisfies(term string, v version) (bool, error) {
+	op := strings.TrimRight(term[:len(term)-len(strings.TrimLeft(term, "=!<>~^"))], " ")
+	c, err := parseVersion(term[len(op):])
+	if err != nil {
+		return false, err
+	}
+	cmp := v.compare(c)
+	switch op {
+	case "", "=":
+		return cmp == 0, nil
+	case "!=":
+		return cmp != 0, nil
+	case ">":
+		return cmp > 0, nil
+	case "<":
+		return cmp < 0, nil
+	case ">=", "=>":
+		return cmp >= 0, nil
+	case "<=", "=<":
+		return cmp <= 0, nil
+	case "~":
+		upper := version{parts: [3]int64{c.parts[0], c.parts[1] + 1, 0}}
+		return cmp >= 0 && v.compare(upper) < 0, nil
+	case "^":
+		upper := version{parts: [3]int64{c.parts[0] + 1, 0, 0}}
+		if c.parts[0] == 0 {
+			upper = version{parts: [3]int64{0, c.parts[1] + 1, 0}}
+		}
+		return cmp >= 0 && v.compare(upper) < 0, nil
+	}
+	return false, fmt.Errorf("invalid constraint operator %q", op)
+}
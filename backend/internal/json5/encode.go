@@ -0,0 +1,144 @@
+package json5
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MaxNodes caps the number of values Encode writes, so that aliases
+// expanding to a huge tree ("billion laughs") fail instead of exhausting
+// memory.
+const MaxNodes = 1 << 21
+
+// Encode writes node as strict JSON indented by indent spaces per level,
+// keeping the order of object members. Scalars are written by tag, so the
+// tree may also come from a YAML document; aliases are expanded. An alias
+// inside the node it refers to, or a tree of more than MaxNodes values, is
+// an error.
+func Encode(node *yaml.Node, indent int) (string, error) {
+	if indent <= 0 {
+		indent = 2
+	}
+	e := &encoder{indent: strings.Repeat(" ", indent), expanding: map[*yaml.Node]bool{}}
+	if err := e.encode(node, 0); err != nil {
+		return "", err
+	}
+	e.buf.WriteByte('\n')
+	return e.buf.String(), nil
+}
+
+type encoder struct {
+	buf    bytes.Buffer
+	indent string
+	// expanding holds the targets of the aliases being written, and nodes
+	// counts the values written so far
+	expanding map[*yaml.Node]bool
+	nodes     int
+}
+
+func (e *encoder) newline(depth int) {
+	e.buf.WriteByte('\n')
+	for i := 0; i < depth; i++ {
+		e.buf.WriteString(e.indent)
+	}
+}
+
+func (e *encoder) encode(n *yaml.Node, depth int) error {
+	if e.nodes++; e.nodes > MaxNodes {
+		return fmt.Errorf("line %d: the document expands to more than %d values", n.Line, MaxNodes)
+	}
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			e.buf.WriteString("null")
+			return nil
+		}
+		return e.encode(n.Content[0], depth)
+	case yaml.AliasNode:
+		if e.expanding[n.Alias] {
+			return fmt.Errorf("line %d: alias *%s refers to a value that contains it", n.Line, n.Value)
+		}
+		e.expanding[n.Alias] = true
+		defer delete(e.expanding, n.Alias)
+		return e.encode(n.Alias, depth)
+	case yaml.MappingNode:
+		if len(n.Content) == 0 {
+			e.buf.WriteString("{}")
+			return nil
+		}
+		e.buf.WriteByte('{')
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if i > 0 {
+				e.buf.WriteByte(',')
+			}
+			e.newline(depth + 1)
+			e.str(n.Content[i].Value)
+			e.buf.WriteString(": ")
+			if err := e.encode(n.Content[i+1], depth+1); err != nil {
+				return err
+			}
+		}
+		e.newline(depth)
+		e.buf.WriteByte('}')
+		return nil
+	case yaml.SequenceNode:
+		if len(n.Content) == 0 {
+			e.buf.WriteString("[]")
+			return nil
+		}
+		e.buf.WriteByte('[')
+		for i, c := range n.Content {
+			if i > 0 {
+				e.buf.WriteByte(',')
+			}
+			e.newline(depth + 1)
+			if err := e.encode(c, depth+1); err != nil {
+				return err
+			}
+		}
+		e.newline(depth)
+		e.buf.WriteByte(']')
+		return nil
+	case yaml.ScalarNode:
+		return e.scalar(n)
+	}
+	return fmt.Errorf("line %d: cannot encode node of kind %d as JSON", n.Line, n.Kind)
+}
+
+func (e *encoder) scalar(n *yaml.Node) error {
+	switch n.ShortTag() {
+	case "!!null":
+		e.buf.WriteString("null")
+	case "!!bool", "!!int", "!!float":
+		// JSON literals are kept as written, even numbers beyond float64
+		if n.Style == 0 && json.Valid([]byte(n.Value)) {
+			e.buf.WriteString(n.Value)
+			return nil
+		}
+		// resolve YAML spellings (0x1f, .inf, True) through the decoder
+		var v any
+		if err := n.Decode(&v); err != nil {
+			return fmt.Errorf("line %d: %w", n.Line, err)
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", n.Line, err)
+		}
+		e.buf.Write(data)
+	default:
+		e.str(n.Value)
+	}
+	return nil
+}
+
+func (e *encoder) str(s string) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	e.buf.Write(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+}
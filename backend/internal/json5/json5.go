@@ -0,0 +1,249 @@
+// Package json5 reads JSON leniently, accepting the JSON5/JSONC extensions
+// people commonly paste into configuration files, and writes strict,
+// pretty-printed JSON back out. Parsed values are yaml.Node trees so that the
+// node-based fixers and checks work on JSON unchanged; every node carries its
+// source line and column.
+package json5
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SyntaxError is content that cannot be read even leniently. Line and Column
+// are 1-based.
+type SyntaxError struct {
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Msg)
+}
+
+// Parse reads content into a DocumentNode and reports every repair needed to
+// make it strict JSON, in source order. Besides strict JSON it accepts "//"
+// and "/* */" comments, trailing, repeated and missing commas, single-quoted
+// strings, JSON5 string escapes, unquoted keys, hexadecimal numbers, leading
+// "+" or zeros, bare decimal points, and NaN and Infinity, which become null.
+func Parse(content string) (*yaml.Node, []Repair, error) {
+	p := &parser{tz: NewTokenizer(content)}
+	if err := p.advance(); err != nil {
+		return nil, nil, err
+	}
+	if p.tok.Kind == EOF {
+		return nil, nil, p.errorf("empty content")
+	}
+	v, err := p.value()
+	if err != nil {
+		return nil, nil, err
+	}
+	if p.tok.Kind != EOF {
+		return nil, nil, p.errorf("unexpected %s after the top-level value", describe(p.tok))
+	}
+	return &yaml.Node{Kind: yaml.DocumentNode, Line: 1, Column: 1, Content: []*yaml.Node{v}}, p.repairs(), nil
+}
+
+type parser struct {
+	tz  *Tokenizer
+	tok Token
+	// own holds the repairs found by the parser; the tokenizer keeps its own
+	own []Repair
+}
+
+func (p *parser) advance() error {
+	tok, err := p.tz.Next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *parser) repair(kind string, tok Token, format string, args ...any) {
+	p.own = append(p.own, Repair{Kind: kind, Line: tok.Line, Column: tok.Column, Message: fmt.Sprintf(format, args...)})
+}
+
+// repairs merges the tokenizer's and the parser's repairs in source order.
+func (p *parser) repairs() []Repair {
+	a, b := p.tz.Repairs, p.own
+	out := make([]Repair, 0, len(a)+len(b))
+	for len(a) > 0 || len(b) > 0 {
+		if len(b) == 0 || len(a) > 0 && (a[0].Line < b[0].Line || a[0].Line == b[0].Line && a[0].Column <= b[0].Column) {
+			out, a = append(out, a[0]), a[1:]
+		} else {
+			out, b = append(out, b[0]), b[1:]
+		}
+	}
+	return out
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return &SyntaxError{Line: p.tok.Line, Column: p.tok.Column, Msg: fmt.Sprintf(format, args...)}
+}
+
+func describe(tok Token) string {
+	switch tok.Kind {
+	case EOF:
+		return "end of input"
+	case LeftBrace:
+		return "'{'"
+	case RightBrace:
+		return "'}'"
+	case LeftBracket:
+		return "'['"
+	case RightBracket:
+		return "']'"
+	case Colon:
+		return "':'"
+	case Comma:
+		return "','"
+	case String:
+		return fmt.Sprintf("string %q", tok.Text)
+	}
+	return fmt.Sprintf("%q", tok.Text)
+}
+
+func (p *parser) value() (*yaml.Node, error) {
+	tok := p.tok
+	n := &yaml.Node{Kind: yaml.ScalarNode, Line: tok.Line, Column: tok.Column}
+	switch tok.Kind {
+	case LeftBrace:
+		return p.object()
+	case LeftBracket:
+		return p.array()
+	case String:
+		n.Tag, n.Value, n.Style = "!!str", tok.Text, yaml.DoubleQuotedStyle
+	case Number:
+		n.Tag, n.Value = "!!int", tok.Text
+		if tok.Float {
+			n.Tag = "!!float"
+		}
+	case Word:
+		switch tok.Text {
+		case "true", "false":
+			n.Tag, n.Value = "!!bool", tok.Text
+		case "null":
+			n.Tag, n.Value = "!!null", tok.Text
+		case "NaN", "Infinity":
+			p.repair(RepairNonFinite, tok, "replaced %s with null", tok.Text)
+			n.Tag, n.Value = "!!null", "null"
+		default:
+			return nil, p.errorf("unexpected identifier %q", tok.Text)
+		}
+	default:
+		return nil, p.errorf("unexpected %s", describe(tok))
+	}
+	return n, p.advance()
+}
+
+func (p *parser) object() (*yaml.Node, error) {
+	n := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Style: yaml.FlowStyle, Line: p.tok.Line, Column: p.tok.Column}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	for {
+		if err := p.commas(RightBrace, len(n.Content) == 0); err != nil {
+			return nil, err
+		}
+		switch p.tok.Kind {
+		case EOF:
+			return nil, &SyntaxError{Line: n.Line, Column: n.Column, Msg: "unterminated object"}
+		case RightBrace:
+			return n, p.advance()
+		}
+
+		key, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.Kind != Colon {
+			return nil, p.errorf("expected ':' after key %q, found %s", key.Value, describe(p.tok))
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if p.tok.Kind == EOF {
+			return nil, &SyntaxError{Line: n.Line, Column: n.Column, Msg: "unterminated object"}
+		}
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		n.Content = append(n.Content, key, v)
+
+		switch p.tok.Kind {
+		case Comma, RightBrace, EOF:
+		case String, Word, Number:
+			p.repair(RepairMissingComma, p.tok, "inserted missing comma between object members")
+		default:
+			return nil, p.errorf("expected ',' or '}' after object member, found %s", describe(p.tok))
+		}
+	}
+}
+
+func (p *parser) array() (*yaml.Node, error) {
+	n := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Style: yaml.FlowStyle, Line: p.tok.Line, Column: p.tok.Column}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	for {
+		if err := p.commas(RightBracket, len(n.Content) == 0); err != nil {
+			return nil, err
+		}
+		switch p.tok.Kind {
+		case EOF:
+			return nil, &SyntaxError{Line: n.Line, Column: n.Column, Msg: "unterminated array"}
+		case RightBracket:
+			return n, p.advance()
+		}
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		n.Content = append(n.Content, v)
+
+		switch p.tok.Kind {
+		case Comma, RightBracket, EOF:
+		case LeftBrace, LeftBracket, String, Number, Word:
+			p.repair(RepairMissingComma, p.tok, "inserted missing comma between array elements")
+		default:
+			return nil, p.errorf("expected ',' or ']' after array element, found %s", describe(p.tok))
+		}
+	}
+}
+
+// commas consumes the separators before the next member or the closing
+// token, repairing leading, repeated and trailing commas.
+func (p *parser) commas(closing TokenKind, first bool) error {
+	seen := 0
+	for p.tok.Kind == Comma {
+		tok := p.tok
+		if err := p.advance(); err != nil {
+			return err
+		}
+		seen++
+		switch {
+		case p.tok.Kind == closing:
+			p.repair(RepairTrailingComma, tok, "removed trailing comma")
+		case first || seen > 1:
+			p.repair(RepairExtraComma, tok, "removed extra comma")
+		}
+	}
+	return nil
+}
+
+func (p *parser) key() (*yaml.Node, error) {
+	tok := p.tok
+	n := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Style: yaml.DoubleQuotedStyle, Value: tok.Text, Line: tok.Line, Column: tok.Column}
+	switch tok.Kind {
+	case String:
+	case Word, Number:
+		p.repair(RepairUnquotedKey, tok, "quoted key %q", tok.Text)
+	default:
+		return nil, p.errorf("expected an object key, found %s", describe(tok))
+	}
+	return n, p.advance()
+}
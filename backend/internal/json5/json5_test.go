@@ -0,0 +1,145 @@
+package json5
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		// want is the strict JSON Encode writes for the parsed tree
+		want    string
+		repairs []string
+	}{
+		{"strict", `{"a": [1, 2.5, true, null, "x"]}`, `{"a":[1,2.5,true,null,"x"]}`, nil},
+		{"line comment", "{\n  // note\n  \"a\": 1\n}", `{"a":1}`, []string{"comment 2:3"}},
+		{"block comment", `{"a": /* note */ 1}`, `{"a":1}`, []string{"comment 1:7"}},
+		{"trailing comma", `{"a": [1, 2,],}`, `{"a":[1,2]}`, []string{"trailing-comma 1:12", "trailing-comma 1:14"}},
+		{"extra comma", `[1,, 2]`, `[1,2]`, []string{"extra-comma 1:4"}},
+		{"missing comma", "{\"a\": 1\n \"b\": 2}", `{"a":1,"b":2}`, []string{"missing-comma 2:2"}},
+		{"single quotes", `{'a': 'x"y'}`, `{"a":"x\"y"}`, []string{"single-quotes 1:2", "single-quotes 1:7"}},
+		{"unquoted key", `{a: 1}`, `{"a":1}`, []string{"unquoted-key 1:2"}},
+		{"hex", `[0x1F]`, `[31]`, []string{"hex-number 1:2"}},
+		{"numbers", `[+1, .5, 5., 007]`, `[1,0.5,5,7]`, []string{"number 1:2", "number 1:6", "number 1:10", "number 1:14"}},
+		{"non-finite", `[NaN, -Infinity]`, `[null,null]`, []string{"non-finite 1:2", "non-finite 1:7"}},
+		{"escapes", `["\x41\'"]`, `["A'"]`, []string{"escape 1:3", "escape 1:7"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, repairs, err := Parse(tt.content)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			out, err := Encode(node, 2)
+			if err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			if got := compact(out); got != tt.want {
+				t.Errorf("Parse() = %s, want %s", got, tt.want)
+			}
+			var got []string
+			for _, r := range repairs {
+				got = append(got, fmt.Sprintf("%s %d:%d", r.Kind, r.Line, r.Column))
+			}
+			if strings.Join(got, ", ") != strings.Join(tt.repairs, ", ") {
+				t.Errorf("repairs = %v, want %v", got, tt.repairs)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		content      string
+		line, column int
+	}{
+		{"empty", "  ", 1, 3},
+		{"unterminated array", "{\"a\": [1,\n", 1, 7},
+		{"unterminated string", `{"a": "x}`, 1, 7},
+		{"two values", `{} {}`, 1, 4},
+		{"missing value", `{"a": }`, 1, 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Parse(tt.content)
+			se, ok := err.(*SyntaxError)
+			if !ok {
+				t.Fatalf("Parse() error = %v, want a *SyntaxError", err)
+			}
+			if se.Line != tt.line || se.Column != tt.column {
+				t.Errorf("error at %d:%d (%s), want %d:%d", se.Line, se.Column, se.Msg, tt.line, tt.column)
+			}
+		})
+	}
+}
+
+func TestParsePositions(t *testing.T) {
+	node, _, err := Parse("{\n  \"a\": {\n    \"b\": [true]\n  }\n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := node.Content[0].Content[1].Content[1].Content[0]
+	if b.Line != 3 || b.Column != 11 || b.Tag != "!!bool" {
+		t.Errorf("true parsed at %d:%d with tag %s, want 3:11 !!bool", b.Line, b.Column, b.Tag)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		want    string
+		wantErr bool
+	}{
+		{"key order", "b: 1\na: 2\n", "{\n  \"b\": 1,\n  \"a\": 2\n}\n", false},
+		{"tags", "a: yes\nb: 0x10\nc: ~\nd: '1'\n", "{\n  \"a\": \"yes\",\n  \"b\": 16,\n  \"c\": null,\n  \"d\": \"1\"\n}\n", false},
+		{"empty collections", "a: {}\nb: []\n", "{\n  \"a\": {},\n  \"b\": []\n}\n", false},
+		{"alias", "a: &x [1]\nb: *x\n", "{\n  \"a\": [\n    1\n  ],\n  \"b\": [\n    1\n  ]\n}\n", false},
+		{"recursive alias", "a: &x\n  b: *x\n", "", true},
+		{"alias bomb", aliasBomb(), "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var node yaml.Node
+			if err := yaml.Unmarshal([]byte(tt.yaml), &node); err != nil {
+				t.Fatal(err)
+			}
+			got, err := Encode(&node, 2)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Encode() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Encode() =\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+// aliasBomb returns YAML whose aliases expand to 10^9 values.
+func aliasBomb() string {
+	var b strings.Builder
+	b.WriteString("l0: &l0 [x, x, x, x, x, x, x, x, x, x]\n")
+	for i := 1; i < 9; i++ {
+		fmt.Fprintf(&b, "l%d: &l%d [", i, i)
+		for j := 0; j < 10; j++ {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			fmt.Fprintf(&b, "*l%d", i-1)
+		}
+		b.WriteString("]\n")
+	}
+	return b.String()
+}
+
+// compact removes the whitespace Encode puts between tokens; the tests use
+// no spaces inside strings.
+func compact(s string) string {
+	return strings.Join(strings.Fields(s), "")
+}
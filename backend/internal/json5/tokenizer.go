@@ -0,0 +1,382 @@
+package json5
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TokenKind classifies a token.
+type TokenKind int
+
+const (
+	EOF TokenKind = iota
+	LeftBrace
+	RightBrace
+	LeftBracket
+	RightBracket
+	Colon
+	Comma
+	// String is a quoted string; Text holds its decoded value.
+	String
+	// Number is a numeric literal; Text holds it as a strict JSON number.
+	Number
+	// Word is an unquoted word: true, false, null, NaN, Infinity or an
+	// unquoted key.
+	Word
+)
+
+// Token is one lexical element. Line and Column (1-based) locate its first
+// character.
+type Token struct {
+	Kind TokenKind
+	Text string
+	// Float reports whether a Number has a fraction or exponent.
+	Float  bool
+	Line   int
+	Column int
+}
+
+// Kinds of Repair.
+const (
+	RepairComment       = "comment"
+	RepairTrailingComma = "trailing-comma"
+	RepairExtraComma    = "extra-comma"
+	RepairMissingComma  = "missing-comma"
+	RepairSingleQuotes  = "single-quotes"
+	RepairUnquotedKey   = "unquoted-key"
+	RepairEscape        = "escape"
+	RepairNumber        = "number"
+	RepairHexNumber     = "hex-number"
+	RepairNonFinite     = "non-finite"
+)
+
+// Repair is one deviation from strict JSON that was accepted and rewritten.
+type Repair struct {
+	Kind    string
+	Line    int
+	Column  int
+	Message string
+}
+
+// Tokenizer splits lenient JSON into tokens, skipping comments and
+// normalizing strings and numbers. Repairs collects what it rewrote.
+type Tokenizer struct {
+	src       string
+	pos       int
+	line, col int
+	Repairs   []Repair
+}
+
+// NewTokenizer returns a tokenizer over content.
+func NewTokenizer(content string) *Tokenizer {
+	return &Tokenizer{src: content, line: 1, col: 1}
+}
+
+func (t *Tokenizer) eof() bool { return t.pos >= len(t.src) }
+
+func (t *Tokenizer) peek() rune {
+	r, _ := utf8.DecodeRuneInString(t.src[t.pos:])
+	return r
+}
+
+func (t *Tokenizer) next() rune {
+	r, size := utf8.DecodeRuneInString(t.src[t.pos:])
+	t.pos += size
+	if r == '\n' {
+		t.line++
+		t.col = 1
+	} else {
+		t.col++
+	}
+	return r
+}
+
+func (t *Tokenizer) repair(kind string, line, col int, format string, args ...any) {
+	t.Repairs = append(t.Repairs, Repair{Kind: kind, Line: line, Column: col, Message: fmt.Sprintf(format, args...)})
+}
+
+func (t *Tokenizer) errorAt(line, col int, format string, args ...any) error {
+	return &SyntaxError{Line: line, Column: col, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Next returns the next token, or a token of kind EOF at the end.
+func (t *Tokenizer) Next() (Token, error) {
+	if err := t.skip(); err != nil {
+		return Token{}, err
+	}
+	tok := Token{Line: t.line, Column: t.col}
+	if t.eof() {
+		return tok, nil
+	}
+	r := t.peek()
+	switch r {
+	case '{':
+		tok.Kind = LeftBrace
+	case '}':
+		tok.Kind = RightBrace
+	case '[':
+		tok.Kind = LeftBracket
+	case ']':
+		tok.Kind = RightBracket
+	case ':':
+		tok.Kind = Colon
+	case ',':
+		tok.Kind = Comma
+	}
+	if tok.Kind != EOF {
+		t.next()
+		return tok, nil
+	}
+
+	switch {
+	case r == '"' || r == '\'':
+		s, err := t.str()
+		tok.Kind, tok.Text = String, s
+		return tok, err
+	case r == '-' || r == '+' || r == '.' || r >= '0' && r <= '9':
+		return t.number()
+	case isWordRune(r):
+		tok.Kind, tok.Text = Word, t.word()
+		return tok, nil
+	}
+	return tok, t.errorAt(tok.Line, tok.Column, "unexpected %q", r)
+}
+
+// skip consumes whitespace and comments.
+func (t *Tokenizer) skip() error {
+	for !t.eof() {
+		r := t.peek()
+		switch {
+		case unicode.IsSpace(r) || r == '\ufeff':
+			t.next()
+		case strings.HasPrefix(t.src[t.pos:], "//"):
+			t.repair(RepairComment, t.line, t.col, "removed line comment")
+			for !t.eof() && t.peek() != '\n' {
+				t.next()
+			}
+		case strings.HasPrefix(t.src[t.pos:], "/*"):
+			line, col := t.line, t.col
+			t.next()
+			t.next()
+			for !strings.HasPrefix(t.src[t.pos:], "*/") {
+				if t.eof() {
+					return t.errorAt(line, col, "unterminated comment")
+				}
+				t.next()
+			}
+			t.next()
+			t.next()
+			t.repair(RepairComment, line, col, "removed block comment")
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// word reads an unquoted word. Dashes and dots are allowed after the first
+// character so that keys such as max-age or app.version stay whole.
+func (t *Tokenizer) word() string {
+	start := t.pos
+	for !t.eof() && (isWordRune(t.peek()) || isDigit(t.peek()) || t.peek() == '-' || t.peek() == '.') {
+		t.next()
+	}
+	return t.src[start:t.pos]
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || r == '$' || unicode.IsLetter(r)
+}
+
+func isDigit(r rune) bool { return r >= '0' && r <= '9' }
+
+// str reads a double- or single-quoted string and returns its value.
+func (t *Tokenizer) str() (string, error) {
+	line, col := t.line, t.col
+	quote := t.next()
+	if quote == '\'' {
+		t.repair(RepairSingleQuotes, line, col, "replaced single quotes with double quotes")
+	}
+	var sb strings.Builder
+	for {
+		if t.eof() {
+			return "", t.errorAt(line, col, "unterminated string")
+		}
+		r := t.next()
+		switch {
+		case r == quote:
+			return sb.String(), nil
+		case r == '\n':
+			return "", t.errorAt(line, col, "unterminated string")
+		case r != '\\':
+			sb.WriteRune(r)
+			continue
+		}
+		if t.eof() {
+			return "", t.errorAt(line, col, "unterminated string")
+		}
+		escLine, escCol := t.line, t.col-1
+		e := t.next()
+		switch e {
+		case 'b':
+			sb.WriteByte('\b')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 't':
+			sb.WriteByte('\t')
+		case '"', '\\', '/':
+			sb.WriteRune(e)
+		case 'u':
+			r, err := t.hexRune(4)
+			if err != nil {
+				return "", t.errorAt(escLine, escCol, "invalid \\u escape")
+			}
+			if r >= 0xd800 && r < 0xdc00 && strings.HasPrefix(t.src[t.pos:], "\\u") {
+				t.next()
+				t.next()
+				low, err := t.hexRune(4)
+				if err != nil {
+					return "", t.errorAt(escLine, escCol, "invalid \\u escape")
+				}
+				r = (r-0xd800)<<10 + (low - 0xdc00) + 0x10000
+			}
+			sb.WriteRune(r)
+		case 'x':
+			r, err := t.hexRune(2)
+			if err != nil {
+				return "", t.errorAt(escLine, escCol, "invalid \\x escape")
+			}
+			sb.WriteRune(r)
+			t.repair(RepairEscape, escLine, escCol, "rewrote \\x escape")
+		default:
+			// JSON5 escapes: \v, \0, line continuations and any other
+			// character standing for itself
+			switch e {
+			case 'v':
+				sb.WriteByte('\v')
+			case '0':
+				sb.WriteByte(0)
+			case '\n':
+			default:
+				sb.WriteRune(e)
+			}
+			t.repair(RepairEscape, escLine, escCol, "rewrote non-JSON escape %q", "\\"+string(e))
+		}
+	}
+}
+
+func (t *Tokenizer) hexRune(digits int) (rune, error) {
+	if len(t.src)-t.pos < digits {
+		return 0, strconv.ErrSyntax
+	}
+	v, err := strconv.ParseUint(t.src[t.pos:t.pos+digits], 16, 32)
+	if err != nil {
+		return 0, err
+	}
+	for i := 0; i < digits; i++ {
+		t.next()
+	}
+	return rune(v), nil
+}
+
+// number reads a numeric literal, including JSON5 hexadecimal, Infinity
+// and NaN, and rewrites it as a strict JSON number. Non-finite values are
+// returned as a Word token "null".
+func (t *Tokenizer) number() (Token, error) {
+	tok := Token{Kind: Number, Line: t.line, Column: t.col}
+	start := t.pos
+	sign := ""
+	if r := t.peek(); r == '+' || r == '-' {
+		t.next()
+		if r == '-' {
+			sign = "-"
+		}
+	}
+	invalid := func() (Token, error) {
+		return tok, t.errorAt(tok.Line, tok.Column, "invalid number %q", t.src[start:t.pos])
+	}
+
+	if !t.eof() && isWordRune(t.peek()) {
+		word := t.word()
+		if word != "Infinity" && word != "NaN" {
+			return invalid()
+		}
+		t.repair(RepairNonFinite, tok.Line, tok.Column, "replaced %s with null", t.src[start:t.pos])
+		return Token{Kind: Word, Text: "null", Line: tok.Line, Column: tok.Column}, nil
+	}
+
+	if strings.HasPrefix(t.src[t.pos:], "0x") || strings.HasPrefix(t.src[t.pos:], "0X") {
+		t.next()
+		t.next()
+		digitsStart := t.pos
+		for !t.eof() && strings.ContainsRune("0123456789abcdefABCDEF", t.peek()) {
+			t.next()
+		}
+		v, ok := new(big.Int).SetString(t.src[digitsStart:t.pos], 16)
+		if !ok {
+			return invalid()
+		}
+		tok.Text = sign + v.String()
+		if tok.Text == "-0" {
+			tok.Text = "0"
+		}
+		t.repair(RepairHexNumber, tok.Line, tok.Column, "rewrote %s as %s", t.src[start:t.pos], tok.Text)
+		return tok, nil
+	}
+
+	digits := func() string {
+		s := t.pos
+		for !t.eof() && isDigit(t.peek()) {
+			t.next()
+		}
+		return t.src[s:t.pos]
+	}
+	intPart := digits()
+	frac, exp := "", ""
+	hasDot := false
+	if !t.eof() && t.peek() == '.' {
+		t.next()
+		frac = digits()
+		hasDot = true
+		tok.Float = true
+	}
+	if intPart == "" && frac == "" {
+		return invalid()
+	}
+	if !t.eof() && (t.peek() == 'e' || t.peek() == 'E') {
+		expStart := t.pos
+		t.next()
+		if r := t.peek(); r == '+' || r == '-' {
+			t.next()
+		}
+		if digits() == "" {
+			return invalid()
+		}
+		exp = t.src[expStart:t.pos]
+		tok.Float = true
+	}
+
+	normInt := strings.TrimLeft(intPart, "0")
+	if normInt == "" {
+		normInt = "0"
+	}
+	tok.Text = sign + normInt
+	if frac != "" {
+		tok.Text += "." + frac
+	}
+	tok.Text += exp
+	// leading "+", leading zeros and bare decimal points are not JSON
+	raw := t.src[start:t.pos]
+	if raw[0] == '+' || normInt != intPart || hasDot && (intPart == "" || frac == "") {
+		t.repair(RepairNumber, tok.Line, tok.Column, "rewrote %s as %s", raw, tok.Text)
+	}
+	return tok, nil
+}
@@ -0,0 +1,247 @@
+package kubernetes
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"devformat/backend/internal/schema"
+)
+
+// Bundled OpenAPI (swagger 2.0) definitions, one file per Kubernetes minor
+// version. They are a subset of the upstream definitions covering the common
+// built-in kinds, so validation works fully offline.
+//
+//go:embed schemas/*.json
+var bundled embed.FS
+
+// DefaultVersion is used when a request does not name a Kubernetes version.
+const DefaultVersion = "v1.30"
+
+var (
+	// ErrUnknownVersion is returned for versions without a bundled schema set.
+	ErrUnknownVersion = errors.New("unsupported Kubernetes version")
+	// ErrNoSchema is returned when no bundled schema describes the apiVersion/kind.
+	ErrNoSchema = errors.New("no bundled schema")
+)
+
+type bundle struct {
+	schema *schema.Schema
+	// refs maps "apiVersion/kind" (e.g. "apps/v1/Deployment") to a definition $ref.
+	refs map[string]string
+}
+
+var (
+	mu      sync.Mutex
+	bundles = map[string]*bundle{}
+)
+
+// Versions returns the Kubernetes versions with bundled schemas, oldest first.
+func Versions() []string {
+	entries, _ := bundled.ReadDir("schemas")
+	out := make([]string, 0, len(entries))
+	for _, e := range entries {
+		out = append(out, strings.TrimSuffix(e.Name(), ".json"))
+	}
+	sort.Slice(out, func(i, j int) bool { return minor(out[i]) < minor(out[j]) })
+	return out
+}
+
+// NormalizeVersion maps user input such as "1.28", "v1.28" or "1.28.3" onto
+// a bundled version ("v1.28"). An empty string selects DefaultVersion.
+func NormalizeVersion(v string) (string, error) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return DefaultVersion, nil
+	}
+	v = "v" + strings.TrimPrefix(v, "v")
+	if parts := strings.Split(v, "."); len(parts) > 2 {
+		v = parts[0] + "." + parts[1]
+	}
+	for _, known := range Versions() {
+		if known == v {
+			return v, nil
+		}
+	}
+	return "", fmt.Errorf("%w %q (supported: %s)", ErrUnknownVersion, v, strings.Join(Versions(), ", "))
+}
+
+// ResolveVersion is NormalizeVersion for versions without bundled schemas as
+// well: a well-formed version such as "1.29" maps onto the nearest bundled
+// one, the newer on a tie, and substituted reports that it did. Input that is
+// not a version is still an error.
+func ResolveVersion(v string) (version string, substituted bool, err error) {
+	version, err = NormalizeVersion(v)
+	if err == nil || !errors.Is(err, ErrUnknownVersion) {
+		return version, false, err
+	}
+	want := "v" + strings.TrimPrefix(strings.TrimSpace(v), "v")
+	var major, min int
+	if n, _ := fmt.Sscanf(want, "v%d.%d", &major, &min); n != 2 {
+		return "", false, err
+	}
+	best, bestDist := "", -1
+	for _, known := range Versions() {
+		dist := minor(known) - (major*1000 + min)
+		if dist < 0 {
+			dist = -dist
+		}
+		// Versions is oldest first, so a tie keeps the newer version
+		if bestDist < 0 || dist <= bestDist {
+			best, bestDist = known, dist
+		}
+	}
+	if best == "" {
+		return "", false, err
+	}
+	return best, true, nil
+}
+
+// Validate checks a Kubernetes manifest against the bundled schema of the
+// given version. Unknown fields, wrong types and missing required fields are
+// reported as violations. It returns an error wrapping ErrNoSchema when the
+// apiVersion/kind is not bundled (for example custom resources).
+func Validate(node *yaml.Node, version string) ([]schema.Violation, error) {
+	version, err := NormalizeVersion(version)
+	if err != nil {
+		return nil, err
+	}
+	b, err := load(version)
+	if err != nil {
+		return nil, err
+	}
+
+	if node != nil && node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	if node == nil || node.Kind != yaml.MappingNode {
+		v := schema.Violation{Keyword: "type", Message: "a Kubernetes manifest must be an object"}
+		if node != nil {
+			v.Line, v.Column = node.Line, node.Column
+		}
+		return []schema.Violation{v}, nil
+	}
+
+	fields := map[string]schema.Member{}
+	for _, m := range schema.Members(node) {
+		fields[m.Name] = m
+	}
+	var out []schema.Violation
+	for _, name := range []string{"apiVersion", "kind"} {
+		if m, ok := fields[name]; !ok || strings.TrimSpace(m.Value.Value) == "" {
+			out = append(out, schema.Violation{Keyword: "required", Message: fmt.Sprintf("missing required field %q", name), Line: node.Line, Column: node.Column})
+		}
+	}
+	if len(out) > 0 {
+		return out, nil
+	}
+
+	apiVersion, kind := fields["apiVersion"].Value.Value, fields["kind"].Value.Value
+	ref, ok := b.refs[apiVersion+"/"+kind]
+	if !ok {
+		return nil, fmt.Errorf("%w for %s %s in Kubernetes %s", ErrNoSchema, apiVersion, kind, version)
+	}
+	out, err = b.schema.ValidateRef(ref, node)
+	if err != nil {
+		return nil, err
+	}
+
+	// The API server requires a name unless generateName is set; the OpenAPI
+	// definitions cannot express that, so check it here.
+	if md, ok := fields["metadata"]; !ok {
+		out = append(out, schema.Violation{Path: "/metadata", Keyword: "required", Message: "missing required field \"metadata\"", Line: node.Line, Column: node.Column})
+	} else if md.Value.Kind == yaml.MappingNode {
+		hasName := false
+		for _, m := range schema.Members(md.Value) {
+			if (m.Name == "name" || m.Name == "generateName") && strings.TrimSpace(m.Value.Value) != "" {
+				hasName = true
+			}
+		}
+		if !hasName {
+			out = append(out, schema.Violation{Path: "/metadata", Keyword: "required", Message: "metadata.name is required", Line: md.Key.Line, Column: md.Key.Column})
+		}
+	}
+	return out, nil
+}
+
+// load compiles the bundled definitions for a version once and caches them.
+func load(version string) (*bundle, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if b, ok := bundles[version]; ok {
+		return b, nil
+	}
+
+	data, err := bundled.ReadFile(path.Join("schemas", version+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("%w %q", ErrUnknownVersion, version)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("bundled schema %s: %w", version, err)
+	}
+	defs, _ := doc["definitions"].(map[string]any)
+
+	refs := map[string]string{}
+	for name, def := range defs {
+		d, ok := def.(map[string]any)
+		if !ok {
+			continue
+		}
+		strict(d)
+		gvks, _ := d["x-kubernetes-group-version-kind"].([]any)
+		for _, g := range gvks {
+			gvk, _ := g.(map[string]any)
+			group, _ := gvk["group"].(string)
+			ver, _ := gvk["version"].(string)
+			kind, _ := gvk["kind"].(string)
+			apiVersion := ver
+			if group != "" {
+				apiVersion = group + "/" + ver
+			}
+			refs[apiVersion+"/"+kind] = "#/definitions/" + name
+		}
+	}
+
+	s, err := schema.New(doc)
+	if err != nil {
+		return nil, fmt.Errorf("bundled schema %s: %w", version, err)
+	}
+	b := &bundle{schema: s, refs: refs}
+	bundles[version] = b
+	return b, nil
+}
+
+// strict rejects unknown fields on every object definition that lists its
+// properties, the same way kubeconform's strict mode does.
+func strict(def map[string]any) {
+	if props, ok := def["properties"].(map[string]any); ok {
+		if _, set := def["additionalProperties"]; !set {
+			def["additionalProperties"] = false
+		}
+		for _, p := range props {
+			if m, ok := p.(map[string]any); ok {
+				strict(m)
+			}
+		}
+	}
+	for _, k := range []string{"items", "additionalProperties"} {
+		if m, ok := def[k].(map[string]any); ok {
+			strict(m)
+		}
+	}
+}
+
+// minor returns the minor number of a "v1.N" version for sorting.
+func minor(v string) int {
+	var major, min int
+	fmt.Sscanf(v, "v%d.%d", &major, &min)
+	return major*1000 + min
+}
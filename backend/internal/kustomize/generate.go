@@ -0,0 +1,200 @@
+package kustomize
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// generate adds the ConfigMaps or Secrets of the kustomization's
+// generators. field names the generator list, for error lines.
+func (l *level) generate(kind, field string, gens []Generator) {
+	for i, g := range gens {
+		line := l.line(field, i)
+		if g.Name == "" {
+			l.errorf(l.file, line, "%s has no name", field)
+			continue
+		}
+		data, ok := l.generatorData(g, line)
+		if !ok {
+			continue
+		}
+
+		var existing *Resource
+		for _, r := range l.resources {
+			if r.Kind() == kind && r.names[0] == g.Name && (g.Namespace == "" || namespaceOf(r.Object) == g.Namespace) {
+				existing = r
+			}
+		}
+		switch g.Behavior {
+		case "", "create":
+			if existing != nil {
+				l.errorf(l.file, line, "%s %q already exists; use behavior merge or replace", kind, g.Name)
+				continue
+			}
+		case "merge", "replace":
+			if existing == nil {
+				l.errorf(l.file, line, "%s %q to %s is not generated by a base", kind, g.Name, g.Behavior)
+				continue
+			}
+			old, _ := existing.Object["data"].(map[string]any)
+			if g.Behavior == "merge" && old != nil {
+				for k, v := range data {
+					old[k] = v
+				}
+				data = old
+			}
+			existing.Object["data"] = data
+			l.applyGeneratorOptions(existing, g)
+			existing.File, existing.Line = l.file, line
+			continue
+		default:
+			l.errorf(l.file, line, "unknown generator behavior %q; use create, merge or replace", g.Behavior)
+			continue
+		}
+
+		meta := map[string]any{"name": g.Name}
+		if g.Namespace != "" {
+			meta["namespace"] = g.Namespace
+		}
+		obj := map[string]any{"apiVersion": "v1", "kind": kind, "metadata": meta, "data": data}
+		if kind == "Secret" {
+			typ := g.Type
+			if typ == "" {
+				typ = "Opaque"
+			}
+			obj["type"] = typ
+		}
+		r := &Resource{Object: obj, File: l.file, Line: line, names: []string{g.Name}, generated: true, hash: true}
+		l.applyGeneratorOptions(r, g)
+		l.resources = append(l.resources, r)
+	}
+}
+
+// generatorData collects a generator's literals, files and env files as
+// plain text; the data of generated Secrets is encoded when the build
+// finishes, so that overlays can merge into it.
+func (l *level) generatorData(g Generator, line int) (map[string]any, bool) {
+	data := map[string]any{}
+	put := func(key, value string) bool {
+		if _, dup := data[key]; dup {
+			l.errorf(l.file, line, "%s: key %q is defined twice", g.Name, key)
+			return false
+		}
+		data[key] = value
+		return true
+	}
+	for _, lit := range g.Literals {
+		k, v, ok := strings.Cut(lit, "=")
+		if !ok || k == "" {
+			l.errorf(l.file, line, "%s: literal %q is not key=value", g.Name, lit)
+			return nil, false
+		}
+		if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+			v = v[1 : len(v)-1]
+		}
+		if !put(k, v) {
+			return nil, false
+		}
+	}
+	for _, f := range g.Files {
+		key, p, ok := strings.Cut(f, "=")
+		if !ok {
+			key, p = path.Base(f), f
+		}
+		content, found := l.files[path.Join(l.dir, p)]
+		if !found {
+			l.errorf(l.file, line, "%s: file %q not found", g.Name, p)
+			return nil, false
+		}
+		if !put(key, content) {
+			return nil, false
+		}
+	}
+	envs := g.Envs
+	if g.Env != "" {
+		envs = append(envs, g.Env)
+	}
+	for _, e := range envs {
+		content, found := l.files[path.Join(l.dir, e)]
+		if !found {
+			l.errorf(l.file, line, "%s: env file %q not found", g.Name, e)
+			return nil, false
+		}
+		for n, raw := range strings.Split(content, "\n") {
+			entry := strings.TrimSpace(raw)
+			if entry == "" || strings.HasPrefix(entry, "#") {
+				continue
+			}
+			k, v, ok := strings.Cut(entry, "=")
+			if !ok {
+				l.errorf(l.file, line, "%s: line %d of %s is not KEY=VALUE", g.Name, n+1, e)
+				return nil, false
+			}
+			if !put(strings.TrimSpace(k), v) {
+				return nil, false
+			}
+		}
+	}
+	return data, true
+}
+
+// applyGeneratorOptions sets the labels and annotations of the
+// kustomization's generatorOptions and the generator's options, and turns
+// off the hash suffix when either asks to.
+func (l *level) applyGeneratorOptions(r *Resource, g Generator) {
+	for _, opts := range []*GeneratorOptions{l.k.GeneratorOptions, g.Options} {
+		if opts == nil {
+			continue
+		}
+		setStrings(metadata(r.Object), "labels", opts.Labels)
+		setStrings(metadata(r.Object), "annotations", opts.Annotations)
+		if opts.DisableNameSuffixHash {
+			r.hash = false
+		}
+	}
+}
+
+// encodeSecret base64-encodes the data of a generated Secret.
+func encodeSecret(r *Resource) {
+	if !r.generated || r.Kind() != "Secret" {
+		return
+	}
+	data, _ := r.Object["data"].(map[string]any)
+	for k, v := range data {
+		if s, ok := v.(string); ok {
+			data[k] = base64.StdEncoding.EncodeToString([]byte(s))
+		}
+	}
+}
+
+// contentHash returns the suffix kustomize appends to the name of a
+// generated resource: the first ten hex digits of the SHA-256 of its kind,
+// name, type and data, with some digits replaced by letters so the suffix
+// never forms a word.
+func contentHash(r *Resource) string {
+	encoded := map[string]any{"kind": r.Kind(), "name": r.Name(), "data": r.Object["data"]}
+	if r.Kind() == "Secret" {
+		encoded["type"] = r.Object["type"]
+	}
+	b, _ := json.Marshal(encoded)
+	sum := []byte(fmt.Sprintf("%x", sha256.Sum256(b))[:10])
+	for i, c := range sum {
+		switch c {
+		case '0':
+			sum[i] = 'g'
+		case '1':
+			sum[i] = 'h'
+		case '3':
+			sum[i] = 'k'
+		case 'a':
+			sum[i] = 'm'
+		case 'e':
+			sum[i] = 't'
+		}
+	}
+	return string(sum)
+}
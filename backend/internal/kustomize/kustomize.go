@@ -0,0 +1,503 @@
+// Package kustomize builds Kustomize overlays in memory, without the
+// kustomize binary, so that the manifests they produce can be validated. It
+// supports resources and bases (files and directories with their own
+// kustomization), configMapGenerator and secretGenerator, strategic merge
+// and JSON 6902 patches (patchesStrategicMerge, patchesJson6902 and
+// patches), namespace, namePrefix, nameSuffix, commonLabels and
+// commonAnnotations. Every resource remembers the document it was read from,
+// so problems found in the output can be reported in the source files.
+package kustomize
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"devformat/backend/internal/parser"
+)
+
+// FileNames are the names a kustomization file may have, in the order
+// kustomize looks for them.
+var FileNames = []string{"kustomization.yaml", "kustomization.yml", "Kustomization"}
+
+// Kustomization is a kustomization file.
+type Kustomization struct {
+	Resources             []string          `yaml:"resources"`
+	Bases                 []string          `yaml:"bases"`
+	Namespace             string            `yaml:"namespace"`
+	NamePrefix            string            `yaml:"namePrefix"`
+	NameSuffix            string            `yaml:"nameSuffix"`
+	CommonLabels          map[string]string `yaml:"commonLabels"`
+	CommonAnnotations     map[string]string `yaml:"commonAnnotations"`
+	PatchesStrategicMerge []string          `yaml:"patchesStrategicMerge"`
+	PatchesJSON6902       []Patch           `yaml:"patchesJson6902"`
+	Patches               []Patch           `yaml:"patches"`
+	ConfigMapGenerator    []Generator       `yaml:"configMapGenerator"`
+	SecretGenerator       []Generator       `yaml:"secretGenerator"`
+	GeneratorOptions      *GeneratorOptions `yaml:"generatorOptions"`
+}
+
+// Patch is an entry of patchesJson6902 or patches: a patch read from Path
+// or given inline in Patch, applied to the resources Target selects.
+type Patch struct {
+	Path   string    `yaml:"path"`
+	Patch  string    `yaml:"patch"`
+	Target *Selector `yaml:"target"`
+}
+
+// Selector picks the resources a patch applies to. Name and Namespace are
+// regular expressions matched against the whole value; LabelSelector is a
+// comma-separated list of key=value pairs.
+type Selector struct {
+	Group         string `yaml:"group"`
+	Version       string `yaml:"version"`
+	Kind          string `yaml:"kind"`
+	Name          string `yaml:"name"`
+	Namespace     string `yaml:"namespace"`
+	LabelSelector string `yaml:"labelSelector"`
+}
+
+// Generator is an entry of configMapGenerator or secretGenerator.
+type Generator struct {
+	Name      string `yaml:"name"`
+	Namespace string `yaml:"namespace"`
+	// Behavior is "create" (the default), "merge" or "replace"; the latter
+	// two change a resource generated by a base.
+	Behavior string   `yaml:"behavior"`
+	Files    []string `yaml:"files"`
+	Literals []string `yaml:"literals"`
+	Envs     []string `yaml:"envs"`
+	Env      string   `yaml:"env"`
+	// Type is the type of a generated Secret, "Opaque" by default.
+	Type    string            `yaml:"type"`
+	Options *GeneratorOptions `yaml:"options"`
+}
+
+// GeneratorOptions are the options of generated resources.
+type GeneratorOptions struct {
+	Labels                map[string]string `yaml:"labels"`
+	Annotations           map[string]string `yaml:"annotations"`
+	DisableNameSuffixHash bool              `yaml:"disableNameSuffixHash"`
+}
+
+// Resource is a manifest of the build output.
+type Resource struct {
+	Object map[string]any
+	// File and Line locate the document the resource was read from, or the
+	// generator entry that made it.
+	File string
+	Line int
+	// Node is the document's root node with lines in File; nil for
+	// generated resources.
+	Node *yaml.Node
+
+	// names are the names the resource had, oldest first; patches may
+	// target any of them
+	names []string
+	// patches are the patches applied, in order
+	patches []patchSource
+	// generated is set for the resources of generators, and hash for
+	// those that get a content hash suffix
+	generated bool
+	hash      bool
+}
+
+// patchSource is a strategic merge patch document, or the value of a JSON
+// 6902 operation, with lines and columns in file.
+type patchSource struct {
+	file string
+	node *yaml.Node
+	// pointer is where node was applied: empty for a strategic merge patch,
+	// the path of the operation for a JSON 6902 patch
+	pointer []string
+	// moved is set for a JSON 6902 move or copy, whose value is not in the
+	// patch; node is then the operation itself
+	moved bool
+}
+
+// Error is a problem that kept part of a kustomization from building. Line
+// is 1-based in File, or 0 when the problem is not tied to a line.
+type Error struct {
+	File    string
+	Line    int
+	Message string
+}
+
+// Build builds the kustomization in dir of files, which maps slash-separated
+// paths to contents. The resources that could be built are returned with
+// the problems of the parts that could not, in the legacy kind order of
+// "kustomize build"; unlike kustomize, a missing file or a patch without a
+// target does not stop the build.
+func Build(files map[string]string, dir string) ([]*Resource, []Error) {
+	b := &builder{files: files, visiting: map[string]bool{}}
+	resources := b.build(dir, "", 0)
+	finish(resources)
+	sortResources(resources)
+	return resources, b.errs
+}
+
+type builder struct {
+	files    map[string]string
+	errs     []Error
+	visiting map[string]bool
+}
+
+func (b *builder) errorf(file string, line int, format string, args ...any) {
+	b.errs = append(b.errs, Error{File: file, Line: line, Message: fmt.Sprintf(format, args...)})
+}
+
+// File returns the path of the kustomization file in dir of files.
+func File(files map[string]string, dir string) (string, bool) {
+	for _, name := range FileNames {
+		p := path.Join(dir, name)
+		if _, ok := files[p]; ok {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+// isDir reports whether any file lies below dir.
+func (b *builder) isDir(dir string) bool {
+	prefix := dir + "/"
+	if dir == "" || dir == "." {
+		return len(b.files) > 0
+	}
+	for name := range b.files {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// build builds the kustomization in dir. from and fromLine name the
+// resources entry that referenced it, for errors.
+func (b *builder) build(dir, from string, fromLine int) []*Resource {
+	file, ok := File(b.files, dir)
+	if !ok {
+		b.errorf(from, fromLine, "directory %q has no kustomization.yaml", displayDir(dir))
+		return nil
+	}
+	if b.visiting[dir] {
+		b.errorf(from, fromLine, "kustomization %q includes itself", displayDir(dir))
+		return nil
+	}
+	b.visiting[dir] = true
+	defer delete(b.visiting, dir)
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(b.files[file]), &doc); err != nil {
+		line, _ := parser.YAMLErrorPosition(b.files[file], err)
+		b.errorf(file, line, "YAML syntax error: %v", err)
+		return nil
+	}
+	var k Kustomization
+	if err := doc.Decode(&k); err != nil {
+		b.errorf(file, 1, "not a kustomization: %v", err)
+		return nil
+	}
+	l := level{builder: b, dir: dir, file: file, k: k, node: &doc}
+
+	for i, entry := range append(append([]string(nil), k.Resources...), k.Bases...) {
+		field, index := "resources", i
+		if i >= len(k.Resources) {
+			field, index = "bases", i-len(k.Resources)
+		}
+		l.resources = append(l.resources, l.resource(entry, l.line(field, index))...)
+	}
+	l.generate("ConfigMap", "configMapGenerator", k.ConfigMapGenerator)
+	l.generate("Secret", "secretGenerator", k.SecretGenerator)
+	l.patch()
+	l.transform()
+	return l.resources
+}
+
+// level is one kustomization being built.
+type level struct {
+	*builder
+	dir       string
+	file      string
+	k         Kustomization
+	node      *yaml.Node
+	resources []*Resource
+}
+
+// line returns the line of item index of a sequence field of the
+// kustomization, or of the field when index is negative or out of range.
+func (l *level) line(field string, index int) int {
+	if len(l.node.Content) == 0 {
+		return 1
+	}
+	root := l.node.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != field {
+			continue
+		}
+		v := root.Content[i+1]
+		if v.Kind == yaml.SequenceNode && index >= 0 && index < len(v.Content) {
+			return v.Content[index].Line
+		}
+		return root.Content[i].Line
+	}
+	return 1
+}
+
+// resource loads a resources entry: a file of manifests or a directory
+// with its own kustomization.
+func (l *level) resource(entry string, line int) []*Resource {
+	if strings.Contains(entry, "://") || strings.HasPrefix(entry, "github.com/") || strings.HasPrefix(entry, "git@") {
+		l.errorf(l.file, line, "remote resource %q is not supported; include it in the archive", entry)
+		return nil
+	}
+	p := path.Join(l.dir, entry)
+	if strings.HasPrefix(p, "../") || p == ".." {
+		l.errorf(l.file, line, "resource %q lies outside the archive", entry)
+		return nil
+	}
+	if _, ok := l.files[p]; ok {
+		return l.load(p)
+	}
+	if l.isDir(p) {
+		return l.build(p, l.file, line)
+	}
+	l.errorf(l.file, line, "resource %q not found", entry)
+	return nil
+}
+
+// load reads the manifests of a file. Empty documents and "List" kinds are
+// skipped and unfolded respectively, as kustomize does.
+func (l *level) load(file string) []*Resource {
+	var out []*Resource
+	for _, d := range parser.SplitYAMLDocuments(l.files[file]) {
+		var doc yaml.Node
+		if err := yaml.Unmarshal([]byte(d.Content), &doc); err != nil {
+			line, _ := parser.YAMLErrorPosition(d.Content, err)
+			l.errorf(file, line+d.StartLine-1, "YAML syntax error: %v", err)
+			continue
+		}
+		if len(doc.Content) == 0 {
+			continue
+		}
+		shiftLines(&doc, d.StartLine-1, 0)
+		var obj map[string]any
+		if err := doc.Decode(&obj); err != nil || obj == nil {
+			l.errorf(file, d.StartLine, "document is not a Kubernetes resource")
+			continue
+		}
+		if kindOf(obj) == "" || nameOf(obj) == "" && !strings.HasSuffix(kindOf(obj), "List") {
+			l.errorf(file, doc.Content[0].Line, "resource has no kind or metadata.name")
+			continue
+		}
+		if strings.HasSuffix(kindOf(obj), "List") {
+			items, _ := obj["items"].([]any)
+			itemNodes := mapValue(doc.Content[0], "items")
+			for i, item := range items {
+				m, ok := item.(map[string]any)
+				if !ok {
+					continue
+				}
+				r := &Resource{Object: m, File: file, Line: doc.Content[0].Line, names: []string{nameOf(m)}}
+				if itemNodes != nil && i < len(itemNodes.Content) {
+					r.Node, r.Line = itemNodes.Content[i], itemNodes.Content[i].Line
+				}
+				out = append(out, r)
+			}
+			continue
+		}
+		out = append(out, &Resource{Object: obj, File: file, Line: doc.Content[0].Line, Node: doc.Content[0], names: []string{nameOf(obj)}})
+	}
+	return out
+}
+
+// Position returns the file, line and column of the value at a JSON pointer
+// such as "/spec/template/spec/containers/0/image": the last patch that sets
+// the value, or else the document the resource was read from, at the closest
+// ancestor present there when a transformer added the value. A JSON 6902
+// operation sets everything under its path, so a value under it is placed
+// at the closest node of the operation's value. Generated resources are
+// placed at their generator entry, with column 0.
+func (r *Resource) Position(pointer string) (string, int, int) {
+	segs := splitPointer(pointer)
+	for i := len(r.patches) - 1; i >= 0; i-- {
+		p := r.patches[i]
+		if !hasPrefix(segs, p.pointer) {
+			continue
+		}
+		if p.moved {
+			return p.file, p.node.Line, p.node.Column
+		}
+		value, _ := valueAt(r.Object, p.pointer)
+		if n, ok := resolve(p.node, value, segs[len(p.pointer):]); ok || len(p.pointer) > 0 {
+			return p.file, n.Line, n.Column
+		}
+	}
+	if r.Node == nil {
+		return r.File, r.Line, 0
+	}
+	n, _ := resolve(r.Node, r.Object, segs)
+	return r.File, n.Line, n.Column
+}
+
+// hasPrefix reports whether segs starts with prefix.
+func hasPrefix(segs, prefix []string) bool {
+	if len(prefix) > len(segs) {
+		return false
+	}
+	for i := range prefix {
+		if segs[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// resolve follows segs from node, the source of obj, and returns the
+// deepest node reached and whether it is the one segs point at. Items of
+// lists with a merge key are matched by that key, so a patch or a source
+// whose items are ordered differently from the output still resolves.
+func resolve(node *yaml.Node, obj any, segs []string) (*yaml.Node, bool) {
+	n := node
+	cur := obj
+	field := ""
+	for _, seg := range segs {
+		var next *yaml.Node
+		switch n.Kind {
+		case yaml.MappingNode:
+			next = mapValue(n, seg)
+			m, _ := cur.(map[string]any)
+			cur = m[seg]
+		case yaml.SequenceNode:
+			list, _ := cur.([]any)
+			i, err := strconv.Atoi(seg)
+			if err != nil || i < 0 || i >= len(list) {
+				return n, false
+			}
+			cur = list[i]
+			if item, ok := cur.(map[string]any); ok && mergeKeys[field] != nil {
+				next = matchingItem(n, item, mergeKeys[field])
+			} else if i < len(n.Content) {
+				next = n.Content[i]
+			}
+		}
+		if next == nil {
+			return n, false
+		}
+		n, field = next, seg
+	}
+	return n, true
+}
+
+// matchingItem returns the item of a sequence node whose merge key has the
+// value item has.
+func matchingItem(seq *yaml.Node, item map[string]any, keys []string) *yaml.Node {
+	for _, key := range keys {
+		v, ok := item[key]
+		if !ok {
+			continue
+		}
+		for _, c := range seq.Content {
+			if kv := mapValue(c, key); kv != nil && kv.Value == fmt.Sprint(v) {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+// splitPointer splits a JSON pointer into its unescaped segments.
+func splitPointer(pointer string) []string {
+	if pointer == "" || pointer == "/" {
+		return nil
+	}
+	segs := strings.Split(strings.TrimPrefix(pointer, "/"), "/")
+	for i, seg := range segs {
+		segs[i] = strings.ReplaceAll(strings.ReplaceAll(seg, "~1", "/"), "~0", "~")
+	}
+	return segs
+}
+
+// Kind returns the resource's kind.
+func (r *Resource) Kind() string { return kindOf(r.Object) }
+
+// Name returns the resource's current name.
+func (r *Resource) Name() string { return nameOf(r.Object) }
+
+// shiftLines adds offset to the line of every node of a tree, and column
+// to every column.
+func shiftLines(n *yaml.Node, offset, column int) {
+	n.Line += offset
+	n.Column += column
+	for _, c := range n.Content {
+		shiftLines(c, offset, column)
+	}
+}
+
+// mapValue returns the value of key in a mapping node, or nil.
+func mapValue(n *yaml.Node, key string) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func displayDir(dir string) string {
+	if dir == "" {
+		return "."
+	}
+	return dir
+}
+
+func kindOf(obj map[string]any) string {
+	s, _ := obj["kind"].(string)
+	return s
+}
+
+func metadata(obj map[string]any) map[string]any {
+	m, ok := obj["metadata"].(map[string]any)
+	if !ok {
+		m = map[string]any{}
+		obj["metadata"] = m
+	}
+	return m
+}
+
+func nameOf(obj map[string]any) string {
+	m, _ := obj["metadata"].(map[string]any)
+	s, _ := m["name"].(string)
+	return s
+}
+
+func namespaceOf(obj map[string]any) string {
+	m, _ := obj["metadata"].(map[string]any)
+	s, _ := m["namespace"].(string)
+	return s
+}
+
+// groupVersion splits an object's apiVersion, "apps/v1" or "v1".
+func groupVersion(obj map[string]any) (string, string) {
+	av, _ := obj["apiVersion"].(string)
+	if i := strings.LastIndexByte(av, '/'); i >= 0 {
+		return av[:i], av[i+1:]
+	}
+	return "", av
+}
+
+// sortedKeys returns the keys of m in order, for deterministic output.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
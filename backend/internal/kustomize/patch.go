@@ -0,0 +1,648 @@
+package kustomize
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"devformat/backend/internal/parser"
+)
+
+// mergeKeys are the keys that identify the items of the lists strategic
+// merge patches merge rather than replace, by field name. For "ports" the
+// first key present in the patch item is used, since container ports and
+// service ports differ.
+var mergeKeys = map[string][]string{
+	"containers":                {"name"},
+	"initContainers":            {"name"},
+	"ephemeralContainers":       {"name"},
+	"env":                       {"name"},
+	"volumes":                   {"name"},
+	"volumeMounts":              {"mountPath"},
+	"volumeDevices":             {"devicePath"},
+	"ports":                     {"containerPort", "port"},
+	"imagePullSecrets":          {"name"},
+	"hostAliases":               {"ip"},
+	"topologySpreadConstraints": {"topologyKey"},
+	"conditions":                {"type"},
+}
+
+// patchText is the content of a patch with where it was read from: a file,
+// or a block scalar in the kustomization, whose lines and columns are
+// shifted onto the kustomization file.
+type patchText struct {
+	content string
+	file    string
+	line    int
+	column  int
+}
+
+// patch applies the kustomization's patches to its resources, strategic
+// merge patches first, as kustomize does.
+func (l *level) patch() {
+	for i, entry := range l.k.PatchesStrategicMerge {
+		line := l.line("patchesStrategicMerge", i)
+		text, ok := l.patchText(Patch{Path: entry}, l.item("patchesStrategicMerge", i), line)
+		if !ok {
+			continue
+		}
+		l.strategicMerge(text, nil, line)
+	}
+	for i, p := range l.k.PatchesJSON6902 {
+		line := l.line("patchesJson6902", i)
+		if p.Target == nil {
+			l.errorf(l.file, line, "JSON 6902 patch has no target")
+			continue
+		}
+		if text, ok := l.patchText(p, mapValue(l.item("patchesJson6902", i), "patch"), line); ok {
+			l.jsonPatch(text, p.Target, line)
+		}
+	}
+	for i, p := range l.k.Patches {
+		line := l.line("patches", i)
+		text, ok := l.patchText(p, mapValue(l.item("patches", i), "patch"), line)
+		if !ok {
+			continue
+		}
+		// a patch that is a list of operations is a JSON 6902 patch
+		var probe any
+		if err := yaml.Unmarshal([]byte(text.content), &probe); err == nil {
+			if _, isList := probe.([]any); isList {
+				if p.Target == nil {
+					l.errorf(l.file, line, "JSON 6902 patch has no target")
+					continue
+				}
+				l.jsonPatch(text, p.Target, line)
+				continue
+			}
+		}
+		l.strategicMerge(text, p.Target, line)
+	}
+}
+
+// item returns item index of a sequence field of the kustomization.
+func (l *level) item(field string, index int) *yaml.Node {
+	if len(l.node.Content) == 0 {
+		return nil
+	}
+	v := mapValue(l.node.Content[0], field)
+	if v == nil || v.Kind != yaml.SequenceNode || index >= len(v.Content) {
+		return nil
+	}
+	return v.Content[index]
+}
+
+// patchText reads a patch from its file or inline text. patchesStrategicMerge
+// entries are file paths unless they hold YAML, so p.Path is tried as
+// inline YAML when no such file exists; inline is the node holding the
+// inline text.
+func (l *level) patchText(p Patch, inline *yaml.Node, line int) (patchText, bool) {
+	if p.Path != "" {
+		file := path.Join(l.dir, p.Path)
+		if content, ok := l.files[file]; ok {
+			return patchText{content: content, file: file}, true
+		}
+		if !strings.ContainsAny(p.Path, ":\n") {
+			l.errorf(l.file, line, "patch file %q not found", p.Path)
+			return patchText{}, false
+		}
+		p.Patch = p.Path
+	}
+	if strings.TrimSpace(p.Patch) == "" {
+		l.errorf(l.file, line, "patch has neither path nor patch")
+		return patchText{}, false
+	}
+	text := patchText{content: p.Patch, file: l.file}
+	if inline != nil && (inline.Style == yaml.LiteralStyle || inline.Style == yaml.FoldedStyle) {
+		// the text of a block scalar starts on the line after its indicator
+		text.line = inline.Line
+		lines := strings.Split(l.files[l.file], "\n")
+		if inline.Line < len(lines) {
+			next := lines[inline.Line]
+			text.column = len(next) - len(strings.TrimLeft(next, " "))
+		}
+	}
+	return text, true
+}
+
+// strategicMerge applies the documents of a strategic merge patch. Without
+// a target each document patches the resource with its kind and name.
+func (l *level) strategicMerge(text patchText, target *Selector, line int) {
+	for _, d := range parser.SplitYAMLDocuments(text.content) {
+		var doc yaml.Node
+		if err := yaml.Unmarshal([]byte(d.Content), &doc); err != nil {
+			l.errorf(l.file, line, "patch is not valid YAML: %v", err)
+			continue
+		}
+		if len(doc.Content) == 0 {
+			continue
+		}
+		var patch map[string]any
+		if err := doc.Decode(&patch); err != nil || patch == nil {
+			l.errorf(l.file, line, "strategic merge patch must be a mapping")
+			continue
+		}
+		src := patchSource{file: text.file, node: doc.Content[0]}
+		if text.line > 0 || text.file != l.file {
+			shiftLines(src.node, text.line+d.StartLine-1, text.column)
+		} else {
+			// inline text in a flow scalar has no useful position
+			src.node = nil
+		}
+
+		targets := l.patchTargets(patch, target)
+		if len(targets) == 0 {
+			l.errorf(l.file, line, "no resource matches the patch of %s", describeTarget(patch, target))
+			continue
+		}
+		if target != nil {
+			// with a target the patch's own name does not select or rename
+			if m, ok := patch["metadata"].(map[string]any); ok {
+				delete(m, "name")
+			}
+		}
+		for _, r := range targets {
+			if dir, _ := patch["$patch"].(string); dir == "delete" {
+				l.remove(r)
+				continue
+			}
+			mergeMap(r.Object, deepCopy(patch).(map[string]any))
+			if src.node != nil {
+				r.patches = append(r.patches, src)
+			}
+		}
+	}
+}
+
+// patchTargets returns the resources a patch applies to.
+func (l *level) patchTargets(patch map[string]any, target *Selector) []*Resource {
+	if target == nil {
+		group, _ := groupVersion(patch)
+		target = &Selector{Group: group, Kind: kindOf(patch), Name: regexp.QuoteMeta(nameOf(patch)), Namespace: regexp.QuoteMeta(namespaceOf(patch))}
+		if target.Kind == "" || target.Name == "" {
+			return nil
+		}
+	}
+	var out []*Resource
+	for _, r := range l.resources {
+		if target.matches(r) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func (l *level) remove(r *Resource) {
+	for i, x := range l.resources {
+		if x == r {
+			l.resources = append(l.resources[:i], l.resources[i+1:]...)
+			return
+		}
+	}
+}
+
+func describeTarget(patch map[string]any, target *Selector) string {
+	if target != nil {
+		parts := []string{}
+		for _, kv := range [][2]string{{"group", target.Group}, {"version", target.Version}, {"kind", target.Kind}, {"name", target.Name}, {"namespace", target.Namespace}, {"labelSelector", target.LabelSelector}} {
+			if kv[1] != "" {
+				parts = append(parts, kv[0]+"="+kv[1])
+			}
+		}
+		return "target " + strings.Join(parts, ", ")
+	}
+	return kindOf(patch) + " " + strconv.Quote(nameOf(patch))
+}
+
+// matches reports whether the selector picks r. A name matches any name r
+// had, so patches in an overlay may use the names of the base.
+func (s *Selector) matches(r *Resource) bool {
+	group, version := groupVersion(r.Object)
+	if s.Group != "" && s.Group != group || s.Version != "" && s.Version != version || s.Kind != "" && s.Kind != r.Kind() {
+		return false
+	}
+	if s.Name != "" {
+		found := false
+		for _, name := range r.names {
+			if matchWhole(s.Name, name) {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if s.Namespace != "" && !matchWhole(s.Namespace, namespaceOf(r.Object)) {
+		return false
+	}
+	if s.LabelSelector != "" {
+		labels, _ := metadata(r.Object)["labels"].(map[string]any)
+		for _, term := range strings.Split(s.LabelSelector, ",") {
+			term = strings.TrimSpace(term)
+			switch {
+			case strings.Contains(term, "!="):
+				k, v, _ := strings.Cut(term, "!=")
+				if fmt.Sprint(labels[strings.TrimSpace(k)]) == strings.TrimSpace(v) {
+					return false
+				}
+			case strings.Contains(term, "="):
+				k, v, _ := strings.Cut(strings.Replace(term, "==", "=", 1), "=")
+				if got, ok := labels[strings.TrimSpace(k)]; !ok || fmt.Sprint(got) != strings.TrimSpace(v) {
+					return false
+				}
+			case term != "":
+				if _, ok := labels[term]; !ok {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// matchWhole matches s against a regular expression anchored at both ends,
+// or compares it literally when pattern is not a valid expression.
+func matchWhole(pattern, s string) bool {
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return pattern == s
+	}
+	return re.MatchString(s)
+}
+
+// mergeMap applies a strategic merge patch to dst: maps merge recursively,
+// null deletes a key, lists with a merge key merge by it and other lists
+// are replaced. The "$patch: replace" and "$patch: delete" directives are
+// honoured on maps and list items.
+func mergeMap(dst, patch map[string]any) {
+	if dir, _ := patch["$patch"].(string); dir == "replace" {
+		for k := range dst {
+			delete(dst, k)
+		}
+	}
+	for k, pv := range patch {
+		if strings.HasPrefix(k, "$") {
+			continue
+		}
+		switch p := pv.(type) {
+		case nil:
+			delete(dst, k)
+		case map[string]any:
+			dir, _ := p["$patch"].(string)
+			if dir == "delete" {
+				delete(dst, k)
+				continue
+			}
+			if dm, ok := dst[k].(map[string]any); ok && dir != "replace" {
+				mergeMap(dm, p)
+				continue
+			}
+			dst[k] = stripDirectives(p)
+		case []any:
+			if dl, ok := dst[k].([]any); ok && mergeKeys[k] != nil {
+				dst[k] = mergeList(dl, p, mergeKeys[k])
+				continue
+			}
+			dst[k] = stripDirectives(p)
+		default:
+			dst[k] = pv
+		}
+	}
+}
+
+// mergeList merges the items of patch into dst by the first of keys each
+// patch item has. An item {"$patch": "replace"} replaces the whole list.
+func mergeList(dst, patch []any, keys []string) []any {
+	for _, item := range patch {
+		if m, ok := item.(map[string]any); ok && len(m) == 1 && m["$patch"] == "replace" {
+			var rest []any
+			for _, x := range patch {
+				if x != item {
+					rest = append(rest, stripDirectives(x))
+				}
+			}
+			return rest
+		}
+	}
+	out := append([]any(nil), dst...)
+	for _, item := range patch {
+		pm, ok := item.(map[string]any)
+		if !ok {
+			// lists of scalars are replaced
+			return stripDirectives(patch).([]any)
+		}
+		key := ""
+		for _, k := range keys {
+			if _, ok := pm[k]; ok {
+				key = k
+				break
+			}
+		}
+		idx := -1
+		if key != "" {
+			for i, x := range out {
+				if xm, ok := x.(map[string]any); ok && fmt.Sprint(xm[key]) == fmt.Sprint(pm[key]) {
+					idx = i
+					break
+				}
+			}
+		}
+		switch {
+		case pm["$patch"] == "delete":
+			if idx >= 0 {
+				out = append(out[:idx], out[idx+1:]...)
+			}
+		case idx >= 0:
+			mergeMap(out[idx].(map[string]any), pm)
+		default:
+			out = append(out, stripDirectives(pm))
+		}
+	}
+	return out
+}
+
+// stripDirectives returns a copy of v without "$patch" and similar keys.
+func stripDirectives(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(t))
+		for k, x := range t {
+			if !strings.HasPrefix(k, "$") {
+				out[k] = stripDirectives(x)
+			}
+		}
+		return out
+	case []any:
+		out := make([]any, len(t))
+		for i, x := range t {
+			out[i] = stripDirectives(x)
+		}
+		return out
+	}
+	return v
+}
+
+// deepCopy copies the maps and lists of a decoded YAML value.
+func deepCopy(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(t))
+		for k, x := range t {
+			out[k] = deepCopy(x)
+		}
+		return out
+	case []any:
+		out := make([]any, len(t))
+		for i, x := range t {
+			out[i] = deepCopy(x)
+		}
+		return out
+	}
+	return v
+}
+
+// operation is a JSON 6902 patch operation.
+type operation struct {
+	Op    string `yaml:"op"`
+	Path  string `yaml:"path"`
+	From  string `yaml:"from"`
+	Value any    `yaml:"value"`
+}
+
+// jsonPatch applies a JSON 6902 patch, written as YAML or JSON, to every
+// resource target selects. The operations that set a value are recorded on
+// the resource, so that problems in the value are reported in the patch.
+func (l *level) jsonPatch(text patchText, target *Selector, line int) {
+	var ops []operation
+	if err := yaml.Unmarshal([]byte(text.content), &ops); err != nil {
+		l.errorf(l.file, line, "JSON 6902 patch must be a list of operations: %v", err)
+		return
+	}
+	targets := l.patchTargets(nil, target)
+	if len(targets) == 0 {
+		l.errorf(l.file, line, "no resource matches the patch of %s", describeTarget(nil, target))
+		return
+	}
+	nodes := l.operationNodes(text)
+	for _, r := range targets {
+		for i, op := range ops {
+			if err := applyOperation(r.Object, op); err != nil {
+				l.errorf(l.file, line, "operation %d (%s %s) of the patch fails on %s %q: %v", i+1, op.Op, op.Path, r.Kind(), r.Name(), err)
+				break
+			}
+			if i >= len(nodes) {
+				continue
+			}
+			src := patchSource{file: text.file, node: mapValue(nodes[i], "value"), pointer: appliedPath(r.Object, splitPointer(op.Path))}
+			switch op.Op {
+			case "add", "replace":
+			case "move", "copy":
+				src.node, src.moved = nodes[i], true
+			default:
+				continue
+			}
+			if src.node != nil {
+				r.patches = append(r.patches, src)
+			}
+		}
+	}
+}
+
+// operationNodes returns the nodes of a JSON 6902 patch's operations with
+// lines and columns in the patch's file, or nil when the patch is inline
+// text without a useful position.
+func (l *level) operationNodes(text patchText) []*yaml.Node {
+	if text.line == 0 && text.file == l.file {
+		return nil
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(text.content), &doc); err != nil || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.SequenceNode {
+		return nil
+	}
+	shiftLines(doc.Content[0], text.line, text.column)
+	return doc.Content[0].Content
+}
+
+// appliedPath returns the path an operation was applied at, with the
+// index the value got for a path ending in "-", which appends to a list.
+func appliedPath(obj map[string]any, segs []string) []string {
+	last := len(segs) - 1
+	if last < 0 || segs[last] != "-" {
+		return segs
+	}
+	if list, err := valueAt(obj, segs[:last]); err == nil {
+		if l, ok := list.([]any); ok {
+			segs[last] = strconv.Itoa(len(l) - 1)
+		}
+	}
+	return segs
+}
+
+var errNoPath = errors.New("path does not exist")
+
+// applyOperation applies one JSON 6902 operation to obj.
+func applyOperation(obj map[string]any, op operation) error {
+	segs := splitPointer(op.Path)
+	if len(segs) == 0 {
+		return errors.New("cannot patch the whole document")
+	}
+	switch op.Op {
+	case "add":
+		return at(obj, segs, func(c any, key string) (any, error) { return add(c, key, deepCopy(op.Value)) })
+	case "remove":
+		return at(obj, segs, remove)
+	case "replace":
+		return at(obj, segs, func(c any, key string) (any, error) {
+			if _, err := get(c, key); err != nil {
+				return nil, err
+			}
+			return set(c, key, deepCopy(op.Value))
+		})
+	case "test":
+		return at(obj, segs, func(c any, key string) (any, error) {
+			v, err := get(c, key)
+			if err != nil {
+				return nil, err
+			}
+			if !reflect.DeepEqual(v, op.Value) {
+				return nil, fmt.Errorf("value is %v, not %v", v, op.Value)
+			}
+			return c, nil
+		})
+	case "move", "copy":
+		from := splitPointer(op.From)
+		if len(from) == 0 {
+			return errors.New("operation needs a from path")
+		}
+		var value any
+		err := at(obj, from, func(c any, key string) (any, error) {
+			v, err := get(c, key)
+			if err != nil {
+				return nil, err
+			}
+			value = deepCopy(v)
+			if op.Op == "move" {
+				return remove(c, key)
+			}
+			return c, nil
+		})
+		if err != nil {
+			return fmt.Errorf("from: %w", err)
+		}
+		return at(obj, segs, func(c any, key string) (any, error) { return add(c, key, value) })
+	}
+	return fmt.Errorf("unknown operation %q", op.Op)
+}
+
+// at applies fn to the map or list holding the last segment of segs and
+// stores the container fn returns, which differs for lists that grew or
+// shrank.
+func at(node any, segs []string, fn func(container any, key string) (any, error)) error {
+	_, err := atValue(node, segs, fn)
+	return err
+}
+
+func atValue(node any, segs []string, fn func(container any, key string) (any, error)) (any, error) {
+	if len(segs) == 1 {
+		return fn(node, segs[0])
+	}
+	child, err := get(node, segs[0])
+	if err != nil {
+		return nil, err
+	}
+	updated, err := atValue(child, segs[1:], fn)
+	if err != nil {
+		return nil, err
+	}
+	return set(node, segs[0], updated)
+}
+
+// valueAt returns the value at segs in v.
+func valueAt(v any, segs []string) (any, error) {
+	for _, seg := range segs {
+		var err error
+		if v, err = get(v, seg); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+func get(c any, key string) (any, error) {
+	switch t := c.(type) {
+	case map[string]any:
+		v, ok := t[key]
+		if !ok {
+			return nil, errNoPath
+		}
+		return v, nil
+	case []any:
+		i, err := strconv.Atoi(key)
+		if err != nil || i < 0 || i >= len(t) {
+			return nil, errNoPath
+		}
+		return t[i], nil
+	}
+	return nil, errNoPath
+}
+
+func set(c any, key string, v any) (any, error) {
+	switch t := c.(type) {
+	case map[string]any:
+		t[key] = v
+		return t, nil
+	case []any:
+		i, err := strconv.Atoi(key)
+		if err != nil || i < 0 || i >= len(t) {
+			return nil, errNoPath
+		}
+		t[i] = v
+		return t, nil
+	}
+	return nil, errNoPath
+}
+
+func add(c any, key string, v any) (any, error) {
+	switch t := c.(type) {
+	case map[string]any:
+		t[key] = v
+		return t, nil
+	case []any:
+		if key == "-" {
+			return append(t, v), nil
+		}
+		i, err := strconv.Atoi(key)
+		if err != nil || i < 0 || i > len(t) {
+			return nil, errNoPath
+		}
+		t = append(t, nil)
+		copy(t[i+1:], t[i:])
+		t[i] = v
+		return t, nil
+	}
+	return nil, errNoPath
+}
+
+func remove(c any, key string) (any, error) {
+	switch t := c.(type) {
+	case map[string]any:
+		if _, ok := t[key]; !ok {
+			return nil, errNoPath
+		}
+		delete(t, key)
+		return t, nil
+	case []any:
+		i, err := strconv.Atoi(key)
+		if err != nil || i < 0 || i >= len(t) {
+			return nil, errNoPath
+		}
+		return append(t[:i], t[i+1:]...), nil
+	}
+	return nil, errNoPath
+}
@@ -0,0 +1,287 @@
+package kustomize
+
+import (
+	"sort"
+	"strings"
+)
+
+// clusterScoped are the built-in kinds the namespace transformer leaves
+// alone.
+var clusterScoped = map[string]bool{
+	"Namespace":                      true,
+	"Node":                           true,
+	"PersistentVolume":               true,
+	"ClusterRole":                    true,
+	"ClusterRoleBinding":             true,
+	"CustomResourceDefinition":       true,
+	"StorageClass":                   true,
+	"PriorityClass":                  true,
+	"IngressClass":                   true,
+	"RuntimeClass":                   true,
+	"CSIDriver":                      true,
+	"APIService":                     true,
+	"ValidatingWebhookConfiguration": true,
+	"MutatingWebhookConfiguration":   true,
+}
+
+// kindOrder is the position of kinds in the output of "kustomize build",
+// whose legacy order puts the kinds others depend on first and webhook
+// configurations last. Other kinds come in between, at 0.
+var kindOrder = map[string]int{
+	"Namespace":                      -22,
+	"ResourceQuota":                  -21,
+	"StorageClass":                   -20,
+	"CustomResourceDefinition":       -19,
+	"ServiceAccount":                 -18,
+	"PodSecurityPolicy":              -17,
+	"Role":                           -16,
+	"ClusterRole":                    -15,
+	"RoleBinding":                    -14,
+	"ClusterRoleBinding":             -13,
+	"ConfigMap":                      -12,
+	"Secret":                         -11,
+	"Endpoints":                      -10,
+	"Service":                        -9,
+	"LimitRange":                     -8,
+	"PriorityClass":                  -7,
+	"PersistentVolume":               -6,
+	"PersistentVolumeClaim":          -5,
+	"Deployment":                     -4,
+	"StatefulSet":                    -3,
+	"CronJob":                        -2,
+	"PodDisruptionBudget":            -1,
+	"MutatingWebhookConfiguration":   1,
+	"ValidatingWebhookConfiguration": 2,
+}
+
+// labelPaths are the selector and pod template fields commonLabels extends
+// besides metadata.labels, by kind. Fields marked create are added when
+// missing.
+var labelPaths = map[string][]fieldPath{
+	"Deployment":            {{"spec/selector/matchLabels", true}, {"spec/template/metadata/labels", true}},
+	"ReplicaSet":            {{"spec/selector/matchLabels", true}, {"spec/template/metadata/labels", true}},
+	"DaemonSet":             {{"spec/selector/matchLabels", true}, {"spec/template/metadata/labels", true}},
+	"StatefulSet":           {{"spec/selector/matchLabels", true}, {"spec/template/metadata/labels", true}},
+	"ReplicationController": {{"spec/selector", true}, {"spec/template/metadata/labels", true}},
+	"Service":               {{"spec/selector", true}},
+	"Job":                   {{"spec/selector/matchLabels", false}, {"spec/template/metadata/labels", true}},
+	"CronJob":               {{"spec/jobTemplate/metadata/labels", true}, {"spec/jobTemplate/spec/template/metadata/labels", true}},
+	"PodDisruptionBudget":   {{"spec/selector/matchLabels", false}},
+	"NetworkPolicy":         {{"spec/podSelector/matchLabels", false}},
+}
+
+// annotationPaths are the pod template fields commonAnnotations extends
+// besides metadata.annotations, by kind.
+var annotationPaths = map[string][]fieldPath{
+	"Deployment":            {{"spec/template/metadata/annotations", true}},
+	"ReplicaSet":            {{"spec/template/metadata/annotations", true}},
+	"DaemonSet":             {{"spec/template/metadata/annotations", true}},
+	"StatefulSet":           {{"spec/template/metadata/annotations", true}},
+	"ReplicationController": {{"spec/template/metadata/annotations", true}},
+	"Job":                   {{"spec/template/metadata/annotations", true}},
+	"CronJob":               {{"spec/jobTemplate/metadata/annotations", true}, {"spec/jobTemplate/spec/template/metadata/annotations", true}},
+}
+
+type fieldPath struct {
+	path   string
+	create bool
+}
+
+// transform applies the kustomization's namespace, name prefix and suffix,
+// common labels and annotations to every resource built so far, then
+// updates references to the resources it renamed.
+func (l *level) transform() {
+	renames := map[string]map[string]string{}
+	for _, r := range l.resources {
+		kind := r.Kind()
+		before := r.Name()
+		if l.k.Namespace != "" && !clusterScoped[kind] {
+			metadata(r.Object)["namespace"] = l.k.Namespace
+		}
+		if (l.k.NamePrefix != "" || l.k.NameSuffix != "") && kind != "CustomResourceDefinition" {
+			rename(r, l.k.NamePrefix+before+l.k.NameSuffix, renames)
+		}
+		if len(l.k.CommonLabels) > 0 {
+			setStrings(metadata(r.Object), "labels", l.k.CommonLabels)
+			for _, f := range labelPaths[kind] {
+				setPath(r.Object, f, l.k.CommonLabels)
+			}
+		}
+		if len(l.k.CommonAnnotations) > 0 {
+			setStrings(metadata(r.Object), "annotations", l.k.CommonAnnotations)
+			for _, f := range annotationPaths[kind] {
+				setPath(r.Object, f, l.k.CommonAnnotations)
+			}
+		}
+	}
+	fixReferences(l.resources, renames)
+}
+
+// finish appends the content hash to the names of generated resources and
+// encodes the data of generated Secrets, once the whole tree is built.
+func finish(resources []*Resource) {
+	renames := map[string]map[string]string{}
+	for _, r := range resources {
+		if r.hash {
+			rename(r, r.Name()+"-"+contentHash(r), renames)
+		}
+		encodeSecret(r)
+	}
+	fixReferences(resources, renames)
+}
+
+// sortResources puts resources in kustomize's legacy order: by kindOrder,
+// then by group, version and kind, then by namespace and name.
+func sortResources(resources []*Resource) {
+	key := func(r *Resource) string {
+		group, version := groupVersion(r.Object)
+		return group + "_" + version + "_" + r.Kind()
+	}
+	sort.SliceStable(resources, func(i, j int) bool {
+		a, b := resources[i], resources[j]
+		if oa, ob := kindOrder[a.Kind()], kindOrder[b.Kind()]; oa != ob {
+			return oa < ob
+		}
+		if ka, kb := key(a), key(b); ka != kb {
+			return ka < kb
+		}
+		if na, nb := namespaceOf(a.Object), namespaceOf(b.Object); na != nb {
+			return na < nb
+		}
+		return a.Name() < b.Name()
+	})
+}
+
+// rename gives r a new name and records it in renames by kind.
+func rename(r *Resource, name string, renames map[string]map[string]string) {
+	before := r.Name()
+	if name == before {
+		return
+	}
+	metadata(r.Object)["name"] = name
+	r.names = append(r.names, name)
+	if renames[r.Kind()] == nil {
+		renames[r.Kind()] = map[string]string{}
+	}
+	renames[r.Kind()][before] = name
+}
+
+// setStrings adds values to the string map under key of m.
+func setStrings(m map[string]any, key string, values map[string]string) {
+	if len(values) == 0 {
+		return
+	}
+	target, ok := m[key].(map[string]any)
+	if !ok {
+		target = map[string]any{}
+		m[key] = target
+	}
+	for _, k := range sortedKeys(values) {
+		target[k] = values[k]
+	}
+}
+
+// setPath adds values to the map at a slash-separated path of obj,
+// creating the path when f.create is set.
+func setPath(obj map[string]any, f fieldPath, values map[string]string) {
+	segs := strings.Split(f.path, "/")
+	cur := obj
+	for _, seg := range segs[:len(segs)-1] {
+		next, ok := cur[seg].(map[string]any)
+		if !ok {
+			if !f.create {
+				return
+			}
+			next = map[string]any{}
+			cur[seg] = next
+		}
+		cur = next
+	}
+	if _, ok := cur[segs[len(segs)-1]]; !ok && !f.create {
+		return
+	}
+	setStrings(cur, segs[len(segs)-1], values)
+}
+
+// fixReferences updates the references of resources to renamed
+// ConfigMaps, Secrets, ServiceAccounts, PersistentVolumeClaims, Services
+// and roles. renames maps kinds to old and new names.
+func fixReferences(resources []*Resource, renames map[string]map[string]string) {
+	if len(renames) == 0 {
+		return
+	}
+	for _, r := range resources {
+		for k, v := range r.Object {
+			if k != "metadata" {
+				fixValue(k, v, renames)
+			}
+		}
+	}
+}
+
+// referenceKeys maps the keys of reference objects to the kind and the
+// name fields they refer by.
+var referenceKeys = map[string]struct {
+	kind   string
+	fields []string
+}{
+	"configMap":             {"ConfigMap", []string{"name"}},
+	"configMapRef":          {"ConfigMap", []string{"name"}},
+	"configMapKeyRef":       {"ConfigMap", []string{"name"}},
+	"secret":                {"Secret", []string{"secretName", "name"}},
+	"secretRef":             {"Secret", []string{"name"}},
+	"secretKeyRef":          {"Secret", []string{"name"}},
+	"persistentVolumeClaim": {"PersistentVolumeClaim", []string{"claimName"}},
+	"service":               {"Service", []string{"name"}},
+}
+
+// stringReferences maps keys whose string value names a resource to its
+// kind.
+var stringReferences = map[string]string{
+	"serviceAccountName": "ServiceAccount",
+	"serviceName":        "Service",
+}
+
+func fixValue(key string, v any, renames map[string]map[string]string) {
+	switch t := v.(type) {
+	case map[string]any:
+		if ref, ok := referenceKeys[key]; ok {
+			for _, f := range ref.fields {
+				renameField(t, f, renames[ref.kind])
+			}
+		}
+		if key == "roleRef" {
+			kind, _ := t["kind"].(string)
+			renameField(t, "name", renames[kind])
+		}
+		for k, c := range t {
+			if kind, ok := stringReferences[k]; ok {
+				renameField(t, k, renames[kind])
+				continue
+			}
+			fixValue(k, c, renames)
+		}
+	case []any:
+		for _, item := range t {
+			if m, ok := item.(map[string]any); ok {
+				switch key {
+				case "imagePullSecrets":
+					renameField(m, "name", renames["Secret"])
+				case "subjects":
+					if m["kind"] == "ServiceAccount" {
+						renameField(m, "name", renames["ServiceAccount"])
+					}
+				}
+			}
+			fixValue(key, item, renames)
+		}
+	}
+}
+
+func renameField(m map[string]any, field string, names map[string]string) {
+	if s, ok := m[field].(string); ok {
+		if to, ok := names[s]; ok {
+			m[field] = to
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Severity levels used by rules; they match types.ValidationError.Severity.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+// Document is the input handed to every rule: the parsed node tree together
+// with the raw source lines, since some rules (line length, trailing spaces)
+// are purely textual.
+type Document struct {
+	// Node is the DocumentNode produced by yaml.v3 for YAML or JSON content.
+	Node *yaml.Node
+	// Lines holds the raw document text split on "\n".
+	Lines []string
+	// Format is "yaml" or "json".
+	Format string
+	// Explicit reports whether the document was introduced by a "---" marker.
+	Explicit bool
+}
+
+// Problem is a single rule violation. Lines and columns are 1-based and
+// relative to the document. RuleID and Severity are filled in by Run.
+type Problem struct {
+	RuleID   string
+	Line     int
+	Column   int
+	Message  string
+	Severity string
+}
+
+// Options holds rule settings such as line-length's "max".
+type Options map[string]any
+
+// Int returns the integer option key, or def when it is unset or not a number.
+func (o Options) Int(key string, def int) int {
+	switch v := o[key].(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	}
+	return def
+}
+
+// Rule is a named check over a document. Options carries the defaults a
+// configuration file may override.
+type Rule struct {
+	ID          string
+	Severity    string
+	Description string
+	Options     Options
+	Check       func(doc *Document, opts Options) []Problem
+}
+
+var (
+	mu       sync.RWMutex
+	registry = map[string]Rule{}
+)
+
+// Register adds a rule to the registry. Registering an ID twice replaces the
+// earlier rule, which lets callers override built-ins.
+func Register(r Rule) {
+	mu.Lock()
+	defer mu.Unlock()
+	registry[r.ID] = r
+}
+
+// Lookup returns the rule registered under id.
+func Lookup(id string) (Rule, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	r, ok := registry[id]
+	return r, ok
+}
+
+// Rules returns every registered rule sorted by ID.
+func Rules() []Rule {
+	mu.RLock()
+	defer mu.RUnlock()
+	out := make([]Rule, 0, len(registry))
+	for _, r := range registry {
+		out = append(out, r)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// Select resolves requested rule IDs into rules. "all" selects every
+// registered rule; unknown IDs are an error.
+func Select(ids []string) ([]Rule, error) {
+	var out []Rule
+	seen := map[string]bool{}
+	var unknown []string
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		if id == "all" {
+			return Rules(), nil
+		}
+		seen[id] = true
+		r, ok := Lookup(id)
+		if !ok {
+			unknown = append(unknown, id)
+			continue
+		}
+		out = append(out, r)
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown lint rule(s): %s", strings.Join(unknown, ", "))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+// Run applies rules to a document and returns their problems ordered by
+// position. Each problem carries the rule's ID and severity.
+func Run(doc *Document, rules []Rule) []Problem {
+	var out []Problem
+	for _, r := range rules {
+		for _, p := range r.Check(doc, r.Options) {
+			p.RuleID = r.ID
+			p.Severity = r.Severity
+			out = append(out, p)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Line != out[j].Line {
+			return out[i].Line < out[j].Line
+		}
+		return out[i].Column < out[j].Column
+	})
+	return out
+}
@@ -0,0 +1,160 @@
+package lint
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+
+	"devformat/backend/internal/dupkeys"
+)
+
+// Built-in rules follow yamllint's rule IDs and default behaviour.
+func init() {
+	Register(Rule{
+		ID:          "key-duplicates",
+		Severity:    SeverityError,
+		Description: "Forbid duplicated keys in mappings.",
+		Check:       checkKeyDuplicates,
+	})
+	Register(Rule{
+		ID:          "truthy",
+		Severity:    SeverityWarning,
+		Description: "Forbid YAML 1.1 truthy values such as yes/no/on/off; use true or false.",
+		Check:       checkTruthy,
+	})
+	Register(Rule{
+		ID:          "key-ordering",
+		Severity:    SeverityWarning,
+		Description: "Require keys in mappings to be sorted alphabetically.",
+		Check:       checkKeyOrdering,
+	})
+	Register(Rule{
+		ID:          "line-length",
+		Severity:    SeverityError,
+		Description: "Limit line length (option \"max\", default 80); single unbreakable words are allowed.",
+		Options:     Options{"max": DefaultMaxLineLength},
+		Check:       checkLineLength,
+	})
+	Register(Rule{
+		ID:          "trailing-spaces",
+		Severity:    SeverityError,
+		Description: "Forbid trailing spaces at the end of lines.",
+		Check:       checkTrailingSpaces,
+	})
+	Register(Rule{
+		ID:          "document-start",
+		Severity:    SeverityWarning,
+		Description: "Require an explicit \"---\" document start marker.",
+		Check:       checkDocumentStart,
+	})
+}
+
+// DefaultMaxLineLength is the default line-length limit, matching yamllint.
+const DefaultMaxLineLength = 80
+
+// truthyValues are plain scalars YAML 1.1 treats as booleans.
+var truthyValues = map[string]bool{
+	"YES": true, "Yes": true, "yes": true, "NO": true, "No": true, "no": true,
+	"TRUE": true, "True": true, "FALSE": true, "False": true,
+	"ON": true, "On": true, "on": true, "OFF": true, "Off": true, "off": true,
+}
+
+// walk visits every node of the tree once. Aliases are not followed so that
+// anchored content is reported only where it is defined.
+func walk(n *yaml.Node, visit func(n *yaml.Node)) {
+	if n == nil {
+		return
+	}
+	visit(n)
+	for _, c := range n.Content {
+		walk(c, visit)
+	}
+}
+
+func checkKeyDuplicates(doc *Document, _ Options) []Problem {
+	var out []Problem
+	for _, d := range dupkeys.FindYAML(doc.Node) {
+		out = append(out, Problem{Line: d.Second.Line, Column: d.Second.Column, Message: fmt.Sprintf("duplication of key %q in mapping (first defined at line %d, column %d)", d.Key, d.First.Line, d.First.Column)})
+	}
+	return out
+}
+
+func checkTruthy(doc *Document, _ Options) []Problem {
+	var out []Problem
+	walk(doc.Node, func(n *yaml.Node) {
+		if n.Kind == yaml.ScalarNode && n.Style == 0 && truthyValues[n.Value] {
+			out = append(out, Problem{Line: n.Line, Column: n.Column, Message: fmt.Sprintf("truthy value %q should be one of [false, true]", n.Value)})
+		}
+	})
+	return out
+}
+
+func checkKeyOrdering(doc *Document, _ Options) []Problem {
+	var out []Problem
+	walk(doc.Node, func(n *yaml.Node) {
+		if n.Kind != yaml.MappingNode {
+			return
+		}
+		prev := ""
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k := n.Content[i]
+			if k.Kind != yaml.ScalarNode || k.ShortTag() == "!!merge" {
+				continue
+			}
+			if prev != "" && k.Value < prev {
+				out = append(out, Problem{Line: k.Line, Column: k.Column, Message: fmt.Sprintf("wrong ordering of key %q in mapping", k.Value)})
+				continue
+			}
+			prev = k.Value
+		}
+	})
+	return out
+}
+
+func checkLineLength(doc *Document, opts Options) []Problem {
+	var out []Problem
+	max := opts.Int("max", DefaultMaxLineLength)
+	for i, line := range doc.Lines {
+		length := utf8.RuneCountInString(line)
+		if length <= max || isNonBreakable(line) {
+			continue
+		}
+		out = append(out, Problem{Line: i + 1, Column: max + 1, Message: fmt.Sprintf("line too long (%d > %d characters)", length, max)})
+	}
+	return out
+}
+
+// isNonBreakable reports whether a line holds a single word after its
+// indentation, list markers or comment sign (e.g. a long URL).
+func isNonBreakable(line string) bool {
+	s := strings.TrimLeft(line, " ")
+	for strings.HasPrefix(s, "- ") {
+		s = strings.TrimLeft(s[2:], " ")
+	}
+	if strings.HasPrefix(s, "#") {
+		s = strings.TrimLeft(s[1:], " ")
+	}
+	return !strings.ContainsAny(strings.TrimRight(s, " \t"), " \t")
+}
+
+func checkTrailingSpaces(doc *Document, _ Options) []Problem {
+	var out []Problem
+	for i, line := range doc.Lines {
+		line = strings.TrimSuffix(line, "\r")
+		trimmed := strings.TrimRight(line, " \t")
+		if len(trimmed) == len(line) {
+			continue
+		}
+		out = append(out, Problem{Line: i + 1, Column: utf8.RuneCountInString(trimmed) + 1, Message: "trailing spaces"})
+	}
+	return out
+}
+
+func checkDocumentStart(doc *Document, _ Options) []Problem {
+	if doc.Format == "json" || doc.Explicit {
+		return nil
+	}
+	return []Problem{{Line: 1, Column: 1, Message: "missing document start \"---\""}}
+}
@@ -0,0 +1,57 @@
+package lint
+
+import (
+	"strings"
+)
+
+// DisableNextLine is the inline comment directive that suppresses problems
+// on the following line:
+//
+//	# devformat-disable-next-line truthy line-length
+//
+// Without rule IDs every rule is suppressed for that line.
+const DisableNextLine = "devformat-disable-next-line"
+
+// Suppressions maps a 1-based line number to the rule IDs suppressed on it.
+// An empty list suppresses every rule.
+type Suppressions map[int][]string
+
+// ParseSuppressions collects DisableNextLine directives from content.
+func ParseSuppressions(content string) Suppressions {
+	out := Suppressions{}
+	for i, line := range strings.Split(content, "\n") {
+		idx := strings.Index(line, "#")
+		if idx < 0 {
+			continue
+		}
+		comment := strings.TrimSpace(line[idx+1:])
+		if !strings.HasPrefix(comment, DisableNextLine) {
+			continue
+		}
+		rest := strings.TrimPrefix(comment, DisableNextLine)
+		if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+			continue
+		}
+		ids := strings.FieldsFunc(rest, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' })
+		// line i (0-based) suppresses the next line, i+2 in 1-based numbering
+		out[i+2] = ids
+	}
+	return out
+}
+
+// Suppressed reports whether problems of rule id on line are suppressed.
+func (s Suppressions) Suppressed(line int, id string) bool {
+	ids, ok := s[line]
+	if !ok {
+		return false
+	}
+	if len(ids) == 0 {
+		return true
+	}
+	for _, x := range ids {
+		if x == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,249 @@
+// Package openapi generates the OpenAPI 3.0 document of the HTTP API. Schemas
+// are derived by reflection from the request and response types in
+// internal/types, so the document cannot drift from what the handlers encode.
+//
+// The checked-in copy at backend/openapi.json is refreshed with:
+//
+//	go generate ./internal/openapi
+package openapi
+
+//go:generate go run ../../cmd/openapi -o ../../openapi.json
+
+import (
+	"reflect"
+	"strings"
+
+	"devformat/backend/internal/convert"
+	"devformat/backend/internal/dupkeys"
+	"devformat/backend/internal/fixer"
+	"devformat/backend/internal/parser"
+	"devformat/backend/internal/types"
+)
+
+// enums lists the allowed values of string fields, keyed by "Type.jsonName".
+var enums = map[string][]string{
+	"ValidationError.severity":          {"error", "warning", "info"},
+	"RuleInfo.severity":                 {"error", "warning", "info"},
+	"Suggestion.confidence":             {types.ConfidenceHigh, types.ConfidenceMedium, types.ConfidenceLow},
+	"Suggestion.source":                 {types.SourceHeuristic, types.SourceAI},
+	"FixRequest.duplicateKeys":          {string(dupkeys.KeepFirst), string(dupkeys.KeepLast), string(dupkeys.Merge)},
+	"FixHunk.kind":                      fixKinds(),
+	"ValidateResponse.format":           {parser.FormatYAML, parser.FormatJSON, parser.FormatTOML, parser.FormatHCL, parser.FormatINI, parser.FormatDotenv, parser.FormatXML},
+	"ValidateResponse.formatConfidence": {types.ConfidenceHigh, types.ConfidenceMedium, types.ConfidenceLow},
+	"ConvertRequest.from":               convert.Formats,
+	"ConvertRequest.to":                 convert.Formats,
+	"ConvertResponse.from":              convert.Formats,
+	"ConvertResponse.to":                convert.Formats,
+}
+
+// fixKinds lists what a fix hunk can be attributed to: the built-in fixers
+// in the order they run, then the Kubernetes metadata.name fix and
+// reformatting.
+func fixKinds() []string {
+	var kinds []string
+	for _, f := range fixer.Fixers() {
+		kinds = append(kinds, f.ID)
+	}
+	return append(kinds, types.FixKindMetadataName, types.FixKindFormat)
+}
+
+// Document returns the OpenAPI document as a JSON-encodable value.
+func Document() map[string]any {
+	g := &generator{components: map[string]any{}}
+
+	jsonBody := func(v any) map[string]any {
+		return map[string]any{
+			"required": true,
+			"content":  map[string]any{"application/json": map[string]any{"schema": g.schema(reflect.TypeOf(v))}},
+		}
+	}
+	jsonResponse := func(description string, v any) map[string]any {
+		return map[string]any{
+			"description": description,
+			"content":     map[string]any{"application/json": map[string]any{"schema": g.schema(reflect.TypeOf(v))}},
+		}
+	}
+	errorResponse := jsonResponse("Invalid request or server error", types.ErrorResponse{})
+
+	paths := map[string]any{
+		"/api/validate": map[string]any{
+			"post": map[string]any{
+				"operationId": "validate",
+				"summary":     "Validate YAML/JSON content against syntax, schemas and lint rules",
+				"requestBody": jsonBody(types.ValidateRequest{}),
+				"responses": map[string]any{
+					"200": jsonResponse("Validation result", types.ValidateResponse{}),
+					"400": errorResponse,
+				},
+			},
+		},
+		"/api/fix": map[string]any{
+			"post": map[string]any{
+				"operationId": "fix",
+				"summary":     "Repair and re-format YAML content",
+				"requestBody": jsonBody(types.FixRequest{}),
+				"responses": map[string]any{
+					"200": jsonResponse("Fix result", types.FixResponse{}),
+					"400": errorResponse,
+					"500": errorResponse,
+				},
+			},
+		},
+		"/api/apply-suggestion": map[string]any{
+			"post": map[string]any{
+				"operationId": "applySuggestion",
+				"summary":     "Splice a suggestion into the content, re-validate it and return a unified diff",
+				"requestBody": jsonBody(types.ApplySuggestionRequest{}),
+				"responses": map[string]any{
+					"200": jsonResponse("Patched content", types.ApplySuggestionResponse{}),
+					"400": errorResponse,
+					"404": errorResponse,
+				},
+			},
+		},
+		"/api/convert": map[string]any{
+			"post": map[string]any{
+				"operationId": "convert",
+				"summary":     "Convert content between YAML, JSON and TOML, reporting what the target format loses",
+				"requestBody": jsonBody(types.ConvertRequest{}),
+				"responses": map[string]any{
+					"200": jsonResponse("Converted content", types.ConvertResponse{}),
+					"400": errorResponse,
+				},
+			},
+		},
+		"/api/helm/lint": map[string]any{
+			"post": map[string]any{
+				"operationId": "helmLint",
+				"summary":     "Lint a Helm chart archive: Chart.yaml, values against values.schema.json and every rendered template",
+				"requestBody": jsonBody(types.HelmLintRequest{}),
+				"responses": map[string]any{
+					"200": jsonResponse("Problems per chart file", types.HelmLintResponse{}),
+					"400": errorResponse,
+				},
+			},
+		},
+		"/api/kustomize/build": map[string]any{
+			"post": map[string]any{
+				"operationId": "kustomizeBuild",
+				"summary":     "Build a Kustomize overlay archive in-process and validate the output against the Kubernetes schemas",
+				"requestBody": jsonBody(types.KustomizeRequest{}),
+				"responses": map[string]any{
+					"200": jsonResponse("Built manifests and problems per file", types.KustomizeResponse{}),
+					"400": errorResponse,
+				},
+			},
+		},
+		"/api/format-zip": map[string]any{
+			"post": map[string]any{
+				"operationId": "formatZip",
+				"summary":     "Format Terraform files and return them as a zip archive",
+				"requestBody": jsonBody(types.FormatRequest{}),
+				"responses": map[string]any{
+					"200": map[string]any{
+						"description": "Zip archive",
+						"content":     map[string]any{"application/zip": map[string]any{"schema": map[string]any{"type": "string", "format": "binary"}}},
+					},
+					"400": errorResponse,
+					"500": errorResponse,
+					"504": errorResponse,
+				},
+			},
+		},
+		"/api/rules": map[string]any{
+			"get": map[string]any{
+				"operationId": "listRules",
+				"summary":     "List the lint rules that can be selected on validate",
+				"responses": map[string]any{
+					"200": jsonResponse("Registered lint rules", types.RulesResponse{}),
+				},
+			},
+		},
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "DevFormat API",
+			"version":     types.APIVersion,
+			"description": "Every route is also served under /api/" + types.APIVersion + "/.",
+		},
+		"paths":      paths,
+		"components": map[string]any{"schemas": g.components},
+	}
+}
+
+type generator struct {
+	components map[string]any
+}
+
+// schema returns the schema of t. Named structs become components and are
+// referenced by name.
+func (g *generator) schema(t reflect.Type) map[string]any {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.schema(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			// encoding/json writes []byte as base64
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		name := t.Name()
+		if _, ok := g.components[name]; !ok {
+			// reserve the name first so recursive types terminate
+			g.components[name] = nil
+			g.components[name] = g.object(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + name}
+	}
+	// interfaces accept any value
+	return map[string]any{}
+}
+
+// object describes a struct from its json tags. Response fields without
+// omitempty are always present; request fields are required only when the
+// handlers bind them with binding:"required".
+func (g *generator) object(t reflect.Type) map[string]any {
+	isRequest := strings.HasSuffix(t.Name(), "Request")
+	props := map[string]any{}
+	required := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s := g.schema(f.Type)
+		if values, ok := enums[t.Name()+"."+name]; ok {
+			s["enum"] = values
+		}
+		props[name] = s
+		if isRequest && f.Tag.Get("binding") == "required" || !isRequest && !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+	}
+	out := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		out["required"] = required
+	}
+	return out
+}
@@ -0,0 +1,558 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+
+	"devformat/backend/internal/json5"
+	"devformat/backend/internal/types"
+)
+
+// Formats recognized by Detect.
+const (
+	FormatYAML   = "yaml"
+	FormatJSON   = "json"
+	FormatTOML   = "toml"
+	FormatHCL    = "hcl"
+	FormatINI    = "ini"
+	FormatDotenv = "dotenv"
+	FormatXML    = "xml"
+)
+
+// Detection is the outcome of Detect.
+type Detection struct {
+	Format string
+	// Confidence is types.ConfidenceHigh, ConfidenceMedium or ConfidenceLow.
+	Confidence string
+	// Reason says what decided the format, for explanations.
+	Reason string
+	// Err is the syntax error of the content in Format, when one was found.
+	Err *FormatError
+}
+
+// FormatError is a syntax error found while trying a format. Line is 1-based,
+// or 0 when unknown.
+type FormatError struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *FormatError) Error() string {
+	if e.Line == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// extensionFormats maps file extensions to formats.
+var extensionFormats = map[string]string{
+	".yaml": FormatYAML, ".yml": FormatYAML,
+	".json": FormatJSON, ".jsonc": FormatJSON, ".json5": FormatJSON,
+	".toml": FormatTOML,
+	".tf":   FormatHCL, ".tfvars": FormatHCL, ".hcl": FormatHCL, ".nomad": FormatHCL,
+	".ini": FormatINI, ".cfg": FormatINI,
+	".env": FormatDotenv,
+	".xml": FormatXML, ".xsd": FormatXML, ".xsl": FormatXML, ".svg": FormatXML, ".plist": FormatXML,
+}
+
+// baseNameFormats maps well-known file names without a telling extension.
+var baseNameFormats = map[string]string{
+	".env": FormatDotenv, "Pipfile": FormatTOML, ".editorconfig": FormatINI, ".gitconfig": FormatINI,
+}
+
+// modeNames maps editor mode and file type names to formats.
+var modeNames = map[string]string{
+	"yaml": FormatYAML, "yml": FormatYAML,
+	"json": FormatJSON, "jsonc": FormatJSON, "json5": FormatJSON, "js": FormatJSON,
+	"toml": FormatTOML, "conf-toml": FormatTOML,
+	"hcl": FormatHCL, "terraform": FormatHCL, "tf": FormatHCL,
+	"ini": FormatINI, "dosini": FormatINI, "conf-windows": FormatINI,
+	"dotenv": FormatDotenv, "env": FormatDotenv, "sh": FormatDotenv,
+	"xml": FormatXML, "nxml": FormatXML,
+}
+
+// shebangTools maps the interpreter named by a "#!" line to the format it
+// reads; shell scripts source dotenv files.
+var shebangTools = map[string]string{
+	"yq": FormatYAML, "jq": FormatJSON, "xmllint": FormatXML,
+	"sh": FormatDotenv, "bash": FormatDotenv, "zsh": FormatDotenv,
+}
+
+var (
+	// vim: set ft=yaml: / vim: syntax=toml
+	vimModelineRe = regexp.MustCompile(`\bvim?:.*\b(?:ft|filetype|syntax)=([\w-]+)`)
+	// -*- mode: yaml -*- / -*- yaml -*-
+	emacsModelineRe = regexp.MustCompile(`-\*-\s*(?:.*\bmode:\s*)?([\w-]+)\s*;?.*-\*-`)
+)
+
+// Detect determines the format of content from, in order of precedence, an
+// editor modeline, the filename extension and a shebang line, each confirmed
+// by parsing the content; without such a hint it tries the formats from the
+// most to the least specific. A hinted format the content does not parse as
+// is still returned, with medium confidence and the syntax error. A lone
+// YAML scalar is YAML with low confidence; content nothing recognizes falls
+// back to first-character sniffing (JSON for "{" and "[", XML for "<",
+// otherwise YAML) with low confidence.
+func Detect(content, filename string) Detection {
+	content = strings.TrimPrefix(content, "\ufeff")
+	trimmed := strings.TrimSpace(content)
+	if trimmed == "" {
+		return Detection{Format: FormatYAML, Confidence: types.ConfidenceLow, Reason: "empty content"}
+	}
+
+	if format, reason := formatHint(content, filename); format != "" {
+		if err := checkSyntax(format, content); err != nil {
+			return Detection{Format: format, Confidence: types.ConfidenceMedium, Reason: reason + ", but the content does not parse as " + format, Err: err}
+		}
+		return Detection{Format: format, Confidence: types.ConfidenceHigh, Reason: reason}
+	}
+
+	for _, t := range trials {
+		if confidence := t.try(content, trimmed); confidence != "" {
+			reason := t.reason
+			if reason == "" {
+				reason = "content parses as " + t.format
+			}
+			return Detection{Format: t.format, Confidence: confidence, Reason: reason}
+		}
+	}
+
+	d := Detection{Format: FormatYAML, Confidence: types.ConfidenceLow, Reason: "no format matched"}
+	switch trimmed[0] {
+	case '{', '[':
+		d.Format = FormatJSON
+	case '<':
+		d.Format = FormatXML
+	}
+	d.Err = checkSyntax(d.Format, content)
+	return d
+}
+
+// formatHint returns the format named by a modeline, the filename or a
+// shebang line, with the reason.
+func formatHint(content, filename string) (string, string) {
+	lines := strings.Split(content, "\n")
+	// editors read modelines from the first and last few lines
+	var edges []string
+	if len(lines) <= 10 {
+		edges = lines
+	} else {
+		edges = append(append(edges, lines[:5]...), lines[len(lines)-5:]...)
+	}
+	for _, line := range edges {
+		for _, re := range []*regexp.Regexp{vimModelineRe, emacsModelineRe} {
+			if m := re.FindStringSubmatch(line); m != nil {
+				if format := modeNames[strings.ToLower(m[1])]; format != "" {
+					return format, "editor modeline"
+				}
+			}
+		}
+	}
+
+	if filename != "" {
+		base := filepath.Base(filename)
+		if format := baseNameFormats[base]; format != "" {
+			return format, "file name " + base
+		}
+		if strings.HasPrefix(base, ".env.") {
+			return FormatDotenv, "file name " + base
+		}
+		ext := strings.ToLower(filepath.Ext(base))
+		if format := extensionFormats[ext]; format != "" {
+			return format, "file extension " + ext
+		}
+	}
+
+	if strings.HasPrefix(lines[0], "#!") {
+		fields := strings.Fields(strings.TrimPrefix(lines[0], "#!"))
+		for i, f := range fields {
+			tool := filepath.Base(f)
+			// #!/usr/bin/env [-S] tool
+			if tool == "env" || i > 0 && strings.HasPrefix(f, "-") {
+				continue
+			}
+			if format := shebangTools[tool]; format != "" {
+				return format, "shebang " + tool
+			}
+			break
+		}
+	}
+	return "", ""
+}
+
+// trial tries one format on content without a hint; try returns the
+// confidence of a match, or "" for none. Reason defaults to "content parses
+// as <format>".
+type trial struct {
+	format string
+	reason string
+	try    func(content, trimmed string) string
+}
+
+// trials are ordered from the most to the least specific format. A JSON
+// document is also YAML, so JSON goes first, then JSON5 and JSONC (trailing
+// commas, comments, single quotes), except for content that is also YAML and
+// has unquoted keys, a YAML flow mapping such as "{a: 1}". YAML only counts with a mapping or sequence, since nearly any text is
+// a YAML scalar, and goes before INI so that a flow sequence such as "[a, b]"
+// is not read as a section header. A lone YAML scalar is the last resort.
+var trials = []trial{
+	{FormatXML, "", func(content, trimmed string) string {
+		if trimmed[0] == '<' && checkXML(content) == nil {
+			return types.ConfidenceHigh
+		}
+		return ""
+	}},
+	{FormatJSON, "", func(_, trimmed string) string {
+		if (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid([]byte(trimmed)) {
+			return types.ConfidenceHigh
+		}
+		return ""
+	}},
+	// JSON5 and JSONC, which the fixer repairs
+	{FormatJSON, "content parses as JSON5", func(content, trimmed string) string {
+		if trimmed[0] != '{' && trimmed[0] != '[' {
+			return ""
+		}
+		_, repairs, err := json5.Parse(content)
+		if err != nil {
+			return ""
+		}
+		for _, r := range repairs {
+			if r.Kind == json5.RepairUnquotedKey && checkYAML(content) == nil {
+				return ""
+			}
+		}
+		return types.ConfidenceMedium
+	}},
+	{FormatDotenv, "", func(content, _ string) string {
+		if keys, err := dotenvKeys(content); err == nil && allEnvNames(keys) {
+			return types.ConfidenceHigh
+		}
+		return ""
+	}},
+	{FormatTOML, "", func(content, _ string) string {
+		if checkTOML(content) == nil && tomlLineRe.MatchString(content) {
+			return types.ConfidenceHigh
+		}
+		return ""
+	}},
+	{FormatYAML, "", func(content, _ string) string {
+		if yamlCollections(content) {
+			return types.ConfidenceHigh
+		}
+		return ""
+	}},
+	{FormatDotenv, "", func(content, _ string) string {
+		if _, err := dotenvKeys(content); err == nil {
+			return types.ConfidenceMedium
+		}
+		return ""
+	}},
+	{FormatINI, "", func(content, _ string) string {
+		if checkINI(content) == nil && iniSectionRe.MatchString(content) {
+			return types.ConfidenceMedium
+		}
+		return ""
+	}},
+	{FormatHCL, "", func(content, _ string) string {
+		if checkHCL(content) == nil && hclBlockRe.MatchString(content) {
+			return types.ConfidenceMedium
+		}
+		return ""
+	}},
+	{FormatYAML, "content parses as a YAML scalar", func(content, _ string) string {
+		if checkYAML(content) == nil {
+			return types.ConfidenceLow
+		}
+		return ""
+	}},
+}
+
+var (
+	// tomlLineRe matches a table header or a key/value pair
+	tomlLineRe = regexp.MustCompile(`(?m)^\s*(\[\[?[^\]]+\]\]?|[\w"'.-]+\s*=)`)
+	// iniSectionRe matches a section header
+	iniSectionRe = regexp.MustCompile(`(?m)^\s*\[[^\]]+\]\s*$`)
+	// hclBlockRe matches a block header such as resource "aws_s3_bucket" "b" {
+	hclBlockRe = regexp.MustCompile(`(?m)^\s*[A-Za-z_][\w-]*(\s+("[^"]*"|[A-Za-z_][\w-]*))*\s*\{\s*$`)
+	// envNameRe matches a conventional environment variable name
+	envNameRe = regexp.MustCompile(`^[A-Z_][A-Z0-9_]*$`)
+	// dotenvLineRe matches KEY=value, optionally exported
+	dotenvLineRe = regexp.MustCompile(`^(?:export\s+)?([A-Za-z_][A-Za-z0-9_.-]*)\s*=(.*)$`)
+	// heredocRe matches the opening of an HCL heredoc, <<EOF or <<-EOF
+	heredocRe = regexp.MustCompile(`^<<-?([A-Za-z_]\w*)[ \t]*\r?\n`)
+	// iniLineRe matches key = value or key: value
+	iniLineRe = regexp.MustCompile(`^[^=:\s\[][^=:]*[=:]`)
+)
+
+// checkSyntax returns the first syntax error of content in format, or nil.
+func checkSyntax(format, content string) *FormatError {
+	switch format {
+	case FormatYAML:
+		return checkYAML(content)
+	case FormatJSON:
+		if _, _, err := json5.Parse(content); err != nil {
+			var se *json5.SyntaxError
+			if errors.As(err, &se) {
+				return &FormatError{Line: se.Line, Column: se.Column, Message: se.Msg}
+			}
+			return &FormatError{Message: err.Error()}
+		}
+	case FormatTOML:
+		return checkTOML(content)
+	case FormatXML:
+		return checkXML(content)
+	case FormatDotenv:
+		_, err := dotenvKeys(content)
+		return err
+	case FormatINI:
+		return checkINI(content)
+	case FormatHCL:
+		return checkHCL(content)
+	}
+	return nil
+}
+
+func checkYAML(content string) *FormatError {
+	dec := yaml.NewDecoder(strings.NewReader(content))
+	for {
+		var node yaml.Node
+		err := dec.Decode(&node)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			line := YAMLErrorLine(err)
+			return &FormatError{Line: line, Message: YAMLErrorMessage(err)}
+		}
+	}
+}
+
+// yamlCollections reports whether content parses as YAML with at least one
+// document holding a mapping or sequence; lone scalars match almost any text.
+func yamlCollections(content string) bool {
+	dec := yaml.NewDecoder(strings.NewReader(content))
+	found := false
+	for {
+		var node yaml.Node
+		err := dec.Decode(&node)
+		if errors.Is(err, io.EOF) {
+			return found
+		}
+		if err != nil {
+			return false
+		}
+		if len(node.Content) > 0 && (node.Content[0].Kind == yaml.MappingNode || node.Content[0].Kind == yaml.SequenceNode) {
+			found = true
+		}
+	}
+}
+
+func checkTOML(content string) *FormatError {
+	var v map[string]any
+	err := toml.Unmarshal([]byte(content), &v)
+	if err == nil {
+		return nil
+	}
+	var de *toml.DecodeError
+	if errors.As(err, &de) {
+		line, col := de.Position()
+		return &FormatError{Line: line, Column: col, Message: strings.TrimPrefix(de.Error(), "toml: ")}
+	}
+	return &FormatError{Message: err.Error()}
+}
+
+// checkXML requires a single well-formed root element.
+func checkXML(content string) *FormatError {
+	dec := xml.NewDecoder(strings.NewReader(content))
+	depth, roots := 0, 0
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var se *xml.SyntaxError
+			if errors.As(err, &se) {
+				return &FormatError{Line: se.Line, Message: se.Msg}
+			}
+			return &FormatError{Message: err.Error()}
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if depth == 0 {
+				roots++
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			if depth == 0 && len(bytes.TrimSpace(t)) > 0 {
+				line, _ := dec.InputPos()
+				return &FormatError{Line: line, Message: "text outside the root element"}
+			}
+		}
+	}
+	switch {
+	case roots == 0:
+		return &FormatError{Message: "no root element"}
+	case roots > 1:
+		return &FormatError{Message: "more than one root element"}
+	}
+	return nil
+}
+
+// dotenvKeys returns the variable names of a dotenv file, or the first line
+// that is not a comment, blank or assignment. Quoted values may span lines.
+func dotenvKeys(content string) ([]string, *FormatError) {
+	var keys []string
+	lines := strings.Split(content, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		m := dotenvLineRe.FindStringSubmatch(line)
+		if m == nil {
+			return nil, &FormatError{Line: i + 1, Message: "expected KEY=value"}
+		}
+		keys = append(keys, m[1])
+		value := strings.TrimSpace(m[2])
+		if value == "" || value[0] != '"' && value[0] != '\'' {
+			continue
+		}
+		quote, start := value[:1], i
+		for !closesQuote(value[1:], quote) {
+			i++
+			if i == len(lines) {
+				return nil, &FormatError{Line: start + 1, Message: "unterminated quoted value"}
+			}
+			value = lines[i]
+		}
+	}
+	if len(keys) == 0 {
+		return nil, &FormatError{Message: "no assignments"}
+	}
+	return keys, nil
+}
+
+// closesQuote reports whether s holds an unescaped quote.
+func closesQuote(s, quote string) bool {
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quote == `"`:
+			i++
+		case s[i:i+1] == quote:
+			return true
+		}
+	}
+	return false
+}
+
+func allEnvNames(keys []string) bool {
+	for _, k := range keys {
+		if !envNameRe.MatchString(k) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkINI accepts section headers, key = value and key: value pairs,
+// continuation lines and ";" or "#" comments.
+func checkINI(content string) *FormatError {
+	for i, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "", trimmed[0] == ';', trimmed[0] == '#':
+		case trimmed[0] == '[':
+			if !strings.HasSuffix(trimmed, "]") {
+				return &FormatError{Line: i + 1, Message: "unterminated section header"}
+			}
+		case line[0] == ' ' || line[0] == '\t':
+			// continuation of the previous value
+		case !iniLineRe.MatchString(trimmed):
+			return &FormatError{Line: i + 1, Message: "expected a section, key = value or comment"}
+		}
+	}
+	return nil
+}
+
+// checkHCL checks that braces, brackets and parentheses balance outside
+// strings and comments; a full HCL parse needs the HCL toolchain.
+func checkHCL(content string) *FormatError {
+	type open struct {
+		ch   byte
+		line int
+	}
+	var stack []open
+	closing := map[byte]byte{'}': '{', ']': '[', ')': '('}
+	line := 1
+	for i := 0; i < len(content); i++ {
+		ch := content[i]
+		switch {
+		case ch == '\n':
+			line++
+		case ch == '#' || strings.HasPrefix(content[i:], "//"):
+			for i+1 < len(content) && content[i+1] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(content[i:], "/*"):
+			end := strings.Index(content[i+2:], "*/")
+			if end < 0 {
+				return &FormatError{Line: line, Message: "unterminated comment"}
+			}
+			line += strings.Count(content[i:i+2+end], "\n")
+			i += end + 3
+		case ch == '"':
+			start := line
+			for i++; i < len(content) && content[i] != '"'; i++ {
+				if content[i] == '\\' {
+					i++
+				} else if content[i] == '\n' {
+					return &FormatError{Line: start, Message: "unterminated string"}
+				}
+			}
+			if i >= len(content) {
+				return &FormatError{Line: start, Message: "unterminated string"}
+			}
+		case strings.HasPrefix(content[i:], "<<"):
+			// heredoc, up to a line holding only its marker
+			m := heredocRe.FindStringSubmatch(content[i:])
+			if m == nil {
+				return &FormatError{Line: line, Message: "invalid heredoc"}
+			}
+			endRe := regexp.MustCompile(`(?m)^[ \t]*` + regexp.QuoteMeta(m[1]) + `[ \t]*$`)
+			loc := endRe.FindStringIndex(content[i+len(m[0]):])
+			if loc == nil {
+				return &FormatError{Line: line, Message: "unterminated heredoc"}
+			}
+			skip := len(m[0]) + loc[1]
+			line += strings.Count(content[i:i+skip], "\n")
+			i += skip - 1
+		case ch == '{' || ch == '[' || ch == '(':
+			stack = append(stack, open{ch, line})
+		case closing[ch] != 0:
+			if len(stack) == 0 || stack[len(stack)-1].ch != closing[ch] {
+				return &FormatError{Line: line, Message: fmt.Sprintf("unexpected %q", ch)}
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+	if len(stack) > 0 {
+		top := stack[len(stack)-1]
+		return &FormatError{Line: top.line, Message: fmt.Sprintf("unclosed %q", top.ch)}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package parser
+
+import (
+	"testing"
+
+	"devformat/backend/internal/types"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name       string
+		content    string
+		filename   string
+		format     string
+		confidence string
+		// errLine is the line of the syntax error, -1 for none
+		errLine int
+	}{
+		{"empty", "  \n", "", FormatYAML, types.ConfidenceLow, -1},
+		{"yaml mapping", "a: 1\nb: [x]\n", "", FormatYAML, types.ConfidenceHigh, -1},
+		{"yaml by extension", "a: 1\n", "app.yml", FormatYAML, types.ConfidenceHigh, -1},
+		{"broken yaml by extension", "a: 1\n b: 2\n", "app.yaml", FormatYAML, types.ConfidenceMedium, 2},
+		{"json", `{"a": 1}`, "", FormatJSON, types.ConfidenceHigh, -1},
+		{"json5 by extension", "{\n  // comment\n  a: 1,\n}\n", "tsconfig.json", FormatJSON, types.ConfidenceHigh, -1},
+		{"json5 by content", "{\n  // comment\n  \"a\": 1,\n}\n", "", FormatJSON, types.ConfidenceMedium, -1},
+		{"broken json by extension", "{\"a\": [1,\n", "x.json", FormatJSON, types.ConfidenceMedium, 1},
+		{"toml", "[server]\nport = 8080\nname = \"x\"\n", "", FormatTOML, types.ConfidenceHigh, -1},
+		{"toml by extension", "port = 8080\n", "config.toml", FormatTOML, types.ConfidenceHigh, -1},
+		{"dotenv", "PORT=8080\nNAME=x\n", ".env", FormatDotenv, types.ConfidenceHigh, -1},
+		{"xml", "<a><b/></a>", "", FormatXML, types.ConfidenceHigh, -1},
+		{"modeline wins over extension", "# -*- mode: yaml -*-\na: 1\n", "x.json", FormatYAML, types.ConfidenceHigh, -1},
+		{"lone scalar", "hello", "", FormatYAML, types.ConfidenceLow, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := Detect(tt.content, tt.filename)
+			if d.Format != tt.format || d.Confidence != tt.confidence {
+				t.Errorf("Detect() = %s (%s: %s), want %s (%s)", d.Format, d.Confidence, d.Reason, tt.format, tt.confidence)
+			}
+			switch {
+			case tt.errLine < 0 && d.Err != nil:
+				t.Errorf("Detect() error = %v, want none", d.Err)
+			case tt.errLine >= 0 && d.Err == nil:
+				t.Errorf("Detect() found no error, want one on line %d", tt.errLine)
+			case tt.errLine >= 0 && d.Err.Line != tt.errLine:
+				t.Errorf("Detect() error = %v, want it on line %d", d.Err, tt.errLine)
+			}
+		})
+	}
+}
@@ -1,39 +1,67 @@
 package parser
 
 import (
-	"bufio"
+	"errors"
+	"fmt"
+	"io"
 	"strings"
+
+	"gopkg.in/yaml.v3"
 )
 
-// SplitYAML splits YAML content into individual documents separated by "---"
-func SplitYAML(content string) []string {
-	docs := []string{}
-	scanner := bufio.NewScanner(strings.NewReader(content))
-	var current []string
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.TrimSpace(line) == "---" {
-			docs = append(docs, strings.Join(current, "\n"))
-			current = []string{}
-			continue
-		}
-		current = append(current, line)
-	}
-	if len(current) > 0 {
-		docs = append(docs, strings.Join(current, "\n"))
+// DetectFormat returns the format of content, one of the Format constants,
+// as Detect finds it without a filename.
+func DetectFormat(content string) string {
+	return Detect(content, "").Format
+}
+
+// ParseJSONNode parses JSON content into a yaml.Node tree so that JSON and
+// YAML documents can share node-based checks. JSON forbids raw tabs inside
+// strings, so replacing them with spaces keeps every line/column intact while
+// avoiding YAML's ban on tab indentation.
+func ParseJSONNode(content string) (*yaml.Node, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(strings.ReplaceAll(content, "\t", " ")), &node); err != nil {
+		return nil, err
 	}
-	return docs
+	return &node, nil
 }
 
-// DetectFormat detects if content is JSON or YAML based on structure
-func DetectFormat(content string) string {
-	trimmed := strings.TrimSpace(content)
-	if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
-		return "json"
+// ParseYAMLNode parses one YAML document into a yaml.Node tree. Unlike
+// yaml.Unmarshal, which stops after the first node, it reports content left
+// over after the document, such as keys dedented below an indented root
+// mapping. Empty content yields an empty node.
+func ParseYAMLNode(content string) (*yaml.Node, error) {
+	dec := yaml.NewDecoder(strings.NewReader(content))
+	var node yaml.Node
+	if err := dec.Decode(&node); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
 	}
-	return "yaml"
+	var rest yaml.Node
+	err := dec.Decode(&rest)
+	switch {
+	case errors.Is(err, io.EOF):
+		return &node, nil
+	case err == nil:
+		// an empty document has nothing to point at but its "---", the last
+		// line that is not blank
+		line := strings.Count(strings.TrimRight(content, " \t\r\n"), "\n") + 1
+		if len(rest.Content) > 0 && !emptyScalar(rest.Content[0]) {
+			line = rest.Content[0].Line
+		}
+		return nil, fmt.Errorf("yaml: line %d: %s", line, trailingContent)
+	case strings.Contains(err.Error(), "did not find expected <document start>"):
+		// yaml.v3 reports parser errors with 0-based line numbers
+		if line := YAMLErrorLine(err); line > 0 {
+			return nil, fmt.Errorf("yaml: line %d: %s", line+1, trailingContent)
+		}
+		return nil, errors.New("yaml: " + trailingContent)
+	}
+	return nil, err
 }
 
+const trailingContent = "found content after the end of the document"
+
 // PreprocessYAML normalizes whitespace in YAML content
 func PreprocessYAML(s string) string {
 	s = strings.ReplaceAll(s, "\r\n", "\n")
@@ -46,7 +74,26 @@ func PreprocessYAML(s string) string {
 	return strings.Join(lines, "\n")
 }
 
-// ContainsHelmTemplate checks if content contains Helm template markers
+// ContainsHelmTemplate checks if content contains Helm template markers.
+// GitHub Actions expressions ("${{ ... }}") are not template markers.
 func ContainsHelmTemplate(content string) bool {
-	return strings.Contains(content, "{{") && strings.Contains(content, "}}")
+	if !strings.Contains(content, "}}") {
+		return false
+	}
+	for i := 0; ; {
+		j := strings.Index(content[i:], "{{")
+		if j < 0 {
+			return false
+		}
+		i += j
+		if i == 0 || content[i-1] != '$' {
+			return true
+		}
+		i += 2
+	}
+}
+
+// emptyScalar reports whether n is the null yaml.v3 puts in an empty document.
+func emptyScalar(n *yaml.Node) bool {
+	return n.Kind == yaml.ScalarNode && n.Style == 0 && n.Value == ""
 }
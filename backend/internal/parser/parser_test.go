@@ -0,0 +1,75 @@
+package parser
+
+import (
+	"errors"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseYAMLNode(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		kind    yaml.Kind
+		// errLine is the line YAMLErrorLine reads from the error, -1 for none
+		errLine int
+	}{
+		{"mapping", "a: 1\n", yaml.MappingNode, -1},
+		{"empty", "", 0, -1},
+		{"comment only", "# nothing\n", 0, -1},
+		{"syntax error", "a: 1\n b: 2\n", 0, 2},
+		{"trailing content", "a: 1\n---\nb: 2\n", 0, 3},
+		{"trailing marker only", "a: 1\n---\n", 0, 2},
+		{"content after scalar", "\"a\"\nb\n", 0, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, err := ParseYAMLNode(tt.content)
+			if tt.errLine >= 0 {
+				if err == nil {
+					t.Fatalf("ParseYAMLNode() succeeded, want an error on line %d", tt.errLine)
+				}
+				if line := YAMLErrorLine(err); line != tt.errLine {
+					t.Errorf("ParseYAMLNode() error = %v, want it on line %d", err, tt.errLine)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseYAMLNode() error = %v", err)
+			}
+			var kind yaml.Kind
+			if len(node.Content) > 0 {
+				kind = node.Content[0].Kind
+			}
+			if kind != tt.kind {
+				t.Errorf("ParseYAMLNode() root kind = %v, want %v", kind, tt.kind)
+			}
+		})
+	}
+}
+
+func TestYAMLErrorPosition(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		err       error
+		line, col int
+		message   string
+	}{
+		{"line and column", "a: 1\n  b: 2\n", errors.New("yaml: line 2: mapping values are not allowed in this context"), 2, 3, "mapping values are not allowed in this context"},
+		{"no line", "a", errors.New("yaml: control characters are not allowed"), 0, 0, "control characters are not allowed"},
+		{"past the end", "a", errors.New("yaml: line 4: did not find expected key"), 4, 1, "did not find expected key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, col := YAMLErrorPosition(tt.content, tt.err)
+			if line != tt.line || col != tt.col {
+				t.Errorf("YAMLErrorPosition() = %d:%d, want %d:%d", line, col, tt.line, tt.col)
+			}
+			if msg := YAMLErrorMessage(tt.err); msg != tt.message {
+				t.Errorf("YAMLErrorMessage() = %q, want %q", msg, tt.message)
+			}
+		})
+	}
+}
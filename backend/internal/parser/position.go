@@ -0,0 +1,91 @@
+package parser
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	yamlLineRe   = regexp.MustCompile(`line (\d+)`)
+	yamlPrefixRe = regexp.MustCompile(`^yaml: (line \d+: )?`)
+)
+
+// YAMLErrorLine extracts the 1-based line number reported in a yaml.v3 error
+// ("yaml: line 3: ..."). It returns 0 when the error carries no line.
+func YAMLErrorLine(err error) int {
+	if err == nil {
+		return 0
+	}
+	m := yamlLineRe.FindStringSubmatch(err.Error())
+	if len(m) != 2 {
+		return 0
+	}
+	n, _ := strconv.Atoi(m[1])
+	return n
+}
+
+// YAMLErrorMessage returns the text of a yaml.v3 error without its "yaml:"
+// prefix and line number, which callers report as a position of their own.
+func YAMLErrorMessage(err error) string {
+	return yamlPrefixRe.ReplaceAllString(err.Error(), "")
+}
+
+// YAMLErrorPosition returns the line reported by a yaml.v3 error together with
+// the column of the first non-blank character on that line, since yaml.v3
+// errors do not carry a column. Both are 0 when the line is unknown.
+func YAMLErrorPosition(content string, err error) (int, int) {
+	line := YAMLErrorLine(err)
+	if line == 0 {
+		return 0, 0
+	}
+	lines := strings.Split(content, "\n")
+	if line > len(lines) {
+		return line, 1
+	}
+	text := lines[line-1]
+	return line, utf8.RuneCountInString(text) - utf8.RuneCountInString(strings.TrimLeft(text, " \t")) + 1
+}
+
+// JSONErrorPosition converts the byte offset of an encoding/json syntax or
+// type error into a 1-based line and column. Both are 0 for other errors.
+func JSONErrorPosition(content string, err error) (int, int) {
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		// Offset points just past the byte that caused the error.
+		return OffsetPosition(content, syntaxErr.Offset-1)
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return OffsetPosition(content, typeErr.Offset-1)
+	}
+	return 0, 0
+}
+
+// OffsetPosition converts a byte offset into a 1-based line and column
+// (counted in characters). Offsets past the end map to the last character.
+func OffsetPosition(content string, offset int64) (int, int) {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > int64(len(content)) {
+		offset = int64(len(content))
+	}
+	before := content[:offset]
+	line := strings.Count(before, "\n") + 1
+	lineStart := strings.LastIndex(before, "\n") + 1
+	return line, utf8.RuneCountInString(before[lineStart:]) + 1
+}
+
+// FindPosition returns the line and column of the first occurrence of substr
+// in content, or 0, 0 when it does not occur.
+func FindPosition(content, substr string) (int, int) {
+	idx := strings.Index(content, substr)
+	if idx < 0 {
+		return 0, 0
+	}
+	return OffsetPosition(content, int64(idx))
+}
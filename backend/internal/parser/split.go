@@ -0,0 +1,201 @@
+package parser
+
+import "strings"
+
+// Document is a single YAML document from a multi-document stream.
+type Document struct {
+	// Content is the document's text, a substring of the stream without the
+	// "---" line that introduced it and without a trailing newline. When the
+	// marker line carries content itself, as in "--- |", Content starts with
+	// that line so it still parses on its own.
+	Content string
+	// StartLine is the 1-based line of the stream on which Content begins.
+	StartLine int
+	// Offset is the byte offset in the stream at which Content begins.
+	Offset int
+	// Explicit reports whether the document was introduced by "---".
+	Explicit bool
+	// Ended reports whether the document was closed by "...".
+	Ended bool
+	// Directives holds the %YAML and %TAG lines that preceded the document.
+	Directives []string
+	// Leading holds the comment and blank lines before the document's
+	// directives or "---" marker, and Trailing those after the "..." that
+	// ends the last document, without a final newline. YAML attaches them
+	// to no document; they are kept so that the stream can be reassembled.
+	Leading  string
+	Trailing string
+}
+
+// SplitYAML splits YAML content into its documents; see SplitYAMLDocuments.
+func SplitYAML(content string) []string {
+	docs := SplitYAMLDocuments(content)
+	out := make([]string, len(docs))
+	for i, d := range docs {
+		out[i] = d.Content
+	}
+	return out
+}
+
+// SplitYAMLDocuments splits a YAML stream into its documents and records
+// where each one starts, so positions reported for a single document can be
+// mapped back onto the whole stream.
+//
+// It scans lines instead of running the YAML decoder over the stream. The
+// decoder yields nodes, not the byte offsets, raw text, comments and
+// directives of each document, which reporting and reassembling fixed
+// streams need, and it stops at the first document it rejects, while
+// validation and fixing want every document on its own.
+//
+// A "---" or "..." line is a marker when it starts at column 0 and is
+// followed by a blank or the end of the line, so an indented "---" in a block
+// scalar stays part of its document. YAML forbids markers at the start of a
+// line inside any scalar, so for well-formed streams this is where the
+// decoder splits too; for malformed ones the split is a best effort. "..."
+// ends a document, and directives are only read where the specification
+// allows them, at the start of the stream or after "...". Comments before a
+// marker or directive, such as a header above the first "---", go into the
+// Leading text of the next document rather than forming one of their own,
+// and comments after a final "..." into the Trailing text of the last one.
+// A stream of nothing but comments is one document. Empty documents are
+// omitted.
+func SplitYAMLDocuments(content string) []Document {
+	s := splitter{content: content}
+	// between is set while no document is open: at the start of the stream
+	// and after "...". Only then are "%" lines directives.
+	between := true
+	var directives []string
+	offset := 0
+	for lineNo := 1; offset < len(content); lineNo++ {
+		end := strings.IndexByte(content[offset:], '\n')
+		next := len(content)
+		if end >= 0 {
+			end += offset
+			next = end + 1
+		} else {
+			end = len(content)
+		}
+		line := content[offset:end]
+		if lineNo == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+
+		switch {
+		case isMarker(line, "---"):
+			s.carry()
+			s.flush()
+			s.explicit, s.directives = true, directives
+			directives, between = nil, false
+			if rest := strings.TrimSpace(line[3:]); rest != "" && !strings.HasPrefix(rest, "#") {
+				s.open(offset, lineNo)
+			}
+		case isMarker(line, "..."):
+			s.ended = true
+			s.flush()
+			directives, between = nil, true
+		case between && strings.HasPrefix(line, "%"):
+			// comments already seen belong to no document; with a directive
+			// they introduce the next one
+			s.carry()
+			directives = append(directives, strings.TrimRight(line, " \t\r"))
+		default:
+			trimmed := strings.TrimSpace(line)
+			if between && (trimmed == "" || strings.HasPrefix(trimmed, "#")) && len(directives) > 0 {
+				break
+			}
+			if between && trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+				// a bare document
+				between = false
+				s.directives, directives = directives, nil
+			}
+			s.open(offset, lineNo)
+		}
+		offset = next
+	}
+	if len(s.docs) > 0 {
+		s.carry()
+	}
+	s.flush()
+	if s.leading != "" && len(s.docs) > 0 {
+		s.docs[len(s.docs)-1].Trailing = s.leading
+	}
+	return s.docs
+}
+
+// splitter accumulates the document being read by SplitYAMLDocuments.
+type splitter struct {
+	content    string
+	docs       []Document
+	started    bool
+	start      int
+	line       int
+	end        int
+	explicit   bool
+	ended      bool
+	directives []string
+	// leading holds the comments carried over to the next document
+	leading string
+}
+
+// open starts the current document's content at the given line unless it
+// has started already.
+func (s *splitter) open(offset, line int) {
+	if !s.started {
+		s.started, s.start, s.line = true, offset, line
+	}
+	s.end = strings.IndexByte(s.content[offset:], '\n')
+	if s.end < 0 {
+		s.end = len(s.content)
+	} else {
+		s.end += offset
+	}
+}
+
+// flush ends the current document, keeping it unless it has no content.
+func (s *splitter) flush() {
+	if s.started {
+		text := strings.TrimSuffix(s.content[s.start:s.end], "\r")
+		if strings.TrimSpace(text) != "" {
+			s.docs = append(s.docs, Document{Content: text, StartLine: s.line, Offset: s.start, Explicit: s.explicit, Ended: s.ended, Directives: s.directives, Leading: s.leading})
+			s.leading = ""
+		}
+	}
+	s.drop()
+}
+
+// carry moves the current document into the leading comments of the next
+// one when it holds nothing but comments and was not introduced by "---".
+// Blank lines alone are dropped, as flush drops empty documents.
+func (s *splitter) carry() {
+	if !s.started || s.explicit {
+		return
+	}
+	text := strings.TrimSuffix(s.content[s.start:s.end], "\r")
+	for _, line := range strings.Split(text, "\n") {
+		if trimmed := strings.TrimSpace(line); trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			return
+		}
+	}
+	if strings.TrimSpace(text) != "" {
+		if s.leading != "" {
+			s.leading += "\n"
+		}
+		s.leading += text
+	}
+	s.drop()
+}
+
+// drop discards the current document.
+func (s *splitter) drop() {
+	s.started, s.explicit, s.ended, s.directives = false, false, false, nil
+}
+
+// isMarker reports whether line is the document marker m ("---" or "...")
+// followed by a blank or the end of the line.
+func isMarker(line, m string) bool {
+	if !strings.HasPrefix(line, m) {
+		return false
+	}
+	rest := line[len(m):]
+	return rest == "" || rest[0] == ' ' || rest[0] == '\t' || rest[0] == '\r'
+}
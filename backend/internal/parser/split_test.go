@@ -0,0 +1,96 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitYAMLDocuments(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []Document
+	}{
+		{
+			name:    "single",
+			content: "a: 1\nb: 2\n",
+			want:    []Document{{Content: "a: 1\nb: 2", StartLine: 1}},
+		},
+		{
+			name:    "markers",
+			content: "a: 1\n---\nb: 2\n---\nc: 3\n",
+			want: []Document{
+				{Content: "a: 1", StartLine: 1},
+				{Content: "b: 2", StartLine: 3, Offset: 9, Explicit: true},
+				{Content: "c: 3", StartLine: 5, Offset: 18, Explicit: true},
+			},
+		},
+		{
+			name:    "empty documents",
+			content: "---\n---\na: 1\n",
+			want:    []Document{{Content: "a: 1", StartLine: 3, Offset: 8, Explicit: true}},
+		},
+		{
+			name:    "indented marker in block scalar",
+			content: "a: |\n  ---\n  x\n",
+			want:    []Document{{Content: "a: |\n  ---\n  x", StartLine: 1}},
+		},
+		{
+			name:    "content on marker line",
+			content: "--- |\n  text\n",
+			want:    []Document{{Content: "--- |\n  text", StartLine: 1, Explicit: true}},
+		},
+		{
+			name:    "directives and end marker",
+			content: "%YAML 1.2\n---\na: 1\n...\n%YAML 1.2\n---\nb: 2\n",
+			want: []Document{
+				{Content: "a: 1", StartLine: 3, Offset: 14, Explicit: true, Ended: true, Directives: []string{"%YAML 1.2"}},
+				{Content: "b: 2", StartLine: 7, Offset: 37, Explicit: true, Directives: []string{"%YAML 1.2"}},
+			},
+		},
+		{
+			name:    "leading comments",
+			content: "# header\n\n# more\n---\na: 1\n",
+			want:    []Document{{Content: "a: 1", StartLine: 5, Offset: 21, Explicit: true, Leading: "# header\n\n# more"}},
+		},
+		{
+			name:    "comments between documents",
+			content: "a: 1\n...\n# next\n---\nb: 2\n",
+			want: []Document{
+				{Content: "a: 1", StartLine: 1, Ended: true},
+				{Content: "b: 2", StartLine: 5, Offset: 20, Explicit: true, Leading: "# next"},
+			},
+		},
+		{
+			name:    "trailing comments",
+			content: "a: 1\n...\n# end\n",
+			want:    []Document{{Content: "a: 1", StartLine: 1, Ended: true, Trailing: "# end"}},
+		},
+		{
+			name:    "comments only",
+			content: "# just a comment\n",
+			want:    []Document{{Content: "# just a comment", StartLine: 1}},
+		},
+		{
+			name:    "comment in explicit document",
+			content: "---\n# about a\na: 1\n",
+			want:    []Document{{Content: "# about a\na: 1", StartLine: 2, Offset: 4, Explicit: true}},
+		},
+		{
+			name:    "crlf",
+			content: "a: 1\r\n---\r\nb: 2\r\n",
+			want: []Document{
+				{Content: "a: 1", StartLine: 1},
+				{Content: "b: 2", StartLine: 3, Offset: 11, Explicit: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SplitYAMLDocuments(tt.content)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitYAMLDocuments() =\n%#v\nwant:\n%#v", got, tt.want)
+			}
+		})
+	}
+}
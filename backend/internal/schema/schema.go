@@ -0,0 +1,183 @@
+package schema
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Schema is a compiled JSON Schema (draft 2020-12) document. References are
+// resolved within the same document only ("#", "#/json/pointer", "#anchor").
+type Schema struct {
+	root    any
+	anchors map[string]any
+	regexps map[string]*regexp.Regexp
+}
+
+// Violation describes a single place where an instance does not satisfy the schema.
+type Violation struct {
+	// Path is the JSON pointer of the offending value inside the instance.
+	Path    string
+	Keyword string
+	Message string
+	Line    int
+	Column  int
+}
+
+// Compile parses a schema written as JSON or YAML and prepares it for validation.
+func Compile(data []byte) (*Schema, error) {
+	var root any
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("schema is neither valid JSON nor YAML: %w", err)
+	}
+	return New(root)
+}
+
+// New prepares an already decoded schema document (map[string]any or bool)
+// for validation.
+func New(root any) (*Schema, error) {
+	switch root.(type) {
+	case map[string]any, bool:
+	default:
+		return nil, fmt.Errorf("schema must be an object or a boolean")
+	}
+	s := &Schema{root: root, anchors: map[string]any{}, regexps: map[string]*regexp.Regexp{}}
+	if err := s.index(root); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// MustCompile is like Compile but panics on error. It is meant for schemas
+// embedded in the binary.
+func MustCompile(data []byte) *Schema {
+	s, err := Compile(data)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// subschemaMaps lists keywords whose value is a map of name -> schema.
+var subschemaMaps = []string{"properties", "patternProperties", "$defs", "definitions", "dependentSchemas"}
+
+// subschemaValues lists keywords whose value is a single schema.
+var subschemaValues = []string{"additionalProperties", "additionalItems", "items", "contains", "propertyNames", "not", "if", "then", "else", "unevaluatedProperties", "unevaluatedItems"}
+
+// subschemaLists lists keywords whose value is an array of schemas.
+var subschemaLists = []string{"allOf", "anyOf", "oneOf", "prefixItems", "items"}
+
+// index walks every subschema once to record anchors and compile patterns so
+// that invalid regular expressions are reported at compile time.
+func (s *Schema) index(node any) error {
+	m, ok := node.(map[string]any)
+	if !ok {
+		return nil
+	}
+	if a, ok := m["$anchor"].(string); ok && a != "" {
+		s.anchors[a] = m
+	}
+	if p, ok := m["pattern"].(string); ok {
+		if err := s.compilePattern(p); err != nil {
+			return err
+		}
+	}
+	if pp, ok := m["patternProperties"].(map[string]any); ok {
+		for p := range pp {
+			if err := s.compilePattern(p); err != nil {
+				return err
+			}
+		}
+	}
+	for _, k := range subschemaMaps {
+		if sub, ok := m[k].(map[string]any); ok {
+			for _, v := range sub {
+				if err := s.index(v); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	for _, k := range subschemaValues {
+		if err := s.index(m[k]); err != nil {
+			return err
+		}
+	}
+	for _, k := range subschemaLists {
+		if list, ok := m[k].([]any); ok {
+			for _, v := range list {
+				if err := s.index(v); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Schema) compilePattern(p string) error {
+	if _, ok := s.regexps[p]; ok {
+		return nil
+	}
+	re, err := regexp.Compile(p)
+	if err != nil {
+		return fmt.Errorf("invalid pattern %q: %w", p, err)
+	}
+	s.regexps[p] = re
+	return nil
+}
+
+// resolveRef looks up a reference inside the schema document.
+func (s *Schema) resolveRef(ref string) (any, error) {
+	if ref == "#" || ref == "" {
+		return s.root, nil
+	}
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("unsupported $ref %q: only references within the document are supported", ref)
+	}
+	frag := ref[1:]
+	if !strings.HasPrefix(frag, "/") {
+		if a, ok := s.anchors[frag]; ok {
+			return a, nil
+		}
+		return nil, fmt.Errorf("unknown anchor in $ref %q", ref)
+	}
+	cur := s.root
+	for _, tok := range strings.Split(frag[1:], "/") {
+		tok = unescapePointer(tok)
+		switch v := cur.(type) {
+		case map[string]any:
+			next, ok := v[tok]
+			if !ok {
+				return nil, fmt.Errorf("unresolvable $ref %q", ref)
+			}
+			cur = next
+		case []any:
+			var i int
+			if _, err := fmt.Sscanf(tok, "%d", &i); err != nil || i < 0 || i >= len(v) {
+				return nil, fmt.Errorf("unresolvable $ref %q", ref)
+			}
+			cur = v[i]
+		default:
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+	}
+	return cur, nil
+}
+
+// escapePointer escapes a single JSON pointer reference token.
+func escapePointer(tok string) string {
+	tok = strings.ReplaceAll(tok, "~", "~0")
+	return strings.ReplaceAll(tok, "/", "~1")
+}
+
+func unescapePointer(tok string) string {
+	if u, err := url.PathUnescape(tok); err == nil {
+		tok = u
+	}
+	tok = strings.ReplaceAll(tok, "~1", "/")
+	return strings.ReplaceAll(tok, "~0", "~")
+}
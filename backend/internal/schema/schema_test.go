@@ -0,0 +1,114 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const testSchema = `{
+  "$defs": {
+    "port": {"type": "integer", "minimum": 1, "maximum": 65535}
+  },
+  "type": "object",
+  "required": ["name"],
+  "properties": {
+    "name": {"type": "string", "pattern": "^[a-z]+$"},
+    "mode": {"enum": ["fast", "slow"]},
+    "port": {"$ref": "#/$defs/port"},
+    "tags": {"type": "array", "items": {"type": "string"}, "minItems": 1},
+    "target": {
+      "oneOf": [
+        {"type": "string"},
+        {"type": "object", "required": ["host"]}
+      ]
+    },
+    "size": {"anyOf": [{"type": "integer"}, {"type": "string", "pattern": "^[0-9]+Gi$"}]}
+  },
+  "additionalProperties": false
+}`
+
+func TestValidate(t *testing.T) {
+	s, err := Compile([]byte(testSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		instance string
+		// want lists "path keyword line:column" of every violation; values
+		// of a mapping are reported at their key
+		want []string
+	}{
+		{"valid", "name: web\nmode: fast\nport: 80\ntags: [a]\ntarget: {host: x}\nsize: 10Gi\n", nil},
+		{"required", "mode: fast\n", []string{" required 1:1"}},
+		{"type", "name: web\nport: http\n", []string{"/port type 2:1"}},
+		{"pattern", "name: Web\n", []string{"/name pattern 1:1"}},
+		{"enum", "name: web\nmode: medium\n", []string{"/mode enum 2:1"}},
+		{"ref", "name: web\nport: 70000\n", []string{"/port maximum 2:1"}},
+		{"items", "name: web\ntags:\n  - a\n  - 2\n", []string{"/tags/1 type 4:5"}},
+		{"minItems", "name: web\ntags: []\n", []string{"/tags minItems 2:1"}},
+		{"oneOf", "name: web\ntarget: {port: 1}\n", []string{"/target oneOf 2:1"}},
+		{"anyOf", "name: web\nsize: big\n", []string{"/size anyOf 2:1"}},
+		{"additionalProperties", "name: web\nextra: 1\n", []string{"/extra additionalProperties 2:1"}},
+		{"null document", "", []string{" type 0:0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var node yaml.Node
+			if err := yaml.Unmarshal([]byte(tt.instance), &node); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, v := range s.Validate(&node) {
+				got = append(got, fmt.Sprintf("%s %s %d:%d", v.Path, v.Keyword, v.Line, v.Column))
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("violations:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  string
+		wantErr bool
+	}{
+		{"json", `{"type": "string"}`, false},
+		{"yaml", "type: string\nminLength: 1\n", false},
+		{"boolean", "true", false},
+		{"array", "[1, 2]", true},
+		{"malformed", "{type: [", true},
+		{"bad pattern", `{"pattern": "("}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compile([]byte(tt.schema))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Compile() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateRef(t *testing.T) {
+	s := MustCompile([]byte(testSchema))
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte("0"), &node); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.ValidateRef("#/$defs/port", &node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Keyword != "minimum" {
+		t.Errorf("ValidateRef() = %+v, want one minimum violation", got)
+	}
+	if _, err := s.ValidateRef("#/$defs/missing", &node); err == nil {
+		t.Error("ValidateRef() of a missing definition succeeded")
+	}
+}
@@ -0,0 +1,624 @@
+package schema
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxDepth bounds $ref recursion so self-referencing schemas cannot loop forever.
+const maxDepth = 256
+
+// Validate checks a parsed YAML/JSON node against the schema and returns every
+// violation found. A DocumentNode is unwrapped; a nil node is treated as null.
+func (s *Schema) Validate(node *yaml.Node) []Violation {
+	return s.validate(s.root, resolve(unwrapDocument(node)), "", 0)
+}
+
+// ValidateRef validates node against the subschema that ref points to, for
+// example "#/definitions/io.k8s.api.apps.v1.Deployment".
+func (s *Schema) ValidateRef(ref string, node *yaml.Node) ([]Violation, error) {
+	target, err := s.resolveRef(ref)
+	if err != nil {
+		return nil, err
+	}
+	return s.validate(target, resolve(unwrapDocument(node)), "", 0), nil
+}
+
+func unwrapDocument(node *yaml.Node) *yaml.Node {
+	if node != nil && node.Kind == yaml.DocumentNode {
+		if len(node.Content) == 0 {
+			return nil
+		}
+		return node.Content[0]
+	}
+	return node
+}
+
+func (s *Schema) validate(sch any, n *yaml.Node, ptr string, depth int) []Violation {
+	if depth > maxDepth {
+		return []Violation{violation(n, ptr, "$ref", "schema recursion limit exceeded")}
+	}
+	switch v := sch.(type) {
+	case bool:
+		if v {
+			return nil
+		}
+		return []Violation{violation(n, ptr, "false", "no value is allowed here")}
+	case map[string]any:
+		return s.validateObject(v, n, ptr, depth)
+	}
+	return nil
+}
+
+func (s *Schema) validateObject(m map[string]any, n *yaml.Node, ptr string, depth int) []Violation {
+	var out []Violation
+
+	if ref, ok := m["$ref"].(string); ok {
+		target, err := s.resolveRef(ref)
+		if err != nil {
+			out = append(out, violation(n, ptr, "$ref", err.Error()))
+		} else {
+			out = append(out, s.validate(target, n, ptr, depth+1)...)
+		}
+	}
+
+	got := typeOf(n)
+
+	if t, ok := m["type"]; ok {
+		if !typeMatches(t, n, got) {
+			out = append(out, violation(n, ptr, "type", fmt.Sprintf("expected %s, got %s", describeType(t), got)))
+			// further type-specific keywords would only add noise
+			return out
+		}
+	}
+
+	if enum, ok := m["enum"].([]any); ok {
+		val := toValue(n)
+		found := false
+		for _, e := range enum {
+			if reflect.DeepEqual(normalize(e), val) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			out = append(out, violation(n, ptr, "enum", fmt.Sprintf("value must be one of %s", formatList(enum))))
+		}
+	}
+	if c, ok := m["const"]; ok {
+		if !reflect.DeepEqual(normalize(c), toValue(n)) {
+			out = append(out, violation(n, ptr, "const", fmt.Sprintf("value must be %s", formatValue(c))))
+		}
+	}
+
+	switch got {
+	case "integer", "number":
+		out = append(out, s.validateNumber(m, n, ptr)...)
+	case "string":
+		out = append(out, s.validateString(m, n, ptr)...)
+	case "array":
+		out = append(out, s.validateArray(m, n, ptr, depth)...)
+	case "object":
+		out = append(out, s.validateMapping(m, n, ptr, depth)...)
+	}
+
+	if all, ok := m["allOf"].([]any); ok {
+		for _, sub := range all {
+			out = append(out, s.validate(sub, n, ptr, depth+1)...)
+		}
+	}
+	if anyOf, ok := m["anyOf"].([]any); ok {
+		matched := false
+		for _, sub := range anyOf {
+			if len(s.validate(sub, n, ptr, depth+1)) == 0 {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			out = append(out, violation(n, ptr, "anyOf", "value does not match any of the allowed schemas"))
+		}
+	}
+	if oneOf, ok := m["oneOf"].([]any); ok {
+		matches := 0
+		for _, sub := range oneOf {
+			if len(s.validate(sub, n, ptr, depth+1)) == 0 {
+				matches++
+			}
+		}
+		if matches == 0 {
+			out = append(out, violation(n, ptr, "oneOf", "value does not match any of the allowed schemas"))
+		} else if matches > 1 {
+			out = append(out, violation(n, ptr, "oneOf", fmt.Sprintf("value matches %d schemas but exactly one is allowed", matches)))
+		}
+	}
+	if not, ok := m["not"]; ok {
+		if len(s.validate(not, n, ptr, depth+1)) == 0 {
+			out = append(out, violation(n, ptr, "not", "value must not match the schema in \"not\""))
+		}
+	}
+	if cond, ok := m["if"]; ok {
+		if len(s.validate(cond, n, ptr, depth+1)) == 0 {
+			if then, ok := m["then"]; ok {
+				out = append(out, s.validate(then, n, ptr, depth+1)...)
+			}
+		} else if els, ok := m["else"]; ok {
+			out = append(out, s.validate(els, n, ptr, depth+1)...)
+		}
+	}
+	return out
+}
+
+func (s *Schema) validateNumber(m map[string]any, n *yaml.Node, ptr string) []Violation {
+	var out []Violation
+	f, ok := numberValue(n)
+	if !ok {
+		return nil
+	}
+	if mo, ok := toFloat(m["multipleOf"]); ok && mo > 0 {
+		q := f / mo
+		if math.Abs(q-math.Round(q)) > 1e-9 {
+			out = append(out, violation(n, ptr, "multipleOf", fmt.Sprintf("value must be a multiple of %v", mo)))
+		}
+	}
+	if max, ok := toFloat(m["maximum"]); ok {
+		// draft-04 style boolean exclusiveMaximum
+		if excl, _ := m["exclusiveMaximum"].(bool); excl && f >= max {
+			out = append(out, violation(n, ptr, "maximum", fmt.Sprintf("value must be less than %v", max)))
+		} else if f > max {
+			out = append(out, violation(n, ptr, "maximum", fmt.Sprintf("value must be at most %v", max)))
+		}
+	}
+	if max, ok := toFloat(m["exclusiveMaximum"]); ok && f >= max {
+		out = append(out, violation(n, ptr, "exclusiveMaximum", fmt.Sprintf("value must be less than %v", max)))
+	}
+	if min, ok := toFloat(m["minimum"]); ok {
+		if excl, _ := m["exclusiveMinimum"].(bool); excl && f <= min {
+			out = append(out, violation(n, ptr, "minimum", fmt.Sprintf("value must be greater than %v", min)))
+		} else if f < min {
+			out = append(out, violation(n, ptr, "minimum", fmt.Sprintf("value must be at least %v", min)))
+		}
+	}
+	if min, ok := toFloat(m["exclusiveMinimum"]); ok && f <= min {
+		out = append(out, violation(n, ptr, "exclusiveMinimum", fmt.Sprintf("value must be greater than %v", min)))
+	}
+	return out
+}
+
+func (s *Schema) validateString(m map[string]any, n *yaml.Node, ptr string) []Violation {
+	var out []Violation
+	length := utf8.RuneCountInString(n.Value)
+	if max, ok := toInt(m["maxLength"]); ok && length > max {
+		out = append(out, violation(n, ptr, "maxLength", fmt.Sprintf("string is longer than %d characters", max)))
+	}
+	if min, ok := toInt(m["minLength"]); ok && length < min {
+		out = append(out, violation(n, ptr, "minLength", fmt.Sprintf("string is shorter than %d characters", min)))
+	}
+	if p, ok := m["pattern"].(string); ok {
+		if re := s.regexps[p]; re != nil && !re.MatchString(n.Value) {
+			out = append(out, violation(n, ptr, "pattern", fmt.Sprintf("string does not match pattern %q", p)))
+		}
+	}
+	return out
+}
+
+func (s *Schema) validateArray(m map[string]any, n *yaml.Node, ptr string, depth int) []Violation {
+	var out []Violation
+	items := n.Content
+
+	if max, ok := toInt(m["maxItems"]); ok && len(items) > max {
+		out = append(out, violation(n, ptr, "maxItems", fmt.Sprintf("array has more than %d items", max)))
+	}
+	if min, ok := toInt(m["minItems"]); ok && len(items) < min {
+		out = append(out, violation(n, ptr, "minItems", fmt.Sprintf("array has fewer than %d items", min)))
+	}
+	if unique, _ := m["uniqueItems"].(bool); unique {
+		for i := 0; i < len(items); i++ {
+			for j := 0; j < i; j++ {
+				if reflect.DeepEqual(toValue(items[i]), toValue(items[j])) {
+					out = append(out, violation(resolve(items[i]), itemPointer(ptr, i), "uniqueItems", fmt.Sprintf("duplicate of item %d", j)))
+					break
+				}
+			}
+		}
+	}
+
+	// prefixItems (2020-12) or array-form items (older drafts) validate positionally
+	prefix, _ := m["prefixItems"].([]any)
+	rest, hasRest := m["items"]
+	if tuple, ok := rest.([]any); ok {
+		prefix = tuple
+		rest, hasRest = m["additionalItems"]
+	}
+	for i, item := range items {
+		if i < len(prefix) {
+			out = append(out, s.validate(prefix[i], resolve(item), itemPointer(ptr, i), depth+1)...)
+		} else if hasRest {
+			out = append(out, s.validate(rest, resolve(item), itemPointer(ptr, i), depth+1)...)
+		}
+	}
+
+	if contains, ok := m["contains"]; ok {
+		count := 0
+		for i, item := range items {
+			if len(s.validate(contains, resolve(item), itemPointer(ptr, i), depth+1)) == 0 {
+				count++
+			}
+		}
+		min := 1
+		if v, ok := toInt(m["minContains"]); ok {
+			min = v
+		}
+		if count < min {
+			out = append(out, violation(n, ptr, "contains", fmt.Sprintf("array must contain at least %d matching item(s)", min)))
+		}
+		if max, ok := toInt(m["maxContains"]); ok && count > max {
+			out = append(out, violation(n, ptr, "maxContains", fmt.Sprintf("array must contain at most %d matching item(s)", max)))
+		}
+	}
+	return out
+}
+
+func (s *Schema) validateMapping(m map[string]any, n *yaml.Node, ptr string, depth int) []Violation {
+	var out []Violation
+	members := Members(n)
+	present := map[string]bool{}
+	for _, mem := range members {
+		present[mem.Name] = true
+	}
+
+	if max, ok := toInt(m["maxProperties"]); ok && len(members) > max {
+		out = append(out, violation(n, ptr, "maxProperties", fmt.Sprintf("object has more than %d properties", max)))
+	}
+	if min, ok := toInt(m["minProperties"]); ok && len(members) < min {
+		out = append(out, violation(n, ptr, "minProperties", fmt.Sprintf("object has fewer than %d properties", min)))
+	}
+	if req, ok := m["required"].([]any); ok {
+		for _, r := range req {
+			if name, ok := r.(string); ok && !present[name] {
+				out = append(out, violation(n, ptr, "required", fmt.Sprintf("missing required property %q", name)))
+			}
+		}
+	}
+	if deps, ok := m["dependentRequired"].(map[string]any); ok {
+		out = append(out, dependentRequired(deps, n, ptr, present)...)
+	}
+	if deps, ok := m["dependencies"].(map[string]any); ok {
+		// draft-07 combined form: array -> dependentRequired, schema -> dependentSchemas
+		arrays := map[string]any{}
+		for k, v := range deps {
+			if _, isList := v.([]any); isList {
+				arrays[k] = v
+			} else if present[k] {
+				out = append(out, s.validate(v, n, ptr, depth+1)...)
+			}
+		}
+		out = append(out, dependentRequired(arrays, n, ptr, present)...)
+	}
+	if deps, ok := m["dependentSchemas"].(map[string]any); ok {
+		for k, sub := range deps {
+			if present[k] {
+				out = append(out, s.validate(sub, n, ptr, depth+1)...)
+			}
+		}
+	}
+
+	props, _ := m["properties"].(map[string]any)
+	patterns, _ := m["patternProperties"].(map[string]any)
+	additional, hasAdditional := m["additionalProperties"]
+	names, hasNames := m["propertyNames"]
+
+	for _, mem := range members {
+		childPtr := ptr + "/" + escapePointer(mem.Name)
+		if hasNames {
+			keyNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: mem.Name, Line: mem.Key.Line, Column: mem.Key.Column}
+			for _, v := range s.validate(names, keyNode, childPtr, depth+1) {
+				v.Message = fmt.Sprintf("invalid property name %q: %s", mem.Name, v.Message)
+				out = append(out, v)
+			}
+		}
+		matched := false
+		if sub, ok := props[mem.Name]; ok {
+			matched = true
+			out = append(out, atKey(s.validate(sub, mem.Value, childPtr, depth+1), childPtr, mem.Key)...)
+		}
+		for p, sub := range patterns {
+			if re := s.regexps[p]; re != nil && re.MatchString(mem.Name) {
+				matched = true
+				out = append(out, atKey(s.validate(sub, mem.Value, childPtr, depth+1), childPtr, mem.Key)...)
+			}
+		}
+		if !matched && hasAdditional {
+			if b, ok := additional.(bool); ok && !b {
+				out = append(out, violation(mem.Key, childPtr, "additionalProperties", fmt.Sprintf("property %q is not allowed", mem.Name)))
+			} else {
+				out = append(out, atKey(s.validate(additional, mem.Value, childPtr, depth+1), childPtr, mem.Key)...)
+			}
+		}
+	}
+	return out
+}
+
+func dependentRequired(deps map[string]any, n *yaml.Node, ptr string, present map[string]bool) []Violation {
+	var out []Violation
+	keys := make([]string, 0, len(deps))
+	for k := range deps {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if !present[k] {
+			continue
+		}
+		list, _ := deps[k].([]any)
+		for _, r := range list {
+			if name, ok := r.(string); ok && !present[name] {
+				out = append(out, violation(n, ptr, "dependentRequired", fmt.Sprintf("property %q is required when %q is present", name, k)))
+			}
+		}
+	}
+	return out
+}
+
+// Member is a single key/value pair of a mapping node after merge keys (<<)
+// have been expanded.
+type Member struct {
+	Name  string
+	Key   *yaml.Node
+	Value *yaml.Node
+}
+
+// Members returns the key/value pairs of a mapping node in document order.
+// Aliases are resolved and YAML merge keys are expanded, with explicit keys
+// taking precedence over merged ones.
+func Members(n *yaml.Node) []Member {
+	n = resolve(n)
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	var own, merged []Member
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := resolve(n.Content[i]), resolve(n.Content[i+1])
+		if k.Kind == yaml.ScalarNode && k.ShortTag() == "!!merge" {
+			if v.Kind == yaml.SequenceNode {
+				for _, item := range v.Content {
+					merged = append(merged, Members(item)...)
+				}
+			} else {
+				merged = append(merged, Members(v)...)
+			}
+			continue
+		}
+		own = append(own, Member{Name: k.Value, Key: n.Content[i], Value: v})
+	}
+	if len(merged) == 0 {
+		return own
+	}
+	seen := map[string]bool{}
+	for _, m := range own {
+		seen[m.Name] = true
+	}
+	for _, m := range merged {
+		if !seen[m.Name] {
+			seen[m.Name] = true
+			own = append(own, m)
+		}
+	}
+	return own
+}
+
+// atKey moves violations reported for a mapping member itself onto the
+// member's key, so "replicas: abc" is reported where "replicas" starts.
+// Violations for nested values keep their own positions.
+func atKey(vs []Violation, ptr string, key *yaml.Node) []Violation {
+	for i := range vs {
+		if vs[i].Path == ptr {
+			vs[i].Line, vs[i].Column = key.Line, key.Column
+		}
+	}
+	return vs
+}
+
+func violation(n *yaml.Node, ptr, keyword, msg string) Violation {
+	v := Violation{Path: ptr, Keyword: keyword, Message: msg}
+	if n != nil {
+		v.Line, v.Column = n.Line, n.Column
+	}
+	return v
+}
+
+func itemPointer(ptr string, i int) string {
+	return ptr + "/" + strconv.Itoa(i)
+}
+
+func resolve(n *yaml.Node) *yaml.Node {
+	for n != nil && n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	return n
+}
+
+// typeOf returns the JSON Schema type name of a node.
+func typeOf(n *yaml.Node) string {
+	n = resolve(n)
+	if n == nil {
+		return "null"
+	}
+	switch n.Kind {
+	case yaml.MappingNode:
+		return "object"
+	case yaml.SequenceNode:
+		return "array"
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return "null"
+		}
+		return typeOf(n.Content[0])
+	}
+	switch n.ShortTag() {
+	case "!!null":
+		return "null"
+	case "!!bool":
+		return "boolean"
+	case "!!int":
+		return "integer"
+	case "!!float":
+		return "number"
+	}
+	return "string"
+}
+
+func typeMatches(t any, n *yaml.Node, got string) bool {
+	var want []string
+	switch v := t.(type) {
+	case string:
+		want = []string{v}
+	case []any:
+		for _, x := range v {
+			if s, ok := x.(string); ok {
+				want = append(want, s)
+			}
+		}
+	default:
+		return true
+	}
+	for _, w := range want {
+		if w == got || (w == "number" && got == "integer") {
+			return true
+		}
+		if w == "integer" && got == "number" {
+			if f, ok := numberValue(n); ok && f == math.Trunc(f) && !math.IsInf(f, 0) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func describeType(t any) string {
+	if list, ok := t.([]any); ok {
+		parts := make([]string, 0, len(list))
+		for _, x := range list {
+			parts = append(parts, fmt.Sprint(x))
+		}
+		return strings.Join(parts, " or ")
+	}
+	return fmt.Sprint(t)
+}
+
+// numberValue parses an integer or float scalar using YAML 1.2 rules.
+func numberValue(n *yaml.Node) (float64, bool) {
+	n = resolve(n)
+	if n == nil || n.Kind != yaml.ScalarNode {
+		return 0, false
+	}
+	var f float64
+	if err := n.Decode(&f); err != nil {
+		return 0, false
+	}
+	return f, true
+}
+
+// toValue converts a node into plain Go values (map[string]any, []any,
+// float64, bool, string, nil) suitable for equality comparison.
+func toValue(n *yaml.Node) any {
+	n = resolve(n)
+	if n == nil {
+		return nil
+	}
+	switch n.Kind {
+	case yaml.MappingNode:
+		out := map[string]any{}
+		for _, m := range Members(n) {
+			out[m.Name] = toValue(m.Value)
+		}
+		return out
+	case yaml.SequenceNode:
+		out := make([]any, 0, len(n.Content))
+		for _, c := range n.Content {
+			out = append(out, toValue(c))
+		}
+		return out
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return nil
+		}
+		return toValue(n.Content[0])
+	}
+	switch typeOf(n) {
+	case "null":
+		return nil
+	case "boolean":
+		var b bool
+		_ = n.Decode(&b)
+		return b
+	case "integer", "number":
+		f, _ := numberValue(n)
+		return f
+	}
+	return n.Value
+}
+
+// normalize converts values decoded from the schema document into the same
+// shape produced by toValue.
+func normalize(v any) any {
+	switch x := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(x))
+		for k, e := range x {
+			out[k] = normalize(e)
+		}
+		return out
+	case []any:
+		out := make([]any, len(x))
+		for i, e := range x {
+			out[i] = normalize(e)
+		}
+		return out
+	}
+	if f, ok := toFloat(v); ok {
+		return f
+	}
+	return v
+}
+
+func toFloat(v any) (float64, bool) {
+	switch x := v.(type) {
+	case int:
+		return float64(x), true
+	case int64:
+		return float64(x), true
+	case uint64:
+		return float64(x), true
+	case float64:
+		return x, true
+	}
+	return 0, false
+}
+
+func toInt(v any) (int, bool) {
+	f, ok := toFloat(v)
+	return int(f), ok
+}
+
+func formatValue(v any) string {
+	if s, ok := v.(string); ok {
+		return strconv.Quote(s)
+	}
+	return fmt.Sprint(v)
+}
+
+func formatList(list []any) string {
+	parts := make([]string, 0, len(list))
+	for _, v := range list {
+		parts = append(parts, formatValue(v))
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
@@ -7,13 +7,14 @@ import (
 	"strings"
 
 	"devformat/backend/internal/parser"
+	"devformat/backend/internal/types"
 
 	"gopkg.in/yaml.v3"
 )
 
 // SuggestYAML returns a list of suggested small fixes (snippets) for a YAML document when auto-fix fails
-func SuggestYAML(content string, parseErr error) ([]map[string]any, error) {
-	suggestions := []map[string]any{}
+func SuggestYAML(content string, parseErr error) ([]types.Suggestion, error) {
+	suggestions := []types.Suggestion{}
 	msg := parseErr.Error()
 	if !(strings.Contains(msg, "did not find expected key") || strings.Contains(msg, "mapping values are not allowed in this context") || strings.Contains(msg, "did not find expected '-' indicator")) {
 		return suggestions, nil
@@ -113,22 +114,9 @@ func SuggestYAML(content string, parseErr error) ([]map[string]any, error) {
 				j++
 			}
 			if changed {
-				snippetLines := []string{}
-				for i := idx; i < j; i++ {
-					line := modified[i]
-					if strings.TrimSpace(line) == "paths:" {
-						continue
-					}
-					snippetLines = append(snippetLines, line)
-				}
-				snippet := strings.Join(snippetLines, "\n")
-				suggestions = append(suggestions, map[string]any{
-					"shortDescription": "Align mapping fields under `backend` list item",
-					"confidence":       "high",
-					"fixedSnippet":     snippet,
-					"startLine":        idx + 1,
-					"endLine":          j,
-				})
+				// the snippet must cover startLine..endLine exactly so it can be spliced back
+				snippet := strings.Join(modified[idx:j], "\n")
+				suggestions = append(suggestions, types.NewSuggestion(types.SourceHeuristic, "Align mapping fields under `backend` list item", types.ConfidenceHigh, idx+1, j, snippet))
 				return suggestions, nil
 			}
 		}
@@ -156,13 +144,7 @@ func SuggestYAML(content string, parseErr error) ([]map[string]any, error) {
 					end = len(linesCopy) - 1
 				}
 				snippet := strings.Join(linesCopy[start:end+1], "\n")
-				suggestions = append(suggestions, map[string]any{
-					"shortDescription": fmt.Sprintf("Align indent at line %d", idx+1),
-					"confidence":       "high",
-					"fixedSnippet":     snippet,
-					"startLine":        start + 1,
-					"endLine":          end + 1,
-				})
+				suggestions = append(suggestions, types.NewSuggestion(types.SourceHeuristic, fmt.Sprintf("Align indent at line %d", idx+1), types.ConfidenceHigh, start+1, end+1, snippet))
 				return suggestions, nil
 			}
 		}
@@ -204,13 +186,7 @@ func SuggestYAML(content string, parseErr error) ([]map[string]any, error) {
 						end = len(modified) - 1
 					}
 					snippet := strings.Join(modified[start:end+1], "\n")
-					suggestions = append(suggestions, map[string]any{
-						"shortDescription": "Align mapping fields under `backend`",
-						"confidence":       "medium",
-						"fixedSnippet":     snippet,
-						"startLine":        start + 1,
-						"endLine":          end + 1,
-					})
+					suggestions = append(suggestions, types.NewSuggestion(types.SourceHeuristic, "Align mapping fields under `backend`", types.ConfidenceMedium, start+1, end+1, snippet))
 					return suggestions, nil
 				}
 			}
@@ -222,9 +198,9 @@ func SuggestYAML(content string, parseErr error) ([]map[string]any, error) {
 
 // DetectBackendMisindent inspects a YAML document for a common Ingress/service backend
 // misindent case and returns a conservative suggested snippet when found.
-func DetectBackendMisindent(content string) []map[string]any {
+func DetectBackendMisindent(content string) []types.Suggestion {
 	lines := strings.Split(parser.PreprocessYAML(content), "\n")
-	suggestions := []map[string]any{}
+	suggestions := []types.Suggestion{}
 
 	for idx, ln := range lines {
 		if strings.Contains(strings.TrimSpace(ln), "backend:") {
@@ -262,13 +238,7 @@ func DetectBackendMisindent(content string) []map[string]any {
 					end = len(modified) - 1
 				}
 				snippet := strings.Join(modified[start:end+1], "\n")
-				suggestions = append(suggestions, map[string]any{
-					"shortDescription": "Align mapping fields under `backend`",
-					"confidence":       "medium",
-					"fixedSnippet":     snippet,
-					"startLine":        start + 1,
-					"endLine":          end + 1,
-				})
+				suggestions = append(suggestions, types.NewSuggestion(types.SourceHeuristic, "Align mapping fields under `backend`", types.ConfidenceMedium, start+1, end+1, snippet))
 				return suggestions
 			}
 		}
@@ -279,9 +249,9 @@ func DetectBackendMisindent(content string) []map[string]any {
 
 // GenerateBackendSuggestion is a conservative fallback that runs a set of
 // heuristic detectors across the whole content and returns any suggestions.
-func GenerateBackendSuggestion(content string) []map[string]any {
+func GenerateBackendSuggestion(content string) []types.Suggestion {
 	if det := DetectBackendMisindent(content); len(det) > 0 {
 		return det
 	}
-	return []map[string]any{}
+	return []types.Suggestion{}
 }
@@ -0,0 +1,46 @@
+package types
+
+// ApplySuggestionRequest represents the request payload for the
+// apply-suggestion endpoint. The suggestion is chosen either by SuggestionID,
+// as returned by validate or fix for the same content, or by an explicit
+// StartLine/EndLine range and Replacement. The remaining fields are those of
+// ValidateRequest and FixRequest; they must match the request the suggestion
+// ID was issued for, and are used to re-validate the patched content.
+type ApplySuggestionRequest struct {
+	Content      string `json:"content" binding:"required"`
+	SuggestionID string `json:"suggestionId,omitempty"`
+	// StartLine and EndLine are 1-based and inclusive.
+	StartLine         int      `json:"startLine,omitempty"`
+	EndLine           int      `json:"endLine,omitempty"`
+	Replacement       string   `json:"replacement,omitempty"`
+	Filename          string   `json:"filename,omitempty"`
+	Schema            string   `json:"schema,omitempty"`
+	SchemaContent     string   `json:"schemaContent,omitempty"`
+	KubernetesVersion string   `json:"kubernetesVersion,omitempty"`
+	Rules             []string `json:"rules,omitempty"`
+	UseAI             bool     `json:"useAI,omitempty"`
+	Config            string   `json:"config,omitempty"`
+	// Values and Env render and interpolate the content, as in
+	// ValidateRequest.
+	Values string `json:"values,omitempty"`
+	Env    string `json:"env,omitempty"`
+	// Fixes, Indent and DuplicateKeys are the options of the fix request a
+	// suggestion ID came from, as in FixRequest.
+	Fixes         []string `json:"fixTypes,omitempty"`
+	Indent        int      `json:"indent,omitempty"`
+	DuplicateKeys string   `json:"duplicateKeys,omitempty"`
+}
+
+// ApplySuggestionResponse represents the response from the apply-suggestion
+// endpoint: the patched content, a unified diff against the original and the
+// validation result of the patched content.
+type ApplySuggestionResponse struct {
+	APIVersion     string            `json:"apiVersion"`
+	Content        string            `json:"content"`
+	Diff           string            `json:"diff"`
+	Applied        Suggestion        `json:"applied"`
+	IsValid        bool              `json:"isValid"`
+	Errors         []ValidationError `json:"errors"`
+	SuggestedFixes []Suggestion      `json:"suggestedFixes,omitempty"`
+	Explanation    string            `json:"explanation,omitempty"`
+}
@@ -0,0 +1,35 @@
+package types
+
+// ConvertRequest represents the request payload for the convert endpoint.
+// From and To are "yaml", "json" or "toml"; an empty From is detected from
+// the content and Filename.
+type ConvertRequest struct {
+	Content string `json:"content" binding:"required"`
+	// Filename helps detect the source format when From is empty.
+	Filename string `json:"filename,omitempty"`
+	From     string `json:"from,omitempty"`
+	To       string `json:"to" binding:"required"`
+	// Indent is the number of spaces per level of YAML and JSON output.
+	Indent int `json:"indent,omitempty"`
+	// NDJSON writes a multi-document YAML stream as one JSON document per
+	// line instead of a JSON array.
+	NDJSON bool `json:"ndjson,omitempty"`
+}
+
+// ConvertWarning is information lost in a conversion, such as a dropped
+// comment. Line is 1-based in the request content, or 0 when the loss is not
+// tied to a position.
+type ConvertWarning struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// ConvertResponse represents the response from the convert endpoint. From
+// is the source format, as detected when the request left it empty.
+type ConvertResponse struct {
+	APIVersion string           `json:"apiVersion"`
+	Content    string           `json:"content"`
+	From       string           `json:"from"`
+	To         string           `json:"to"`
+	Warnings   []ConvertWarning `json:"warnings"`
+}
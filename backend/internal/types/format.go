@@ -0,0 +1,10 @@
+package types
+
+// FormatRequest represents the request payload for the format-zip endpoint.
+type FormatRequest struct {
+	Main   string `json:"main" binding:"required"`
+	Vars   string `json:"variables"`
+	Outs   string `json:"outputs"`
+	Tfvars string `json:"tfvars"`
+	Name   string `json:"name"`
+}
@@ -0,0 +1,22 @@
+package types
+
+// HelmLintRequest represents the request payload for the helm/lint endpoint.
+type HelmLintRequest struct {
+	// Archive is the chart as a .tgz (as "helm package" writes it) or .zip,
+	// base64-encoded.
+	Archive []byte `json:"archive" binding:"required"`
+	// KubernetesVersion selects the bundled schemas the rendered manifests
+	// are checked against; empty uses the newest bundled version.
+	KubernetesVersion string `json:"kubernetesVersion,omitempty"`
+}
+
+// HelmLintResponse represents the response from the helm/lint endpoint.
+// IsValid is false when any file has an error; warnings and info do not
+// count.
+type HelmLintResponse struct {
+	APIVersion string       `json:"apiVersion"`
+	IsValid    bool         `json:"isValid"`
+	Chart      string       `json:"chart"`
+	Version    string       `json:"version"`
+	Files      []FileErrors `json:"files"`
+}
@@ -0,0 +1,25 @@
+package types
+
+// KustomizeRequest represents the request payload for the kustomize/build
+// endpoint.
+type KustomizeRequest struct {
+	// Archive is the kustomization with every base it refers to, as a .tgz
+	// or .zip, base64-encoded.
+	Archive []byte `json:"archive" binding:"required"`
+	// Path is the directory of the kustomization to build within the
+	// archive, e.g. "overlays/prod"; empty picks the shallowest one.
+	Path string `json:"path,omitempty"`
+	// KubernetesVersion selects the bundled schemas the output is checked
+	// against; empty uses the newest bundled version.
+	KubernetesVersion string `json:"kubernetesVersion,omitempty"`
+}
+
+// KustomizeResponse represents the response from the kustomize/build
+// endpoint. Content is the built YAML stream; Files lists the archive files
+// with build or validation problems.
+type KustomizeResponse struct {
+	APIVersion string       `json:"apiVersion"`
+	IsValid    bool         `json:"isValid"`
+	Content    string       `json:"content"`
+	Files      []FileErrors `json:"files"`
+}
@@ -0,0 +1,14 @@
+package types
+
+// RuleInfo describes a lint rule that can be selected in ValidateRequest.Rules.
+type RuleInfo struct {
+	ID          string `json:"id"`
+	Severity    string `json:"severity"`
+	Description string `json:"description"`
+}
+
+// RulesResponse represents the response from the rules endpoint.
+type RulesResponse struct {
+	APIVersion string     `json:"apiVersion"`
+	Rules      []RuleInfo `json:"rules"`
+}
@@ -0,0 +1,109 @@
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// Suggestion sources.
+const (
+	SourceHeuristic = "heuristic"
+	SourceAI        = "ai"
+)
+
+// Suggestion confidence levels.
+const (
+	ConfidenceHigh   = "high"
+	ConfidenceMedium = "medium"
+	ConfidenceLow    = "low"
+)
+
+// Suggestion is a proposed replacement of the lines StartLine..EndLine
+// (1-based, inclusive) with Replacement, offered when content cannot be fixed
+// automatically.
+type Suggestion struct {
+	// ID identifies the suggestion for the same content; it is derived from
+	// the source, range and replacement so it is stable across requests.
+	ID          string `json:"id"`
+	Description string `json:"description"`
+	// Confidence is "high", "medium" or "low".
+	Confidence  string `json:"confidence"`
+	StartLine   int    `json:"startLine"`
+	EndLine     int    `json:"endLine"`
+	Replacement string `json:"replacement"`
+	// Source is "heuristic" or "ai".
+	Source string `json:"source"`
+}
+
+// NewSuggestion builds a suggestion and assigns its ID.
+func NewSuggestion(source, description, confidence string, startLine, endLine int, replacement string) Suggestion {
+	s := Suggestion{
+		Description: description,
+		Confidence:  confidence,
+		StartLine:   startLine,
+		EndLine:     endLine,
+		Replacement: replacement,
+		Source:      source,
+	}
+	s.ID = s.computeID()
+	return s
+}
+
+// Shift moves the suggestion by n lines, e.g. from a document onto the whole
+// multi-document stream, and refreshes its ID.
+func (s *Suggestion) Shift(n int) {
+	if n == 0 {
+		return
+	}
+	s.StartLine += n
+	s.EndLine += n
+	s.ID = s.computeID()
+}
+
+func (s Suggestion) computeID() string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%d\x00%d\x00%s", s.Source, s.StartLine, s.EndLine, s.Replacement)))
+	return "sg-" + hex.EncodeToString(sum[:6])
+}
+
+// Change describes one modification made by the fix endpoint.
+type Change struct {
+	// Line is the 1-based line in the original content the change applies to.
+	Line int `json:"line"`
+	// Column is the 1-based column, when the change has a precise position.
+	Column      int    `json:"column,omitempty"`
+	Description string `json:"description"`
+}
+
+// Kinds of fix a FixHunk can be attributed to besides the IDs of the
+// selectable fixers: the metadata.name added by the Kubernetes schema fixes,
+// and changes no fixer made, such as JSON being pretty-printed.
+const (
+	FixKindMetadataName = "metadata-name"
+	FixKindFormat       = "format"
+)
+
+// FixHunk is one contiguous change between the content sent to the fix
+// endpoint and the fixed content, without surrounding context. Starts are
+// 1-based; a start refers to the line before the hunk when its count is 0.
+type FixHunk struct {
+	OldStart int      `json:"oldStart"`
+	OldLines int      `json:"oldLines"`
+	NewStart int      `json:"newStart"`
+	NewLines int      `json:"newLines"`
+	Before   []string `json:"before"`
+	After    []string `json:"after"`
+	// Kind is the ID of the fixer that produced the hunk, FixKindMetadataName
+	// or FixKindFormat.
+	Kind string `json:"kind"`
+}
+
+// DocumentFixes names the fixers that changed one document of the content
+// sent to the fix endpoint, in the order they ran.
+type DocumentFixes struct {
+	// Document is the 1-based index of the document in the stream.
+	Document int `json:"document"`
+	// StartLine is the line of the content on which the document begins.
+	StartLine int      `json:"startLine"`
+	Fixers    []string `json:"fixers"`
+}
@@ -1,5 +1,10 @@
 package types
 
+// APIVersion is the version of the JSON contract described by these types.
+// It is reported in every response as "apiVersion" and in the OpenAPI
+// document; fields are only added within a version, never renamed or removed.
+const APIVersion = "v1"
+
 // ValidateRequest represents the request payload for validation endpoint
 type ValidateRequest struct {
 	// Content is optional if the client supplies a custom schema via SchemaContent.
@@ -8,6 +13,21 @@ type ValidateRequest struct {
 	Schema        string `json:"schema"`
 	SchemaContent string `json:"schemaContent,omitempty"`
 	UseAI         bool   `json:"useAI,omitempty"`
+	// KubernetesVersion selects the bundled schema set for schema=="kubernetes"
+	// (e.g. "1.28" or "v1.30"); empty uses the newest bundled version.
+	KubernetesVersion string `json:"kubernetesVersion,omitempty"`
+	// Rules lists lint rule IDs to apply (e.g. "truthy", "line-length"), or
+	// "all" for every registered rule. No lint rules run when empty.
+	Rules []string `json:"rules,omitempty"`
+	// Config is the content of a .devformat.yaml file; it replaces the
+	// server's configuration for this request.
+	Config string `json:"config,omitempty"`
+	// Values is the YAML of the values Helm templates in Content are
+	// rendered with; errors are reported at template lines.
+	Values string `json:"values,omitempty"`
+	// Env is the content of the .env file a docker-compose file is
+	// interpolated with before validation.
+	Env string `json:"env,omitempty"`
 }
 
 // ValidationError represents a single validation error
@@ -17,24 +37,78 @@ type ValidationError struct {
 	Message  string `json:"message"`
 	Severity string `json:"severity"`
 	Type     string `json:"type"`
+	// Path is the JSON pointer of the offending value for schema errors.
+	Path string `json:"path,omitempty"`
+}
+
+// FileErrors lists the problems of one file of an uploaded archive, e.g.
+// "templates/deployment.yaml" of a chart. Lines are 1-based in that file,
+// or 0 for problems with the file as a whole.
+type FileErrors struct {
+	File   string            `json:"file"`
+	Errors []ValidationError `json:"errors"`
 }
 
 // ValidateResponse represents the response from validation endpoint
 type ValidateResponse struct {
-	IsValid     bool              `json:"isValid"`
-	Errors      []ValidationError `json:"errors"`
-	Fixed       string            `json:"fixedContent,omitempty"`
-	CanAutoFix  bool              `json:"canAutoFix"`
-	Explanation string            `json:"explanation,omitempty"`
+	APIVersion string            `json:"apiVersion"`
+	IsValid    bool              `json:"isValid"`
+	Errors     []ValidationError `json:"errors"`
+	Fixed      string            `json:"fixedContent,omitempty"`
+	CanAutoFix bool              `json:"canAutoFix"`
+	// Format is the detected format of the content (yaml, json, toml, hcl,
+	// ini, dotenv or xml) and FormatConfidence how sure the detection is.
+	Format           string `json:"format,omitempty"`
+	FormatConfidence string `json:"formatConfidence,omitempty"`
+	Explanation      string `json:"explanation,omitempty"`
 	// suggestedFixes is an optional list of small suggested snippets when auto-fix cannot be applied
-	SuggestedFixes []map[string]any `json:"suggestedFixes,omitempty"`
+	SuggestedFixes []Suggestion `json:"suggestedFixes,omitempty"`
+	// RenderedContent is the output of a Helm template that was validated.
+	RenderedContent string `json:"renderedContent,omitempty"`
 }
 
 // FixRequest represents the request payload for fix endpoint
 type FixRequest struct {
-	Content       string   `json:"content" binding:"required"`
-	Fixes         []string `json:"fixTypes"`
-	Schema        string   `json:"schema"`
-	SchemaContent string   `json:"schemaContent,omitempty"`
-	UseAI         bool     `json:"useAI,omitempty"`
-}
\ No newline at end of file
+	Content string `json:"content" binding:"required"`
+	// Fixes selects fixers by ID ("all" for every fixer); when empty only
+	// "indentation" runs.
+	Fixes []string `json:"fixTypes"`
+	// Indent is the number of spaces per level in fixedContent; it
+	// overrides the configured indentation.
+	Indent int `json:"indent,omitempty"`
+	// DuplicateKeys is how the duplicate-keys fixer resolves repeated keys.
+	DuplicateKeys string `json:"duplicateKeys,omitempty"`
+	Schema        string `json:"schema"`
+	SchemaContent string `json:"schemaContent,omitempty"`
+	UseAI         bool   `json:"useAI,omitempty"`
+	// Filename is matched against the configuration's ignore globs.
+	Filename string `json:"filename,omitempty"`
+	// Config is the content of a .devformat.yaml file, as in ValidateRequest.
+	Config string `json:"config,omitempty"`
+	// Values renders Helm templates, as in ValidateRequest.
+	Values string `json:"values,omitempty"`
+}
+
+// FixResponse represents the response from fix endpoint. FixedContent is empty
+// when the content could not be fixed automatically. Diff is a unified diff
+// from the request content to FixedContent and Hunks lists the same changes
+// without context, each attributed to the fix that made it.
+type FixResponse struct {
+	APIVersion     string            `json:"apiVersion"`
+	FixedContent   string            `json:"fixedContent,omitempty"`
+	Changes        []Change          `json:"changes"`
+	Diff           string            `json:"diff,omitempty"`
+	Hunks          []FixHunk         `json:"hunks"`
+	AppliedFixes   []DocumentFixes   `json:"appliedFixes"`
+	IsValid        bool              `json:"isValid"`
+	Errors         []ValidationError `json:"errors"`
+	CanAutoFix     bool              `json:"canAutoFix"`
+	SuggestedFixes []Suggestion      `json:"suggestedFixes,omitempty"`
+	Explanation    string            `json:"explanation,omitempty"`
+}
+
+// ErrorResponse is returned with 4xx and 5xx statuses.
+type ErrorResponse struct {
+	Error   string `json:"error"`
+	Details string `json:"details,omitempty"`
+}
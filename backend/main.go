@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"devformat/backend/handlers"
+	"devformat/backend/internal/types"
 
 	"github.com/gin-gonic/gin"
 )
@@ -27,10 +28,19 @@ func main() {
 		c.Next()
 	})
 
-	// Register routes directly instead of using groups
-	r.POST("/api/validate", handlers.ValidateHandler)
-	r.POST("/api/fix", handlers.FixHandler)
-	r.POST("/api/format-zip", handlers.FormatAndZipHandler)
+	// Register routes directly instead of using groups. Every route is served
+	// unversioned and under the current contract version (/api/v1/...).
+	for _, prefix := range []string{"/api", "/api/" + types.APIVersion} {
+		r.POST(prefix+"/validate", handlers.ValidateHandler)
+		r.POST(prefix+"/fix", handlers.FixHandler)
+		r.POST(prefix+"/apply-suggestion", handlers.ApplySuggestionHandler)
+		r.POST(prefix+"/convert", handlers.ConvertHandler)
+		r.POST(prefix+"/helm/lint", handlers.HelmLintHandler)
+		r.POST(prefix+"/kustomize/build", handlers.KustomizeHandler)
+		r.POST(prefix+"/format-zip", handlers.FormatAndZipHandler)
+		r.GET(prefix+"/rules", handlers.RulesHandler)
+		r.GET(prefix+"/openapi.json", handlers.OpenAPIHandler)
+	}
 	r.GET("/healthz", func(c *gin.Context) { c.Status(http.StatusOK) })
 
 	port := os.Getenv("PORT")
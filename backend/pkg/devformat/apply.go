@@ -0,0 +1,154 @@
+package devformat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"devformat/backend/internal/diff"
+	"devformat/backend/internal/parser"
+	sugg "devformat/backend/internal/suggestions"
+	"devformat/backend/internal/types"
+)
+
+// ErrSuggestionNotFound is returned by ApplySuggestion when no suggestion for
+// the content has the requested ID.
+var ErrSuggestionNotFound = errors.New("suggestion not found for this content")
+
+// ApplyRequest selects the suggestion to apply to Request.Content, either by
+// SuggestionID (as returned by Validate or Fix for the same content) or by an
+// explicit line range and replacement. The remaining Request fields are used
+// to find the suggestion and to re-validate the result.
+type ApplyRequest struct {
+	Request
+	SuggestionID string
+	// StartLine and EndLine are 1-based and inclusive.
+	StartLine   int
+	EndLine     int
+	Replacement string
+}
+
+// ApplyResult is the outcome of ApplySuggestion.
+type ApplyResult struct {
+	// Content is the patched content.
+	Content string
+	// Diff is a unified diff from the original to the patched content.
+	Diff string
+	// Applied is the suggestion that was spliced in.
+	Applied Suggestion
+	// Validation is the result of validating the patched content.
+	Validation Result
+}
+
+// ApplySuggestion replaces the suggestion's line range in the content with
+// its replacement and re-validates the result. A suggestion ID is looked up
+// among the heuristic suggestions for the content, so it must be the content
+// the ID was issued for; AI suggestions differ between calls and are applied
+// by range instead.
+func ApplySuggestion(ctx context.Context, req ApplyRequest) (ApplyResult, error) {
+	s, err := resolveSuggestion(ctx, req)
+	if err != nil {
+		return ApplyResult{}, err
+	}
+	patched, err := Splice(req.Content, s.StartLine, s.EndLine, s.Replacement)
+	if err != nil {
+		return ApplyResult{}, err
+	}
+
+	vreq := req.Request
+	vreq.Content = patched
+	res, err := Validate(ctx, vreq)
+	if err != nil {
+		return ApplyResult{}, err
+	}
+
+	from, to := diffLabels(req.Filename)
+	return ApplyResult{
+		Content:    patched,
+		Diff:       diff.Unified(from, to, req.Content, patched),
+		Applied:    s,
+		Validation: res,
+	}, nil
+}
+
+func resolveSuggestion(ctx context.Context, req ApplyRequest) (Suggestion, error) {
+	if req.SuggestionID == "" {
+		if req.StartLine <= 0 || req.EndLine < req.StartLine {
+			return Suggestion{}, fmt.Errorf("either a suggestion ID or a line range (startLine <= endLine) is required")
+		}
+		return types.NewSuggestion(types.SourceHeuristic, "Client supplied replacement", types.ConfidenceLow, req.StartLine, req.EndLine, req.Replacement), nil
+	}
+
+	if s, ok := findSuggestion(heuristicSuggestions(req.Content, req.Filename), req.SuggestionID); ok {
+		return s, nil
+	}
+	// the snippets the fixer offers when it refuses to auto-fix depend on
+	// the selected fixers; the fixer is deterministic without AI
+	freq := req.Request
+	freq.UseAI = false
+	fixed, err := Fix(ctx, freq)
+	if err != nil {
+		return Suggestion{}, err
+	}
+	if s, ok := findSuggestion(fixed.Suggestions, req.SuggestionID); ok {
+		return s, nil
+	}
+	return Suggestion{}, ErrSuggestionNotFound
+}
+
+// heuristicSuggestions returns the suggestions Validate derives from the
+// content alone: those for each YAML document that fails to parse and the
+// fallback over the whole content. Schemas, lint rules and AI are not run.
+func heuristicSuggestions(content, filename string) []Suggestion {
+	if parser.ContainsHelmTemplate(content) || parser.Detect(content, filename).Format != parser.FormatYAML {
+		return nil
+	}
+	var out []Suggestion
+	for _, d := range parser.SplitYAMLDocuments(content) {
+		if strings.TrimSpace(d.Content) == "" {
+			continue
+		}
+		node, err := parser.ParseYAMLNode(d.Content)
+		if err == nil {
+			var parsed any
+			if err = node.Decode(&parsed); onlyDuplicateKeys(err) {
+				err = nil
+			}
+		}
+		if err != nil {
+			suggs := suggestForDocument(d.Content, err, false)
+			offsetSuggestions(suggs, d.StartLine-1)
+			out = append(out, suggs...)
+		}
+	}
+	return append(out, sugg.GenerateBackendSuggestion(content)...)
+}
+
+func findSuggestion(suggs []Suggestion, id string) (Suggestion, bool) {
+	for _, s := range suggs {
+		if s.ID == id {
+			return s, true
+		}
+	}
+	return Suggestion{}, false
+}
+
+// Splice replaces lines startLine..endLine (1-based, inclusive) of content
+// with replacement, keeping the content's trailing newline.
+func Splice(content string, startLine, endLine int, replacement string) (string, error) {
+	trailing := strings.HasSuffix(content, "\n")
+	lines := diff.SplitLines(content)
+	if startLine < 1 || endLine < startLine || endLine > len(lines) {
+		return "", fmt.Errorf("line range %d-%d is outside the content (1-%d)", startLine, endLine, len(lines))
+	}
+
+	out := append([]string{}, lines[:startLine-1]...)
+	out = append(out, diff.SplitLines(replacement)...)
+	out = append(out, lines[endLine:]...)
+	patched := strings.Join(out, "\n")
+	if trailing && patched != "" {
+		patched += "\n"
+	}
+	return patched, nil
+}
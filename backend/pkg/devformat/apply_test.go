@@ -0,0 +1,114 @@
+package devformat
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestApplySuggestion(t *testing.T) {
+	const broken = "a: 1\n---\nb:\n  c: 1\n   d: 2\n"
+	validated, err := Validate(context.Background(), Request{Content: broken})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(validated.Suggestions) == 0 {
+		t.Fatal("Validate() offered no suggestions")
+	}
+	suggested := validated.Suggestions[0]
+
+	tests := []struct {
+		name  string
+		req   ApplyRequest
+		want  string
+		valid bool
+		err   bool
+	}{
+		{
+			name: "by range",
+			req:  ApplyRequest{Request: Request{Content: broken}, StartLine: 5, EndLine: 5, Replacement: "  d: 2"},
+			want: "a: 1\n---\nb:\n  c: 1\n  d: 2\n",
+			// the result is re-validated
+			valid: true,
+		},
+		{
+			name:  "by range with more lines",
+			req:   ApplyRequest{Request: Request{Content: "a: 1\nb: 2"}, StartLine: 2, EndLine: 2, Replacement: "b:\n  - 2"},
+			want:  "a: 1\nb:\n  - 2",
+			valid: true,
+		},
+		{
+			name:  "leftover syntax error",
+			req:   ApplyRequest{Request: Request{Content: broken}, StartLine: 4, EndLine: 4, Replacement: "    c: 1"},
+			want:  "a: 1\n---\nb:\n    c: 1\n   d: 2\n",
+			valid: false,
+		},
+		{
+			name: "by id",
+			req:  ApplyRequest{Request: Request{Content: broken}, SuggestionID: suggested.ID},
+			want: mustSplice(t, broken, suggested.StartLine, suggested.EndLine, suggested.Replacement),
+		},
+		{
+			name: "unknown id",
+			req:  ApplyRequest{Request: Request{Content: broken}, SuggestionID: "sg-000000000000"},
+			err:  true,
+		},
+		{
+			name: "id for other content",
+			req:  ApplyRequest{Request: Request{Content: "x:\n  y: 1\n z: 2\n"}, SuggestionID: suggested.ID},
+			err:  true,
+		},
+		{
+			name: "no id or range",
+			req:  ApplyRequest{Request: Request{Content: broken}},
+			err:  true,
+		},
+		{
+			name: "range past the end",
+			req:  ApplyRequest{Request: Request{Content: broken}, StartLine: 5, EndLine: 9, Replacement: "x: 1"},
+			err:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := ApplySuggestion(context.Background(), tt.req)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("ApplySuggestion() = %+v, want an error", res)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Content != tt.want {
+				t.Errorf("Content = %q, want %q", res.Content, tt.want)
+			}
+			if tt.req.SuggestionID == "" && res.Validation.Valid != tt.valid {
+				t.Errorf("Validation.Valid = %v, want %v: %+v", res.Validation.Valid, tt.valid, res.Validation.Problems)
+			}
+			if res.Validation.Valid != (len(res.Validation.Problems) == 0) {
+				t.Errorf("Validation.Valid = %v with problems %+v", res.Validation.Valid, res.Validation.Problems)
+			}
+			if res.Diff == "" {
+				t.Error("Diff is empty")
+			}
+		})
+	}
+}
+
+func TestApplySuggestionNotFound(t *testing.T) {
+	_, err := ApplySuggestion(context.Background(), ApplyRequest{Request: Request{Content: "a: 1\n"}, SuggestionID: "sg-000000000000"})
+	if !errors.Is(err, ErrSuggestionNotFound) {
+		t.Errorf("ApplySuggestion() error = %v, want %v", err, ErrSuggestionNotFound)
+	}
+}
+
+func mustSplice(t *testing.T, content string, start, end int, replacement string) string {
+	t.Helper()
+	out, err := Splice(content, start, end, replacement)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
@@ -0,0 +1,426 @@
+package devformat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"devformat/backend/internal/helm"
+	"devformat/backend/internal/kubernetes"
+	"devformat/backend/internal/parser"
+	"devformat/backend/internal/schema"
+)
+
+// ErrInvalidChart is returned by LintChart when the archive cannot be read as
+// a chart.
+var ErrInvalidChart = errors.New("invalid chart archive")
+
+// ChartRequest describes a Helm chart to lint.
+type ChartRequest struct {
+	// Archive is the chart as a .tgz (as "helm package" writes it) or .zip.
+	Archive []byte
+	// KubernetesVersion selects the bundled Kubernetes schemas the rendered
+	// manifests are checked against, and the version templates see.
+	KubernetesVersion string
+}
+
+// ChartResult is the outcome of LintChart.
+type ChartResult struct {
+	// Valid reports whether no file has an error; warnings and info do not
+	// count.
+	Valid   bool
+	Name    string
+	Version string
+	// Files lists the checked files with their problems, Chart.yaml and
+	// values first, then templates in name order.
+	Files []FileProblems
+}
+
+// FileProblems are the problems of one file of an uploaded archive. Lines
+// refer to the file.
+type FileProblems struct {
+	File     string
+	Problems []Problem
+}
+
+// semverRe matches a SemVer 2 version, with the leading "v" Helm tolerates.
+var semverRe = regexp.MustCompile(`^v?(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`)
+
+// templateExtensions are the file types Helm accepts in templates/.
+var templateExtensions = map[string]bool{".yaml": true, ".yml": true, ".tpl": true, ".txt": true}
+
+// LintChart checks a chart the way "helm lint" does, without the helm
+// binary: Chart.yaml fields, values.yaml against values.schema.json when the
+// chart has one, and every template rendered with the default values and
+// validated against the Kubernetes schemas. Subcharts in charts/ are rendered
+// with their share of the values unless their dependency's condition
+// disables them. An error is returned for an unreadable archive, an
+// unsupported Kubernetes version or a cancelled context.
+func LintChart(ctx context.Context, req ChartRequest) (ChartResult, error) {
+	version, err := kubernetes.NormalizeVersion(req.KubernetesVersion)
+	if err != nil {
+		return ChartResult{}, err
+	}
+	pkg, err := helm.LoadArchive(req.Archive)
+	if err != nil {
+		return ChartResult{}, fmt.Errorf("%w: %v", ErrInvalidChart, err)
+	}
+
+	l := &chartLinter{problems: map[string][]Problem{}, opts: validationOptions{schemaName: "helm", k8sVersion: version}}
+	meta, ok := l.chartYAML(pkg, version)
+	res := ChartResult{Name: meta.Name, Version: meta.Version}
+	if ok {
+		values := l.values(pkg)
+		if err := l.templates(ctx, pkg, "", meta, values); err != nil {
+			return ChartResult{}, err
+		}
+	}
+
+	res.Valid = true
+	for _, file := range l.files() {
+		problems := l.problems[file]
+		for _, p := range problems {
+			if p.Severity == "error" {
+				res.Valid = false
+			}
+		}
+		res.Files = append(res.Files, FileProblems{File: file, Problems: problems})
+	}
+	return res, nil
+}
+
+// chartLinter collects problems by chart file.
+type chartLinter struct {
+	problems map[string][]Problem
+	opts     validationOptions
+}
+
+// add records a problem at the start of line, or with the file as a whole
+// when line is 0.
+func (l *chartLinter) add(file string, line int, severity, typ, format string, args ...any) {
+	column := 1
+	if line == 0 {
+		column = 0
+	}
+	l.problems[file] = append(l.problems[file], Problem{Line: line, Column: column, Message: fmt.Sprintf(format, args...), Severity: severity, Type: typ})
+}
+
+// checked marks a file as checked, so it is listed even without problems.
+func (l *chartLinter) checked(file string) {
+	if _, ok := l.problems[file]; !ok {
+		l.problems[file] = []Problem{}
+	}
+}
+
+// files returns the checked files: Chart.yaml, values.yaml and
+// values.schema.json first, then the rest by name.
+func (l *chartLinter) files() []string {
+	rank := map[string]int{"Chart.yaml": 1, "values.yaml": 2, "values.schema.json": 3}
+	out := make([]string, 0, len(l.problems))
+	for f := range l.problems {
+		out = append(out, f)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		ri, rj := rank[out[i]], rank[out[j]]
+		if ri == 0 {
+			ri = len(rank) + 1
+		}
+		if rj == 0 {
+			rj = len(rank) + 1
+		}
+		if ri != rj {
+			return ri < rj
+		}
+		return out[i] < out[j]
+	})
+	return out
+}
+
+// chartYAML checks the chart's metadata. It reports whether the chart can
+// be rendered, which needs a readable Chart.yaml.
+func (l *chartLinter) chartYAML(pkg *helm.Package, kubeVersion string) (helm.Chart, bool) {
+	const file = "Chart.yaml"
+	var meta helm.Chart
+	content, ok := pkg.Files[file]
+	if !ok {
+		l.add(file, 0, "error", "chart", "Chart.yaml file is missing")
+		return meta, false
+	}
+	l.checked(file)
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		line, col := parser.YAMLErrorPosition(content, err)
+		l.problems[file] = append(l.problems[file], Problem{Line: line, Column: col, Message: "YAML syntax error: " + parser.YAMLErrorMessage(err), Severity: "error", Type: "syntax"})
+		return meta, false
+	}
+	if err := doc.Decode(&meta); err != nil {
+		l.add(file, 1, "error", "chart", "Chart.yaml does not describe a chart: %v", err)
+		return meta, false
+	}
+	root := doc.Content
+	line := func(key string) int {
+		if len(root) == 0 {
+			return 1
+		}
+		for i := 0; i+1 < len(root[0].Content); i += 2 {
+			if root[0].Content[i].Value == key {
+				return root[0].Content[i].Line
+			}
+		}
+		return 1
+	}
+
+	switch meta.APIVersion {
+	case "":
+		l.add(file, 1, "error", "chart", "apiVersion is required. The value must be either \"v1\" or \"v2\"")
+	case "v1", "v2":
+	default:
+		l.add(file, line("apiVersion"), "error", "chart", "apiVersion %q is not valid. The value must be either \"v1\" or \"v2\"", meta.APIVersion)
+	}
+	switch {
+	case meta.Name == "":
+		l.add(file, 1, "error", "chart", "name is required")
+	case strings.ContainsAny(meta.Name, "/\\"):
+		l.add(file, line("name"), "error", "chart", "chart name %q must not contain a path separator", meta.Name)
+	}
+	switch {
+	case meta.Version == "":
+		l.add(file, 1, "error", "chart", "version is required")
+	case !semverRe.MatchString(meta.Version):
+		l.add(file, line("version"), "error", "chart", "version %q is not a valid SemVer 2 version", meta.Version)
+	}
+	if meta.Type != "" && meta.Type != "application" && meta.Type != "library" {
+		l.add(file, line("type"), "error", "chart", "chart type %q is not valid. The value must be \"application\" or \"library\"", meta.Type)
+	}
+	if meta.Icon == "" {
+		l.add(file, 1, "info", "chart", "icon is recommended")
+	}
+	if meta.KubeVersion != "" {
+		ok, err := helm.SemverCompare(meta.KubeVersion, kubeVersion)
+		switch {
+		case err != nil:
+			l.add(file, line("kubeVersion"), "error", "chart", "kubeVersion %q is not a valid constraint: %v", meta.KubeVersion, err)
+		case !ok:
+			l.add(file, line("kubeVersion"), "error", "chart", "chart requires kubeVersion %s, which is incompatible with Kubernetes %s", meta.KubeVersion, kubeVersion)
+		}
+	}
+	for i, m := range meta.Maintainers {
+		if m.Name == "" {
+			l.add(file, line("maintainers"), "error", "chart", "maintainer %d has no name", i+1)
+		}
+	}
+	if len(meta.Dependencies) > 0 && meta.APIVersion == "v1" {
+		l.add(file, line("dependencies"), "warning", "chart", "dependencies in Chart.yaml need apiVersion v2; apiVersion v1 charts list them in requirements.yaml")
+	}
+	for _, d := range meta.Dependencies {
+		switch {
+		case d.Name == "":
+			l.add(file, line("dependencies"), "error", "chart", "a dependency has no name")
+		case subchart(pkg, d.Name) == nil:
+			l.add(file, line("dependencies"), "warning", "chart", "chart dependency %q is missing from charts/; run \"helm dependency update\"", d.Name)
+		}
+	}
+	return meta, true
+}
+
+// values checks values.yaml, against values.schema.json when present, and
+// returns the default values.
+func (l *chartLinter) values(pkg *helm.Package) map[string]any {
+	const file = "values.yaml"
+	content, ok := pkg.Files[file]
+	if !ok {
+		l.add(file, 0, "info", "chart", "values.yaml file does not exist")
+		return map[string]any{}
+	}
+	l.checked(file)
+	var doc yaml.Node
+	values := map[string]any{}
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		line, col := parser.YAMLErrorPosition(content, err)
+		l.problems[file] = append(l.problems[file], Problem{Line: line, Column: col, Message: "YAML syntax error: " + parser.YAMLErrorMessage(err), Severity: "error", Type: "syntax"})
+		return values
+	}
+	if err := doc.Decode(&values); err != nil {
+		l.add(file, 1, "error", "chart", "values must be a mapping: %v", err)
+		return map[string]any{}
+	}
+	if values == nil {
+		values = map[string]any{}
+	}
+
+	schemaContent, ok := pkg.Files["values.schema.json"]
+	if !ok {
+		return values
+	}
+	l.checked("values.schema.json")
+	compiled, err := schema.Compile([]byte(schemaContent))
+	if err != nil {
+		l.add("values.schema.json", 0, "error", "schema", "invalid values schema: %v", err)
+		return values
+	}
+	if len(doc.Content) == 0 {
+		// an empty values.yaml is validated as an empty mapping
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map", Line: 1, Column: 1}}}
+	}
+	l.problems[file] = append(l.problems[file], schemaErrors(compiled.Validate(&doc), "", 0)...)
+	return values
+}
+
+// templates renders the chart's templates with values and validates the
+// output, then does the same for its enabled subcharts. prefix is the path
+// of the chart within the archive, e.g. "charts/redis/".
+func (l *chartLinter) templates(ctx context.Context, pkg *helm.Package, prefix string, meta helm.Chart, values map[string]any) error {
+	templates := pkg.Templates()
+	if len(templates) == 0 && prefix == "" {
+		l.add("templates/", 0, "warning", "chart", "templates/ directory does not exist or is empty")
+	}
+	sources := map[string]string{}
+	valid := templates[:0]
+	for _, t := range templates {
+		if ext := path.Ext(t.Name); !templateExtensions[ext] {
+			l.add(prefix+t.Name, 0, "error", "template", "file extension %q is not valid; templates must be .yaml, .yml, .tpl or .txt", ext)
+			continue
+		}
+		sources[t.Name] = t.Content
+		valid = append(valid, t)
+	}
+	templates = valid
+
+	rendered := helm.Render(ctx, templates, helm.Options{
+		Values:      values,
+		Chart:       meta,
+		KubeVersion: l.opts.k8sVersion,
+		Files:       pkg.OtherFiles(),
+	})
+	for _, r := range rendered {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if r.Err != nil {
+			l.problems[prefix+r.Err.Template] = append(l.problems[prefix+r.Err.Template], Problem{Line: r.Err.Line, Column: r.Err.Column, Message: "Helm template error: " + r.Err.Message, Severity: "error", Type: "template"})
+			continue
+		}
+		l.checked(prefix + r.Name)
+		if path.Ext(r.Name) == ".txt" {
+			// NOTES.txt and the like are shown to users, not applied
+			continue
+		}
+		found, _, err := checkRendered(ctx, r, sources, l.opts)
+		if err != nil {
+			return err
+		}
+		for _, p := range found {
+			l.problems[prefix+p.template] = append(l.problems[prefix+p.template], p.Problem)
+		}
+	}
+
+	for _, d := range meta.Dependencies {
+		sub := subchart(pkg, d.Name)
+		if sub == nil || !dependencyEnabled(d, values) {
+			continue
+		}
+		var subMeta helm.Chart
+		if err := yaml.Unmarshal([]byte(sub.chart.Files["Chart.yaml"]), &subMeta); err != nil {
+			l.add(prefix+"charts/"+sub.dir+"/Chart.yaml", 0, "error", "syntax", "YAML syntax error: %v", err)
+			continue
+		}
+		if err := l.templates(ctx, sub.chart, prefix+"charts/"+sub.dir+"/", subMeta, subchartValues(sub.chart, d, values)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// vendored is a subchart with the directory or archive name it has in
+// charts/.
+type vendored struct {
+	dir   string
+	chart *helm.Package
+}
+
+// subchart finds the vendored chart named name, or returns nil.
+func subchart(pkg *helm.Package, name string) *vendored {
+	dirs := make([]string, 0, len(pkg.Subcharts))
+	for dir := range pkg.Subcharts {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	for _, dir := range dirs {
+		var meta helm.Chart
+		sub := pkg.Subcharts[dir]
+		if yaml.Unmarshal([]byte(sub.Files["Chart.yaml"]), &meta) == nil && meta.Name == name {
+			return &vendored{dir: dir, chart: sub}
+		}
+	}
+	return nil
+}
+
+// dependencyEnabled evaluates a dependency's condition, a comma-separated
+// list of values paths of which the first that exists decides.
+func dependencyEnabled(d helm.Dependency, values map[string]any) bool {
+	for _, cond := range strings.Split(d.Condition, ",") {
+		cond = strings.TrimSpace(cond)
+		if cond == "" {
+			continue
+		}
+		var cur any = values
+		for _, key := range strings.Split(cond, ".") {
+			m, ok := cur.(map[string]any)
+			if !ok {
+				cur = nil
+				break
+			}
+			cur = m[key]
+		}
+		if b, ok := cur.(bool); ok {
+			return b
+		}
+	}
+	return true
+}
+
+// subchartValues returns the values a subchart renders with: its own
+// values.yaml, overridden by the parent's values under the dependency's name
+// or alias, plus the parent's global values.
+func subchartValues(sub *helm.Package, d helm.Dependency, parent map[string]any) map[string]any {
+	values := map[string]any{}
+	_ = yaml.Unmarshal([]byte(sub.Files["values.yaml"]), &values)
+	if values == nil {
+		values = map[string]any{}
+	}
+	key := d.Name
+	if d.Alias != "" {
+		key = d.Alias
+	}
+	if own, ok := parent[key].(map[string]any); ok {
+		overlay(values, own)
+	}
+	if global, ok := parent["global"].(map[string]any); ok {
+		g, _ := values["global"].(map[string]any)
+		if g == nil {
+			g = map[string]any{}
+		}
+		overlay(g, global)
+		values["global"] = g
+	}
+	return values
+}
+
+// overlay copies src into dst recursively, src winning.
+func overlay(dst, src map[string]any) {
+	for k, v := range src {
+		dm, dOK := dst[k].(map[string]any)
+		sm, sOK := v.(map[string]any)
+		if dOK && sOK {
+			overlay(dm, sm)
+			continue
+		}
+		dst[k] = v
+	}
+}
@@ -0,0 +1,39 @@
+package devformat
+
+import (
+	"errors"
+
+	"gopkg.in/yaml.v3"
+
+	"devformat/backend/internal/compose"
+)
+
+// ErrInvalidEnv is returned when req.Env cannot be read as a .env file.
+var ErrInvalidEnv = errors.New("invalid env file")
+
+// composeErrors interpolates a compose file with the variables of the
+// request's .env file, then checks the result against the Compose
+// Specification schema and for what docker compose rejects when it loads
+// the project: undefined networks, volumes, secrets, configs and services,
+// circular depends_on and host ports published twice.
+func composeErrors(node *yaml.Node, env map[string]string, where string, lineOffset int) []Problem {
+	interpolated, issues := compose.Interpolate(node, env)
+	errs := composeProblems(issues, where, lineOffset)
+	errs = append(errs, schemaErrors(compose.Validate(interpolated), where, lineOffset)...)
+	return append(errs, composeProblems(compose.Check(interpolated), where, lineOffset)...)
+}
+
+func composeProblems(issues []compose.Issue, where string, lineOffset int) []Problem {
+	out := make([]Problem, 0, len(issues))
+	for _, i := range issues {
+		out = append(out, Problem{
+			Line:     i.Line + lineOffset,
+			Column:   i.Column,
+			Message:  i.Message + where,
+			Severity: i.Severity,
+			Type:     "compose",
+			Path:     i.Path,
+		})
+	}
+	return out
+}
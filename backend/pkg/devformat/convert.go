@@ -0,0 +1,76 @@
+package devformat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"devformat/backend/internal/convert"
+	"devformat/backend/internal/parser"
+	"devformat/backend/internal/types"
+)
+
+// ErrInvalidConversion is returned by Convert when a format is unknown.
+var ErrInvalidConversion = errors.New("invalid conversion")
+
+// ConvertRequest describes a conversion between "yaml", "json" and "toml".
+type ConvertRequest struct {
+	Content string
+	// Filename helps detect the format of Content when From is empty.
+	Filename string
+	// From is the format of Content; empty detects it.
+	From string
+	To   string
+	// Indent is the number of spaces per level of YAML and JSON output; 0
+	// means 2.
+	Indent int
+	// NDJSON writes a multi-document YAML stream as newline-delimited JSON
+	// rather than a JSON array.
+	NDJSON bool
+}
+
+// ConvertResult is the outcome of Convert.
+type ConvertResult struct {
+	Content string
+	// From is the source format, detected when the request left it empty.
+	From     string
+	To       string
+	Warnings []ConvertWarning
+}
+
+// ConvertWarning is information the target format could not keep.
+type ConvertWarning = types.ConvertWarning
+
+// Convert translates req.Content from one format to another, keeping key
+// order. A YAML stream becomes a JSON array (or NDJSON) with one element per
+// document; TOML takes a single document with a mapping at the top level.
+// Comments, non-string keys, nulls in TOML and other losses are reported in
+// Warnings. Content that cannot be read is an error.
+func Convert(ctx context.Context, req ConvertRequest) (ConvertResult, error) {
+	if err := ctx.Err(); err != nil {
+		return ConvertResult{}, err
+	}
+	from := req.From
+	if from == "" {
+		det := parser.Detect(req.Content, req.Filename)
+		if convert.CheckFormat(det.Format) != nil {
+			return ConvertResult{}, fmt.Errorf("%w: content looks like %s (%s), which cannot be converted", ErrInvalidConversion, det.Format, det.Reason)
+		}
+		from = det.Format
+	}
+	for _, f := range []string{from, req.To} {
+		if err := convert.CheckFormat(f); err != nil {
+			return ConvertResult{}, fmt.Errorf("%w: %v", ErrInvalidConversion, err)
+		}
+	}
+
+	out, warnings, err := convert.Convert(req.Content, convert.Options{From: from, To: req.To, Indent: req.Indent, NDJSON: req.NDJSON})
+	if err != nil {
+		return ConvertResult{}, err
+	}
+	res := ConvertResult{Content: out, From: from, To: req.To, Warnings: []ConvertWarning{}}
+	for _, w := range warnings {
+		res.Warnings = append(res.Warnings, ConvertWarning{Line: w.Line, Message: w.Message})
+	}
+	return res, nil
+}
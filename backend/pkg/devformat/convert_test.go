@@ -0,0 +1,94 @@
+package devformat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      ConvertRequest
+		want     string
+		from     string
+		warnings int
+	}{
+		{"yaml to json keeps order", ConvertRequest{Content: "b: 1\na: [x, 2]\n", To: "json"}, "{\n  \"b\": 1,\n  \"a\": [\n    \"x\",\n    2\n  ]\n}\n", "yaml", 0},
+		{"dropped comment", ConvertRequest{Content: "# c\na: 1\n", To: "json"}, "{\n  \"a\": 1\n}\n", "yaml", 1},
+		{"stream to array", ConvertRequest{Content: "a: 1\n---\nb: 2\n", To: "json", Indent: 4}, "[\n    {\n        \"a\": 1\n    },\n    {\n        \"b\": 2\n    }\n]\n", "yaml", 0},
+		{"stream to ndjson", ConvertRequest{Content: "a: 1\n---\nb: 2\n", To: "json", NDJSON: true}, "{\"a\":1}\n{\"b\":2}\n", "yaml", 0},
+		{"json to yaml", ConvertRequest{Content: `{"a": {"b": 1}}`, To: "yaml"}, "a:\n  b: 1\n", "json", 0},
+		{"json to toml", ConvertRequest{Content: `{"a": {"b": 1}}`, To: "toml"}, "[a]\nb = 1\n", "json", 0},
+		{"toml by file name", ConvertRequest{Content: "a = 1\n", Filename: "x.toml", To: "yaml"}, "a: 1\n", "toml", 0},
+		{"null in toml", ConvertRequest{Content: "a: null\nb: 1\n", To: "toml"}, "b = 1\n", "yaml", 1},
+		{"aliases expanded", ConvertRequest{Content: "a: &x [1]\nb: *x\n", To: "json"}, "{\n  \"a\": [\n    1\n  ],\n  \"b\": [\n    1\n  ]\n}\n", "yaml", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Convert(context.Background(), tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Content != tt.want {
+				t.Errorf("Content =\n%s\nwant:\n%s", res.Content, tt.want)
+			}
+			if res.From != tt.from {
+				t.Errorf("From = %s, want %s", res.From, tt.from)
+			}
+			if len(res.Warnings) != tt.warnings {
+				t.Errorf("Warnings = %+v, want %d", res.Warnings, tt.warnings)
+			}
+		})
+	}
+}
+
+func TestConvertErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     ConvertRequest
+		invalid bool
+	}{
+		{"unknown target", ConvertRequest{Content: "a: 1\n", To: "xml"}, true},
+		{"unconvertible source", ConvertRequest{Content: "<a/>", To: "json"}, true},
+		{"syntax error", ConvertRequest{Content: "a: [1\n", To: "json"}, false},
+		{"recursive sequence alias", ConvertRequest{Content: "&a [*a]", To: "json"}, false},
+		{"recursive mapping alias", ConvertRequest{Content: "a: &a\n  b: *a\nc: *a\n", To: "toml"}, false},
+		{"alias bomb to json", ConvertRequest{Content: aliasBomb(), To: "json"}, false},
+		{"alias bomb to toml", ConvertRequest{Content: "root:\n" + indent(aliasBomb()), To: "toml"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Convert(context.Background(), tt.req)
+			if err == nil {
+				t.Fatal("Convert() succeeded")
+			}
+			if errors.Is(err, ErrInvalidConversion) != tt.invalid {
+				t.Errorf("Convert() error = %v, want ErrInvalidConversion %v", err, tt.invalid)
+			}
+		})
+	}
+}
+
+// aliasBomb returns YAML whose aliases expand to 10^9 values.
+func aliasBomb() string {
+	var b strings.Builder
+	b.WriteString("l0: &l0 [x, x, x, x, x, x, x, x, x, x]\n")
+	for i := 1; i < 9; i++ {
+		fmt.Fprintf(&b, "l%d: &l%d [", i, i)
+		for j := 0; j < 10; j++ {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			fmt.Fprintf(&b, "*l%d", i-1)
+		}
+		b.WriteString("]\n")
+	}
+	return b.String()
+}
+
+func indent(s string) string {
+	return "  " + strings.ReplaceAll(strings.TrimSuffix(s, "\n"), "\n", "\n  ") + "\n"
+}
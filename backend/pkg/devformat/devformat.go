@@ -0,0 +1,153 @@
+// Package devformat is the validation and fixing engine behind the DevFormat
+// HTTP API and CLI, for embedding in other Go programs:
+//
+//	res, err := devformat.Validate(ctx, devformat.Request{
+//		Content:  manifest,
+//		Filename: "k8s/deploy.yaml",
+//		Schema:   "kubernetes",
+//		Rules:    []string{"all"},
+//	})
+//	if err != nil {
+//		return err // the request itself is invalid
+//	}
+//	for _, p := range res.Problems {
+//		fmt.Printf("%d:%d %s: %s\n", p.Line, p.Column, p.Severity, p.Message)
+//	}
+//
+// Problems in the content are reported in the result; an error is returned
+// only for invalid requests or a cancelled context.
+package devformat
+
+import (
+	"devformat/backend/internal/config"
+	"devformat/backend/internal/types"
+)
+
+// Request describes the content to validate or fix and how.
+type Request struct {
+	// Content is the YAML or JSON to process.
+	Content string
+	// Filename is matched against the configuration's ignore and schema
+	// globs. It is optional.
+	Filename string
+	// Schema selects schema checks: "kubernetes", "json" or "custom" (with
+	// SchemaContent), "helm", "docker-compose" or "github-actions". Empty
+	// uses the configuration's default for Filename, if any.
+	Schema string
+	// SchemaContent is a JSON Schema (as JSON or YAML) for Schema "json" or "custom".
+	SchemaContent string
+	// Values is the YAML of the values that Helm templates in Content are
+	// rendered with before validation. Schema "helm" checks the rendered
+	// manifests against the Kubernetes schemas.
+	Values string
+	// Env is the content of the .env file whose variables a docker-compose
+	// file is interpolated with before validation.
+	Env string
+	// KubernetesVersion selects the bundled Kubernetes schemas, e.g. "1.28".
+	KubernetesVersion string
+	// Rules lists lint rule IDs to apply, or "all".
+	Rules []string
+	// Fixes lists the fixers Fix applies, or "all"; none selects
+	// "indentation" only.
+	Fixes []string
+	// Indent overrides the configured indentation of fixed content.
+	Indent int
+	// DuplicateKeys is how the duplicate-keys fixer resolves repeated keys:
+	// "keep-first", "keep-last" (the default) or "merge".
+	DuplicateKeys string
+	// UseAI allows falling back to AI suggestions for broken documents.
+	UseAI bool
+	// Config is the repository configuration to honor; nil uses none.
+	Config *Config
+}
+
+// Problem is a single finding. Lines and columns are 1-based and relative to
+// the whole content; Line is 0 for problems not tied to a position.
+type Problem struct {
+	Line     int
+	Column   int
+	Message  string
+	Severity string
+	// Type is "syntax", "schema", "template", "chart" (Chart.yaml and chart
+	// layout checks of LintChart), "kustomize" (build problems of
+	// BuildKustomization), "compose" (interpolation and project checks of
+	// docker-compose files), "workflow" (job, expression and uses checks of
+	// GitHub Actions workflows), "autofix" or a lint rule ID.
+	Type string
+	// Path is the JSON pointer of the offending value for schema problems.
+	Path string
+}
+
+// Result is the outcome of Validate.
+type Result struct {
+	// Valid reports whether no problems were found.
+	Valid    bool
+	Problems []Problem
+	// Format is the detected content format: "yaml", "json", "toml", "hcl",
+	// "ini", "dotenv" or "xml". Only YAML and JSON get schema and lint checks.
+	Format string
+	// FormatConfidence is how sure the detection is: "high", "medium" or "low".
+	FormatConfidence string
+	CanAutoFix       bool
+	// Ignored reports that the configuration excludes Filename.
+	Ignored     bool
+	Explanation string
+	// Suggestions are snippets proposed for documents that failed to parse.
+	Suggestions []Suggestion
+	// Rendered is the output of a Helm template, which Problems refer to by
+	// "rendered line" while their Line is the template line.
+	Rendered string
+}
+
+// Suggestion is a proposed replacement of a line range, with a stable ID.
+type Suggestion = types.Suggestion
+
+// Change describes one modification made by Fix.
+type Change = types.Change
+
+// FixResult is the outcome of Fix. When the content cannot be fixed
+// automatically, CanAutoFix is false and Problems and Suggestions explain why.
+type FixResult struct {
+	Content string
+	Changes []Change
+	// Diff is a unified diff from the request content to Content, and Hunks
+	// the same changes without context; both are empty when nothing changed.
+	Diff  string
+	Hunks []Hunk
+	// Applied lists, per fixed document, the fixers that changed it.
+	Applied []DocumentFixes
+	// Valid reports whether the fixed content is free of problems.
+	Valid       bool
+	Problems    []Problem
+	CanAutoFix  bool
+	Ignored     bool
+	Suggestions []Suggestion
+	Explanation string
+}
+
+// Hunk is one change made by Fix, attributed to the fix that made it.
+type Hunk = types.FixHunk
+
+// DocumentFixes names the fixers that changed one document.
+type DocumentFixes = types.DocumentFixes
+
+// Config is a parsed .devformat.yaml repository configuration.
+type Config = config.Config
+
+// ConfigFileName is the name of the repository configuration file.
+const ConfigFileName = config.FileName
+
+// ParseConfig parses .devformat.yaml content.
+func ParseConfig(data []byte) (*Config, error) {
+	return config.Parse(data)
+}
+
+// LoadConfig reads and parses a configuration file.
+func LoadConfig(path string) (*Config, error) {
+	return config.Load(path)
+}
+
+// FindConfig looks for ConfigFileName in dir and its parents.
+func FindConfig(dir string) (string, bool) {
+	return config.Find(dir)
+}
@@ -0,0 +1,426 @@
+package devformat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"devformat/backend/internal/ai"
+	"devformat/backend/internal/config"
+	"devformat/backend/internal/diff"
+	"devformat/backend/internal/dupkeys"
+	"devformat/backend/internal/fixer"
+	"devformat/backend/internal/parser"
+	sugg "devformat/backend/internal/suggestions"
+	"devformat/backend/internal/types"
+)
+
+// ErrInvalidFixes is returned by Fix when req.Fixes names an unknown fixer
+// or req.DuplicateKeys an unknown mode.
+var ErrInvalidFixes = errors.New("invalid fix types")
+
+// Fix repairs each YAML document of req.Content with the fixers selected by
+// req.Fixes. Text fixers only touch the lines they repair, so comments, key
+// order, anchors and scalar styles are preserved; documents that a node
+// fixer or the Kubernetes fixes edit get those edits spliced into their
+// lines (see fixer.Snapshot.Patch) and are re-encoded from their node tree,
+// using the configured indentation, only when an edit has no splice.
+// Document markers, directives and "..."
+// are written back as they were, and content no fixer changes is returned
+// byte for byte. Content that cannot be fixed safely is
+// answered with problems and, where possible, suggested snippets instead. An
+// error is returned for unknown fixers, a cancelled context or when the fixed
+// content cannot be encoded.
+//
+// JSON content is read leniently instead and returned as pretty-printed JSON;
+// see fixJSON. req.Indent, when set, overrides the configured indentation.
+// Helm templates are never fixed; the problems of their rendered output are
+// reported as Validate finds them.
+func Fix(ctx context.Context, req Request) (FixResult, error) {
+	fixers, err := fixer.Select(req.Fixes)
+	if err != nil {
+		return FixResult{}, fmt.Errorf("%w: %v", ErrInvalidFixes, err)
+	}
+	dupMode, err := dupkeys.ParseMode(req.DuplicateKeys)
+	if err != nil {
+		return FixResult{}, fmt.Errorf("%w: %v", ErrInvalidFixes, err)
+	}
+	cfg := req.Config
+	if cfg.Ignored(req.Filename) {
+		return FixResult{
+			Content:     req.Content,
+			Changes:     []Change{},
+			Hunks:       []Hunk{},
+			Applied:     []DocumentFixes{},
+			Valid:       true,
+			Problems:    []Problem{},
+			Ignored:     true,
+			Explanation: fmt.Sprintf("%s is ignored by %s", req.Filename, config.FileName),
+		}, nil
+	}
+	if parser.ContainsHelmTemplate(req.Content) {
+		// templates are not rewritten, but their rendered output is checked
+		res, err := Validate(ctx, req)
+		if err != nil {
+			return FixResult{}, err
+		}
+		return unfixable(res.Problems, []Suggestion{}, "Helm templates cannot be auto-fixed. "+res.Explanation+"."), nil
+	}
+	if req.Schema == "" {
+		req.Schema = cfg.SchemaFor(req.Filename)
+	}
+	indent := cfg.IndentWidth()
+	if req.Indent > 0 {
+		indent = req.Indent
+	}
+	settings := fixer.Settings{Indent: indent, DuplicateKeys: dupMode}
+	switch det := parser.Detect(req.Content, req.Filename); det.Format {
+	case parser.FormatJSON:
+		return fixJSON(ctx, req, fixers, settings)
+	case parser.FormatYAML:
+	default:
+		return unfixable([]Problem{}, []Suggestion{}, fmt.Sprintf("Detected %s format (%s); only YAML and JSON can be fixed.", det.Format, det.Reason)), nil
+	}
+
+	// Determine whether auto-fix is allowed for this content
+	autoFixAllowed := true
+	autoFixLine, autoFixCol := 0, 0
+	if ok, reason := fixer.CanAutoFixContent(req.Content); !ok {
+		autoFixAllowed = false
+		autoFixLine, autoFixCol = parser.YAMLErrorPosition(req.Content, errors.New(reason))
+	}
+
+	docs := parser.SplitYAMLDocuments(req.Content)
+	var outBuilder strings.Builder
+	changes := []Change{}
+	applied := []DocumentFixes{}
+	var addedNames []string
+	trace := newFixTrace()
+	// fixedLines counts the lines written to outBuilder
+	fixedLines := 0
+	// unchanged stays set while every document comes out as it went in
+	unchanged := true
+
+	for i, d := range docs {
+		if err := ctx.Err(); err != nil {
+			return FixResult{}, err
+		}
+		doc := d.Content
+		lineOffset := d.StartLine - 1
+		trimmed := strings.TrimSpace(doc)
+		if trimmed == "" {
+			continue
+		}
+
+		repaired, stages, err := applyTextFixers(doc, fixers, settings)
+		fired := []string{}
+		for _, st := range stages {
+			fired = append(fired, st.fixer)
+		}
+		var node *yaml.Node
+		if err == nil {
+			node, err = parser.ParseYAMLNode(repaired)
+		}
+		modified := false
+		var snap fixer.Snapshot
+		if err == nil {
+			snap = fixer.TakeSnapshot(node)
+			for _, f := range fixers {
+				if f.Node == nil || !f.Node(node, settings) {
+					continue
+				}
+				fired = append(fired, f.ID)
+				modified = true
+				text, err := renderDocument(node, snap, repaired, true, indent)
+				if err != nil {
+					return FixResult{}, err
+				}
+				stages = append(stages, stage{f.ID, text})
+			}
+			// keys still duplicated without the duplicate-keys fixer fail here
+			var v any
+			if err = node.Decode(&v); onlyDuplicateKeys(err) {
+				return unfixable(duplicateProblems(dupkeys.FindYAML(node), lineOffset), nil, duplicateKeysExplanation), nil
+			}
+		}
+		if err != nil {
+			line, col := parser.YAMLErrorPosition(doc, err)
+			if line > 0 {
+				line += lineOffset
+			}
+			syntaxErr := []Problem{{Line: line, Column: col, Message: fmt.Sprintf("YAML syntax error in document %d: %s", i+1, parser.YAMLErrorMessage(err)), Severity: "error", Type: "syntax"}}
+			// try to produce suggestions instead of outright failing
+			suggestions, _ := sugg.SuggestYAML(doc, err)
+			offsetSuggestions(suggestions, lineOffset)
+			if len(suggestions) > 0 {
+				return unfixable(syntaxErr, suggestions, "Auto-fix could not be applied automatically. Suggestions are provided for manual review."), nil
+			}
+
+			// If no heuristic suggestions and user requested AI, try AI
+			if req.UseAI {
+				if aiSug := ai.CallGeminiSuggest(doc); len(aiSug) > 0 {
+					offsetSuggestions(aiSug, lineOffset)
+					return unfixable(syntaxErr, aiSug, "Auto-fix could not be applied automatically. AI suggestions are provided for manual review."), nil
+				}
+			}
+			return unfixable(syntaxErr, nil, ""), nil
+		}
+
+		// If auto-fix is not allowed for this content, return the suggested snippet instead
+		if !autoFixAllowed {
+			snippet, err := renderDocument(node, snap, repaired, modified, indent)
+			if err != nil {
+				return FixResult{}, err
+			}
+			end := d.StartLine + strings.Count(strings.TrimRight(doc, "\n"), "\n")
+			suggestions := []Suggestion{types.NewSuggestion(types.SourceHeuristic, "Suggested fixes (auto-fix disabled)", types.ConfidenceMedium, d.StartLine, end, snippet)}
+			return unfixable([]Problem{{Line: autoFixLine, Column: autoFixCol, Message: "auto-fix disabled for this content. Suggestions provided.", Severity: "warning", Type: "autofix"}}, suggestions, "Auto-fix disabled for safety. Please review suggestions before applying."), nil
+		}
+
+		root := fixer.Root(node)
+		metadata := fixer.MappingValue(root, "metadata")
+
+		// Refuse to auto-fix structural issues that require human judgement:
+		if metadata != nil && fixer.IsNull(metadata) {
+			line := topLevelKeyLine(doc, "metadata")
+			return unfixable([]Problem{{Line: line + lineOffset, Column: 1, Message: "auto-fix refused: `metadata` is null. Please correct the document manually.", Severity: "warning", Type: "autofix"}}, nil, ""), nil
+		}
+
+		if fixer.MappingValue(root, "name") != nil && fixer.IsNull(fixer.MappingValue(metadata, "name")) {
+			line := topLevelKeyLine(doc, "name")
+			return unfixable([]Problem{{Line: line + lineOffset, Column: 1, Message: "auto-fix refused: top-level `name` detected. Move `name` into `metadata.name` manually.", Severity: "warning", Type: "autofix"}}, nil, ""), nil
+		}
+
+		// Optional schema-aware fixes for Kubernetes, spliced into the
+		// repaired text like the node fixes.
+		if req.Schema == "kubernetes" {
+			name := fmt.Sprintf("autofix-%d-%d", time.Now().Unix(), i+1)
+			line := d.StartLine
+			if metadata != nil {
+				line = topLevelKeyLine(doc, "metadata") + lineOffset
+			}
+			if addMetadataName(root, name) {
+				modified = true
+				fired = append(fired, metadataNameFix)
+				addedNames = append(addedNames, name)
+				changes = append(changes, Change{Line: line, Description: fmt.Sprintf("added metadata.name: %s", name)})
+				text, err := renderDocument(node, snap, repaired, true, indent)
+				if err != nil {
+					return FixResult{}, err
+				}
+				stages = append(stages, stage{metadataNameFix, text})
+			}
+		}
+
+		out, err := renderDocument(node, snap, repaired, modified, indent)
+		if err != nil {
+			return FixResult{}, err
+		}
+		if out != withNewline(doc) {
+			unchanged = false
+		}
+		before, written := writeDocument(&outBuilder, req.Content, d, out)
+		trace.document(doc, stages, lineOffset, fixedLines+before)
+		fixedLines += written
+		applied = append(applied, DocumentFixes{Document: i + 1, StartLine: d.StartLine, Fixers: fired})
+	}
+
+	fixed := outBuilder.String()
+	if unchanged {
+		fixed = req.Content
+	}
+	valid, problems, err := revalidate(ctx, req, fixed)
+	if err != nil {
+		return FixResult{}, err
+	}
+	from, to := diffLabels(req.Filename)
+	explanation := "Applied YAML formatting fixes."
+	if len(addedNames) > 0 {
+		explanation = "Applied YAML formatting fixes and added missing Kubernetes metadata."
+	}
+	return FixResult{
+		Content:     fixed,
+		Changes:     changes,
+		Diff:        diff.Unified(from, to, req.Content, fixed),
+		Hunks:       fixHunks(req.Content, fixed, trace),
+		Applied:     applied,
+		Valid:       valid,
+		Problems:    problems,
+		CanAutoFix:  true,
+		Explanation: explanation,
+	}, nil
+}
+
+// duplicateKeysExplanation answers content whose keys stay duplicated
+// because the duplicate-keys fixer was not selected.
+const duplicateKeysExplanation = "Duplicate keys remain; select the duplicate-keys fix to resolve them."
+
+// revalidate validates fixed content with the options of the fix request,
+// without AI suggestions, and returns whether it is valid with its problems.
+func revalidate(ctx context.Context, req Request, fixed string) (bool, []Problem, error) {
+	if strings.TrimSpace(fixed) == "" {
+		return true, []Problem{}, nil
+	}
+	vreq := req
+	vreq.Content = fixed
+	vreq.UseAI = false
+	res, err := Validate(ctx, vreq)
+	if err != nil {
+		return false, nil, err
+	}
+	if res.Problems == nil {
+		res.Problems = []Problem{}
+	}
+	return res.Valid, res.Problems, nil
+}
+
+// writeDocument appends a fixed document to the reassembled stream with
+// the comments, directives, "---" marker and "..." it had in content, and
+// the comments after it. Documents after
+// the first are always separated by a marker. It returns the number of lines
+// written before out and in all.
+func writeDocument(b *strings.Builder, content string, d parser.Document, out string) (int, int) {
+	before := len(d.Directives)
+	if d.Leading != "" {
+		b.WriteString(d.Leading + "\n")
+		before += strings.Count(d.Leading, "\n") + 1
+	}
+	for _, dir := range d.Directives {
+		b.WriteString(dir + "\n")
+	}
+	// a document whose content starts on its marker line, as in "--- |",
+	// carries the marker itself
+	if !strings.HasPrefix(out, "---") {
+		switch {
+		case d.Explicit:
+			b.WriteString(markerLine(content, d.Offset))
+			before++
+		case b.Len() > 0:
+			b.WriteString("---\n")
+			before++
+		}
+	}
+	b.WriteString(out)
+	written := before + strings.Count(out, "\n")
+	if d.Ended {
+		b.WriteString("...\n")
+		written++
+	}
+	if d.Trailing != "" {
+		b.WriteString(d.Trailing + "\n")
+		written += strings.Count(d.Trailing, "\n") + 1
+	}
+	return before, written
+}
+
+// markerLine returns the "---" line, with any comment, that precedes the
+// document content starting at offset.
+func markerLine(content string, offset int) string {
+	if offset == 0 {
+		return "---\n"
+	}
+	start := strings.LastIndexByte(content[:offset-1], '\n') + 1
+	line := strings.TrimSuffix(content[start:offset], "\r\n")
+	line = strings.TrimSuffix(line, "\n")
+	if !strings.HasPrefix(strings.TrimPrefix(line, "\ufeff"), "---") {
+		return "---\n"
+	}
+	return strings.TrimPrefix(line, "\ufeff") + "\n"
+}
+
+// unfixable builds the result for content that is not fixed automatically.
+func unfixable(problems []Problem, suggestions []Suggestion, explanation string) FixResult {
+	return FixResult{
+		Changes:     []Change{},
+		Hunks:       []Hunk{},
+		Applied:     []DocumentFixes{},
+		Valid:       false,
+		Problems:    problems,
+		CanAutoFix:  false,
+		Suggestions: suggestions,
+		Explanation: explanation,
+	}
+}
+
+// metadataNameFix is the ID DocumentFixes lists for a metadata.name added by
+// the Kubernetes schema fixes, which are not one of the selectable fixers.
+const metadataNameFix = types.FixKindMetadataName
+
+// addMetadataName gives a Kubernetes object without metadata.name the given
+// name, adding a metadata mapping after "kind" when there is none. It reports
+// whether root was changed.
+func addMetadataName(root *yaml.Node, name string) bool {
+	if root == nil || root.Kind != yaml.MappingNode {
+		return false
+	}
+	metadata := fixer.MappingValue(root, "metadata")
+	switch {
+	case metadata == nil:
+		fixer.InsertMappingPair(root, "kind", "metadata", fixer.StringMapping("name", name))
+	case metadata.Kind == yaml.MappingNode && fixer.MappingValue(metadata, "name") == nil:
+		metadata.Content = append(fixer.StringMapping("name", name).Content, metadata.Content...)
+	default:
+		return false
+	}
+	return true
+}
+
+// applyTextFixers runs the text fixers among fixers over a document in order
+// and returns the result with the text after each fixer that changed it.
+func applyTextFixers(doc string, fixers []fixer.Fixer, s fixer.Settings) (string, []stage, error) {
+	var stages []stage
+	for _, f := range fixers {
+		if f.Text == nil {
+			continue
+		}
+		out, err := f.Text(doc, s)
+		if err != nil {
+			return "", nil, err
+		}
+		if out != doc {
+			stages = append(stages, stage{f.ID, out})
+			doc = out
+		}
+	}
+	return doc, stages, nil
+}
+
+// renderDocument returns the fixed text of a document: the text fixers'
+// output as-is, or with the edits node fixes made to the tree spliced in.
+// Only edits that have no splice re-encode the whole tree.
+func renderDocument(node *yaml.Node, snap fixer.Snapshot, text string, modified bool, indent int) (string, error) {
+	if !modified {
+		return withNewline(text), nil
+	}
+	if out, ok := snap.Patch(text, node, indent); ok {
+		return withNewline(out), nil
+	}
+	out, err := fixer.EncodeYAMLNode(node, indent)
+	if err != nil {
+		return "", errors.New("failed to encode fixed YAML")
+	}
+	return out, nil
+}
+
+// withNewline terminates s with a newline unless it already ends with one.
+func withNewline(s string) string {
+	if s == "" || strings.HasSuffix(s, "\n") {
+		return s
+	}
+	return s + "\n"
+}
+
+// topLevelKeyLine returns the 1-based line of an unindented "key:" in a YAML
+// document, or 1 when the key cannot be found.
+func topLevelKeyLine(doc, key string) int {
+	for i, line := range strings.Split(doc, "\n") {
+		if strings.HasPrefix(line, key+":") {
+			return i + 1
+		}
+	}
+	return 1
+}
@@ -0,0 +1,156 @@
+package devformat
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestFix(t *testing.T) {
+	tests := []struct {
+		name string
+		req  Request
+		want string
+		// hunks lists "oldStart kind" of every hunk
+		hunks []string
+		// problems lists "line:column type" of the problems of the result
+		problems []string
+	}{
+		{
+			name: "unchanged",
+			req:  Request{Content: "a: 1  # note\n\nb: 'x'\n"},
+			want: "a: 1  # note\n\nb: 'x'\n",
+		},
+		{
+			name:  "indentation",
+			req:   Request{Content: "a:\n  b: 1\n c: 2\n"},
+			want:  "a:\n  b: 1\nc: 2\n",
+			hunks: []string{"3 indentation"},
+		},
+		{
+			name:  "one hunk per fixer",
+			req:   Request{Content: "a: 1  \nb: yes\nc: 'x'\nd:\n  e: 1\n f: 2\n", Fixes: []string{"all"}},
+			want:  "a: 1\nb: true\nc: \"x\"\nd:\n  e: 1\nf: 2\n",
+			hunks: []string{"1 trailing-spaces", "2 boolean-format", "3 quotes", "6 indentation"},
+		},
+		{
+			name:  "comments and blank lines kept by node fixers",
+			req:   Request{Content: "# header\na: yes   # flag\n\nlist:\n    - 'x'\n", Fixes: []string{"quotes", "boolean-format"}},
+			want:  "# header\na: true   # flag\n\nlist:\n    - \"x\"\n",
+			hunks: []string{"2 boolean-format", "5 quotes"},
+		},
+		{
+			name:  "duplicate keys",
+			req:   Request{Content: "a: 1\nb: 2\na: 3\n", Fixes: []string{"duplicate-keys"}},
+			want:  "b: 2\na: 3\n",
+			hunks: []string{"1 duplicate-keys"},
+		},
+		{
+			name:  "leading comments and markers",
+			req:   Request{Content: "# header\n---\na: 'x'\n...\n# next\n---\nb: 'y'\n...\n# end\n", Fixes: []string{"quotes"}},
+			want:  "# header\n---\na: \"x\"\n...\n# next\n---\nb: \"y\"\n...\n# end\n",
+			hunks: []string{"3 quotes", "7 quotes"},
+		},
+		{
+			name: "json",
+			req:  Request{Content: "{'a': 1, // one\n b: [2,],}", Filename: "x.json"},
+			want: "{\n  \"a\": 1,\n  \"b\": [\n    2\n  ]\n}\n",
+			hunks: []string{
+				"1 format",
+			},
+		},
+		{
+			name:  "json5 without a file name",
+			req:   Request{Content: "{\"a\": 1,}"},
+			want:  "{\n  \"a\": 1\n}\n",
+			hunks: []string{"1 format"},
+		},
+		{
+			name:  "json duplicate keys",
+			req:   Request{Content: "{\n  \"a\": 1,\n  \"a\": 2\n}\n", Filename: "x.json", Fixes: []string{"duplicate-keys"}},
+			want:  "{\n  \"a\": 2\n}\n",
+			hunks: []string{"2 duplicate-keys"},
+		},
+		{
+			name:     "schema problems left after fixing",
+			req:      Request{Content: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: x\ndata:\n  k: yes\n", Schema: "kubernetes", Fixes: []string{"boolean-format"}},
+			want:     "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: x\ndata:\n  k: true\n",
+			hunks:    []string{"6 boolean-format"},
+			problems: []string{"6:3 schema"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Fix(context.Background(), tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !res.CanAutoFix {
+				t.Fatalf("CanAutoFix = false: %s %+v", res.Explanation, res.Problems)
+			}
+			if res.Content != tt.want {
+				t.Errorf("Content =\n%s\nwant:\n%s", res.Content, tt.want)
+			}
+			var hunks []string
+			for _, h := range res.Hunks {
+				hunks = append(hunks, fmt.Sprintf("%d %s", h.OldStart, h.Kind))
+			}
+			if got := strings.Join(hunks, ", "); got != strings.Join(tt.hunks, ", ") {
+				t.Errorf("hunks = %s, want %s", got, strings.Join(tt.hunks, ", "))
+			}
+			if got := problemPositions(res.Problems); got != strings.Join(tt.problems, ", ") {
+				t.Errorf("problems = %s, want %s", got, strings.Join(tt.problems, ", "))
+			}
+			if res.Valid != (len(tt.problems) == 0) {
+				t.Errorf("Valid = %v with %d problems", res.Valid, len(res.Problems))
+			}
+		})
+	}
+}
+
+func TestFixUnfixable(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      Request
+		problems []string
+	}{
+		{"unclosed flow sequence", Request{Content: "a: 1\nb: [\n"}, []string{"2:1 syntax"}},
+		{"yaml duplicate keys not selected", Request{Content: "a: 1\na: 2\n"}, []string{"2:1 key-duplicates"}},
+		{"json duplicate keys not selected", Request{Content: `{"a": 1, "a": 2}`, Filename: "x.json"}, []string{"1:10 key-duplicates"}},
+		{"json syntax", Request{Content: `{"a": [1,`, Filename: "x.json"}, []string{"1:7 syntax"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Fix(context.Background(), tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.CanAutoFix || res.Valid || res.Content != "" {
+				t.Errorf("Fix() = %+v, want it to refuse", res)
+			}
+			if got := problemPositions(res.Problems); got != strings.Join(tt.problems, ", ") {
+				t.Errorf("problems = %s, want %s", got, strings.Join(tt.problems, ", "))
+			}
+		})
+	}
+}
+
+func TestFixMetadataName(t *testing.T) {
+	res, err := Fix(context.Background(), Request{
+		Content: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  labels: {}\n",
+		Schema:  "kubernetes",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Hunks) != 1 || res.Hunks[0].Kind != metadataNameFix || !strings.HasPrefix(strings.TrimSpace(strings.Join(res.Hunks[0].After, "")), "name: autofix-") {
+		t.Errorf("hunks = %+v, want one metadata-name insertion", res.Hunks)
+	}
+	if len(res.Applied) != 1 || strings.Join(res.Applied[0].Fixers, ",") != metadataNameFix {
+		t.Errorf("Applied = %+v", res.Applied)
+	}
+	if !res.Valid {
+		t.Errorf("Valid = false: %+v", res.Problems)
+	}
+}
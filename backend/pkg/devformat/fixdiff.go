@@ -0,0 +1,133 @@
+package devformat
+
+import (
+	"devformat/backend/internal/diff"
+	"devformat/backend/internal/types"
+)
+
+// diffLabels returns the unified diff file labels for a request.
+func diffLabels(filename string) (string, string) {
+	if filename == "" {
+		filename = "content"
+	}
+	return "a/" + filename, "b/" + filename
+}
+
+// fixHunks lists the changes from original to fixed without context and
+// attributes each to the fix that made it, as recorded by trace. A hunk that
+// replaces lines one for one is split where the fix changes, since adjacent
+// lines are often fixed by different fixers.
+func fixHunks(original, fixed string, trace *fixTrace) []Hunk {
+	hunks := []Hunk{}
+	for _, h := range diff.Hunks(original, fixed, 0) {
+		fh := Hunk{OldStart: h.OldStart, OldLines: h.OldLines, NewStart: h.NewStart, NewLines: h.NewLines, Before: []string{}, After: []string{}}
+		for _, l := range h.Lines {
+			switch l.Op {
+			case diff.Delete:
+				fh.Before = append(fh.Before, l.Text)
+			case diff.Insert:
+				fh.After = append(fh.After, l.Text)
+			}
+		}
+		if len(fh.Before) != len(fh.After) {
+			fh.Kind = trace.kind(fh)
+			hunks = append(hunks, fh)
+			continue
+		}
+		for start := 0; start < len(fh.Before); {
+			part := linePair(fh, start)
+			part.Kind = trace.kind(part)
+			end := start + 1
+			for end < len(fh.Before) && trace.kind(linePair(fh, end)) == part.Kind {
+				end++
+			}
+			part.OldLines, part.NewLines = end-start, end-start
+			part.Before, part.After = fh.Before[start:end], fh.After[start:end]
+			hunks = append(hunks, part)
+			start = end
+		}
+	}
+	return hunks
+}
+
+// linePair returns the i-th line of a one-for-one replacement as a hunk.
+func linePair(h Hunk, i int) Hunk {
+	return Hunk{OldStart: h.OldStart + i, OldLines: 1, NewStart: h.NewStart + i, NewLines: 1, Before: h.Before[i : i+1], After: h.After[i : i+1]}
+}
+
+// stage is the text of a document after a fix changed it.
+type stage struct {
+	fixer string
+	text  string
+}
+
+// fixTrace records which fix removed each line of the original content and
+// which wrote each line of the fixed content.
+type fixTrace struct {
+	// removed and added are keyed by 0-based line.
+	removed map[int]string
+	added   map[int]string
+}
+
+func newFixTrace() *fixTrace {
+	return &fixTrace{removed: map[int]string{}, added: map[int]string{}}
+}
+
+// document follows a document through the stages it went through. Its text
+// starts at 0-based line from of the original content and at line to of the
+// fixed content.
+func (t *fixTrace) document(text string, stages []stage, from, to int) {
+	cur := diff.SplitLines(text)
+	// origin is the line of text each current line still is, or -1 for a
+	// line a fix wrote; by names that fix
+	origin := make([]int, len(cur))
+	for i := range origin {
+		origin[i] = i
+	}
+	by := make([]string, len(cur))
+	for _, st := range stages {
+		next := diff.SplitLines(st.text)
+		nextOrigin := make([]int, 0, len(next))
+		nextBy := make([]string, 0, len(next))
+		i := 0
+		for _, l := range diff.Lines(cur, next) {
+			switch l.Op {
+			case diff.Equal:
+				nextOrigin = append(nextOrigin, origin[i])
+				nextBy = append(nextBy, by[i])
+				i++
+			case diff.Delete:
+				if origin[i] >= 0 {
+					t.removed[from+origin[i]] = st.fixer
+				}
+				i++
+			case diff.Insert:
+				nextOrigin = append(nextOrigin, -1)
+				nextBy = append(nextBy, st.fixer)
+			}
+		}
+		cur, origin, by = next, nextOrigin, nextBy
+	}
+	for i, id := range by {
+		if id != "" {
+			t.added[to+i] = id
+		}
+	}
+}
+
+// kind returns the fix that removed the first traced line of a hunk or, for
+// a hunk that only adds lines, wrote the first one. Lines no fix touched,
+// such as document markers, make it FixKindFormat.
+func (t *fixTrace) kind(h Hunk) string {
+	for i := 0; i < h.OldLines; i++ {
+		if id, ok := t.removed[h.OldStart-1+i]; ok {
+			return id
+		}
+	}
+	for i := 0; i < h.NewLines; i++ {
+		if id, ok := t.added[h.NewStart-1+i]; ok {
+			return id
+		}
+	}
+	return types.FixKindFormat
+}
@@ -0,0 +1,111 @@
+package devformat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"devformat/backend/internal/diff"
+	"devformat/backend/internal/dupkeys"
+	"devformat/backend/internal/fixer"
+	"devformat/backend/internal/json5"
+	"devformat/backend/internal/types"
+)
+
+// fixJSON is Fix for JSON content. The content is read leniently, so the
+// usual hand-editing mistakes (comments, trailing or missing commas, single
+// quotes, unquoted keys, hex numbers, NaN and Infinity) are repaired and each
+// repair is reported as a change. The selected node fixers then run on the
+// tree and the result is written as pretty-printed JSON. Text fixers do not
+// apply: the output is re-indented as a whole.
+func fixJSON(ctx context.Context, req Request, fixers []fixer.Fixer, s fixer.Settings) (FixResult, error) {
+	if err := ctx.Err(); err != nil {
+		return FixResult{}, err
+	}
+	node, repairs, err := json5.Parse(req.Content)
+	if err != nil {
+		var se *json5.SyntaxError
+		line, col, msg := 0, 0, err.Error()
+		if errors.As(err, &se) {
+			line, col, msg = se.Line, se.Column, se.Msg
+		}
+		return unfixable([]Problem{{Line: line, Column: col, Message: "JSON syntax error: " + msg, Severity: "error", Type: "syntax"}}, nil, ""), nil
+	}
+
+	// the lenient read is traced as reformatting, each node fixer by its ID
+	text, err := json5.Encode(node, s.Indent)
+	if err != nil {
+		return FixResult{}, fmt.Errorf("failed to encode fixed JSON: %w", err)
+	}
+	stages := []stage{{types.FixKindFormat, text}}
+	fired := []string{}
+	for _, f := range fixers {
+		if f.Node == nil || !f.Node(node, s) {
+			continue
+		}
+		fired = append(fired, f.ID)
+		if text, err = json5.Encode(node, s.Indent); err != nil {
+			return FixResult{}, fmt.Errorf("failed to encode fixed JSON: %w", err)
+		}
+		stages = append(stages, stage{f.ID, text})
+	}
+	// as in the YAML path, keys stay duplicated unless duplicate-keys ran
+	if dups := dupkeys.FindYAML(node); len(dups) > 0 {
+		return unfixable(duplicateProblems(dups, 0), nil, duplicateKeysExplanation), nil
+	}
+
+	changes := []Change{}
+	for _, r := range repairs {
+		changes = append(changes, Change{Line: r.Line, Column: r.Column, Description: r.Message})
+	}
+	var addedNames []string
+	if req.Schema == "kubernetes" {
+		root := fixer.Root(node)
+		name := fmt.Sprintf("autofix-%d-1", time.Now().Unix())
+		line := 1
+		if root != nil {
+			line = root.Line
+			for i := 0; i+1 < len(root.Content); i += 2 {
+				if root.Content[i].Value == "metadata" {
+					line = root.Content[i].Line
+				}
+			}
+		}
+		if addMetadataName(root, name) {
+			fired = append(fired, metadataNameFix)
+			addedNames = append(addedNames, name)
+			changes = append(changes, Change{Line: line, Description: fmt.Sprintf("added metadata.name: %s", name)})
+		}
+	}
+
+	fixed, err := json5.Encode(node, s.Indent)
+	if err != nil {
+		return FixResult{}, fmt.Errorf("failed to encode fixed JSON: %w", err)
+	}
+	if len(addedNames) > 0 {
+		stages = append(stages, stage{metadataNameFix, fixed})
+	}
+	trace := newFixTrace()
+	trace.document(req.Content, stages, 0, 0)
+	valid, problems, err := revalidate(ctx, req, fixed)
+	if err != nil {
+		return FixResult{}, err
+	}
+	explanation := "Applied JSON formatting fixes."
+	if len(addedNames) > 0 {
+		explanation = "Applied JSON formatting fixes and added missing Kubernetes metadata."
+	}
+	from, to := diffLabels(req.Filename)
+	return FixResult{
+		Content:     fixed,
+		Changes:     changes,
+		Diff:        diff.Unified(from, to, req.Content, fixed),
+		Hunks:       fixHunks(req.Content, fixed, trace),
+		Applied:     []DocumentFixes{{Document: 1, StartLine: 1, Fixers: fired}},
+		Valid:       valid,
+		Problems:    problems,
+		CanAutoFix:  true,
+		Explanation: explanation,
+	}, nil
+}
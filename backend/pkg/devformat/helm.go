@@ -0,0 +1,137 @@
+package devformat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"devformat/backend/internal/helm"
+	"devformat/backend/internal/parser"
+)
+
+// ErrInvalidValues is returned when req.Values is not a YAML mapping.
+var ErrInvalidValues = errors.New("invalid values")
+
+// validateTemplate renders content holding Helm template markers with
+// req.Values and runs the rendered manifests through the pipeline. Schema
+// "helm" checks them against the Kubernetes schemas. Every problem is
+// reported at the template line its rendered line came from.
+func validateTemplate(ctx context.Context, req Request, opts validationOptions, problems []Problem) (Result, error) {
+	values, err := parseValues(req.Values)
+	if err != nil {
+		return Result{}, err
+	}
+	if opts.schemaName == "helm" {
+		var warning *Problem
+		if opts.k8sVersion, warning, err = resolveKubernetesVersion(req.KubernetesVersion); err != nil {
+			return Result{}, err
+		}
+		if warning != nil {
+			problems = append(problems, *warning)
+		}
+	}
+	name := req.Filename
+	if name == "" {
+		name = "template.yaml"
+	}
+
+	r := helm.Render(ctx, []helm.File{{Name: name, Content: req.Content}}, helm.Options{Values: values, KubeVersion: opts.k8sVersion})[0]
+	if r.Err != nil {
+		problems = append(problems, Problem{Line: r.Err.Line, Column: r.Err.Column, Message: "Helm template error: " + r.Err.Message, Severity: "error", Type: "template"})
+		return Result{
+			Valid:       false,
+			Problems:    problems,
+			Format:      "yaml",
+			Explanation: "Helm template failed to render",
+			Suggestions: []Suggestion{},
+		}, nil
+	}
+
+	found, failed, err := checkRendered(ctx, r, map[string]string{name: req.Content}, opts)
+	if err != nil {
+		return Result{}, err
+	}
+	for _, p := range found {
+		problems = append(problems, p.Problem)
+	}
+	problems = suppressProblems(req.Content, problems)
+	sortProblems(problems)
+
+	explanation := "Rendered as a Helm template and validated the output"
+	if len(failed) > 0 {
+		explanation = "Rendered Helm template: " + syntaxExplanation("yaml", failed)
+	}
+	return Result{
+		Valid:       len(problems) == 0,
+		Problems:    problems,
+		Format:      "yaml",
+		Explanation: explanation,
+		// suggestions would replace template lines with rendered text
+		Suggestions: []Suggestion{},
+		Rendered:    r.Content,
+	}, nil
+}
+
+// templateProblem is a problem in rendered output, placed in the template
+// that produced the offending line.
+type templateProblem struct {
+	template string
+	Problem
+}
+
+// checkRendered runs the output of a template through the pipeline and
+// places each problem at the template line its rendered line came from.
+// templates holds the text of every template by name. It also returns the
+// numbers of the rendered documents that failed to parse.
+func checkRendered(ctx context.Context, r helm.Rendered, templates map[string]string, opts validationOptions) ([]templateProblem, []int, error) {
+	checked, err := validateDocuments(ctx, parser.SplitYAMLDocuments(r.Content), "yaml", opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	renderedLines := strings.Split(r.Content, "\n")
+	out := make([]templateProblem, 0, len(checked.problems))
+	for _, p := range checked.problems {
+		tp := templateProblem{template: r.Name, Problem: p}
+		if p.Line > 0 {
+			src := r.Source(p.Line)
+			templateLines := strings.Split(templates[src.Template], "\n")
+			// a column is kept while the rendered line matches the template
+			// line up to it; a value produced by an action then points at
+			// the action
+			if src.Line < 1 || src.Line > len(templateLines) || p.Column-1 > commonPrefix(templateLines[src.Line-1], renderedLines[p.Line-1]) {
+				tp.Column = 0
+			}
+			tp.Message = fmt.Sprintf("%s (rendered line %d)", p.Message, p.Line)
+			tp.Line = src.Line
+			if src.Template != "" {
+				tp.template = src.Template
+			}
+		}
+		out = append(out, tp)
+	}
+	return out, checked.failed, nil
+}
+
+// parseValues reads the YAML values Helm templates are rendered with.
+func parseValues(content string) (map[string]any, error) {
+	values := map[string]any{}
+	if strings.TrimSpace(content) == "" {
+		return values, nil
+	}
+	if err := yaml.Unmarshal([]byte(content), &values); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidValues, err)
+	}
+	return values, nil
+}
+
+// commonPrefix returns the length of the longest common prefix of a and b.
+func commonPrefix(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,129 @@
+package devformat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"devformat/backend/internal/archive"
+	"devformat/backend/internal/fixer"
+	"devformat/backend/internal/kubernetes"
+	"devformat/backend/internal/kustomize"
+)
+
+// ErrInvalidKustomization is returned by BuildKustomization when the archive
+// cannot be read or holds no kustomization at the requested path.
+var ErrInvalidKustomization = errors.New("invalid kustomization archive")
+
+// KustomizeRequest describes a kustomization to build.
+type KustomizeRequest struct {
+	// Archive holds the kustomization with every base it refers to, as a
+	// .tgz or .zip.
+	Archive []byte
+	// Path is the directory of the kustomization to build within the
+	// archive, e.g. "overlays/prod"; empty picks the shallowest one.
+	Path string
+	// KubernetesVersion selects the bundled Kubernetes schemas the output is
+	// checked against.
+	KubernetesVersion string
+}
+
+// KustomizeResult is the outcome of BuildKustomization.
+type KustomizeResult struct {
+	// Valid reports whether the kustomization built without errors and its
+	// output passed validation.
+	Valid bool
+	// Content is the built YAML stream, one document per resource.
+	Content string
+	// Files lists the archive files with problems, in name order. Build
+	// problems are reported in the kustomization file at the offending
+	// entry; problems of the output in the manifest or patch that set the
+	// offending value.
+	Files []FileProblems
+}
+
+// BuildKustomization builds a kustomization in-process, the way "kustomize
+// build" does, and validates every resource of the output against the
+// Kubernetes schemas. An error is returned for an unreadable archive, an
+// unsupported Kubernetes version or a cancelled context.
+func BuildKustomization(ctx context.Context, req KustomizeRequest) (KustomizeResult, error) {
+	version, err := kubernetes.NormalizeVersion(req.KubernetesVersion)
+	if err != nil {
+		return KustomizeResult{}, err
+	}
+	files, err := archive.Unpack(req.Archive)
+	if err != nil {
+		return KustomizeResult{}, fmt.Errorf("%w: %v", ErrInvalidKustomization, err)
+	}
+	dir, ok := archive.Root(files, kustomize.FileNames...)
+	if req.Path != "" {
+		dir = path.Clean(strings.Trim(req.Path, "/"))
+		if dir == "." {
+			dir = ""
+		}
+		_, ok = kustomize.File(files, dir)
+	}
+	if !ok {
+		return KustomizeResult{}, fmt.Errorf("%w: no kustomization.yaml in %q", ErrInvalidKustomization, "/"+dir)
+	}
+
+	resources, buildErrs := kustomize.Build(files, dir)
+	problems := map[string][]Problem{}
+	for _, e := range buildErrs {
+		column := 1
+		if e.Line == 0 {
+			column = 0
+		}
+		problems[e.File] = append(problems[e.File], Problem{Line: e.Line, Column: column, Message: e.Message, Severity: "error", Type: "kustomize"})
+	}
+
+	var out strings.Builder
+	for _, r := range resources {
+		if err := ctx.Err(); err != nil {
+			return KustomizeResult{}, err
+		}
+		var node yaml.Node
+		if err := node.Encode(r.Object); err != nil {
+			return KustomizeResult{}, err
+		}
+		text, err := fixer.EncodeYAMLNode(&node, 2)
+		if err != nil {
+			return KustomizeResult{}, err
+		}
+		if out.Len() > 0 {
+			out.WriteString("---\n")
+		}
+		out.WriteString(text)
+
+		// problems are placed by path, so the output's lines are not needed
+		where := fmt.Sprintf(" in %s %s", r.Kind(), r.Name())
+		for _, p := range kubernetesErrors(&node, version, where, 0) {
+			file, line, column := r.Position(p.Path)
+			p.Line, p.Column = line, column
+			problems[file] = append(problems[file], p)
+		}
+	}
+
+	res := KustomizeResult{Valid: true, Content: out.String()}
+	names := make([]string, 0, len(problems))
+	for name := range problems {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		ps := problems[name]
+		sort.SliceStable(ps, func(i, j int) bool { return ps[i].Line < ps[j].Line })
+		for _, p := range ps {
+			if p.Severity == "error" {
+				res.Valid = false
+			}
+		}
+		res.Files = append(res.Files, FileProblems{File: name, Problems: ps})
+	}
+	return res, nil
+}
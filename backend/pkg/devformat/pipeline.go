@@ -0,0 +1,391 @@
+package devformat
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"devformat/backend/internal/ai"
+	"devformat/backend/internal/compose"
+	"devformat/backend/internal/dupkeys"
+	"devformat/backend/internal/kubernetes"
+	"devformat/backend/internal/lint"
+	"devformat/backend/internal/parser"
+	"devformat/backend/internal/schema"
+	sugg "devformat/backend/internal/suggestions"
+)
+
+// validationOptions holds everything prepared once per request and shared by
+// every document that runs through the pipeline.
+type validationOptions struct {
+	schemaName string
+	userSchema *schema.Schema
+	k8sVersion string
+	// env holds the .env variables a docker-compose file is interpolated with.
+	env   map[string]string
+	rules []lint.Rule
+	useAI bool
+}
+
+// documentResult is the outcome of running one document through the pipeline.
+type documentResult struct {
+	problems    []Problem
+	suggestions []Suggestion
+	syntaxError bool
+}
+
+// newValidationOptions compiles the user schema, resolves the Kubernetes
+// version, reads the .env file of a compose file and selects lint rules,
+// applying the repository configuration's rule settings. Problems with the
+// schema itself and a Kubernetes version replaced by the nearest bundled one
+// are returned as warnings; a malformed Kubernetes version, an unreadable
+// .env file or an unknown rule is a request error.
+func newValidationOptions(req Request) (validationOptions, []Problem, error) {
+	opts := validationOptions{schemaName: req.Schema, useAI: req.UseAI}
+	var warnings []Problem
+
+	// A user-supplied JSON Schema is compiled once and applied to every document.
+	if (req.Schema == "json" || req.Schema == "custom") && strings.TrimSpace(req.SchemaContent) != "" {
+		compiled, err := schema.Compile([]byte(req.SchemaContent))
+		if err != nil {
+			warnings = append(warnings, Problem{Line: 0, Column: 0, Message: fmt.Sprintf("Invalid %s schema: %s", req.Schema, err.Error()), Severity: "warning", Type: "schema"})
+		} else {
+			opts.userSchema = compiled
+		}
+	}
+
+	if req.Schema == "kubernetes" {
+		v, warning, err := resolveKubernetesVersion(req.KubernetesVersion)
+		if err != nil {
+			return opts, nil, err
+		}
+		opts.k8sVersion = v
+		if warning != nil {
+			warnings = append(warnings, *warning)
+		}
+	}
+
+	if req.Schema == "docker-compose" {
+		env, err := compose.ParseEnv(req.Env)
+		if err != nil {
+			return opts, nil, fmt.Errorf("%w: %v", ErrInvalidEnv, err)
+		}
+		opts.env = env
+	}
+
+	rules, err := req.Config.LintRules(req.Rules)
+	if err != nil {
+		return opts, nil, err
+	}
+	opts.rules = rules
+	return opts, warnings, nil
+}
+
+// resolveKubernetesVersion resolves a requested Kubernetes version to a
+// bundled schema set, with a warning when the nearest one is used instead.
+func resolveKubernetesVersion(v string) (string, *Problem, error) {
+	version, substituted, err := kubernetes.ResolveVersion(v)
+	if err != nil || !substituted {
+		return version, nil, err
+	}
+	msg := fmt.Sprintf("No bundled schemas for Kubernetes %s; validated against %s (supported: %s)", strings.TrimSpace(v), version, strings.Join(kubernetes.Versions(), ", "))
+	return version, &Problem{Line: 0, Column: 0, Message: msg, Severity: "warning", Type: "schema"}, nil
+}
+
+// sortProblems orders problems by position; problems not tied to a line
+// come first and problems at the same position keep their pipeline order.
+func sortProblems(problems []Problem) {
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].Line != problems[j].Line {
+			return problems[i].Line < problems[j].Line
+		}
+		return problems[i].Column < problems[j].Column
+	})
+}
+
+// validateDocument runs a single document through the validation pipeline:
+// syntax first, then schema checks and lint rules on the parsed tree. A
+// document that fails to parse stops after the syntax stage and yields
+// suggestions instead.
+func validateDocument(d parser.Document, index int, format string, opts validationOptions) documentResult {
+	where := ""
+	if format != "json" {
+		where = fmt.Sprintf(" in document %d", index+1)
+	}
+
+	node, res := parseDocument(d, index, format, opts)
+	if res.syntaxError {
+		return res
+	}
+
+	res.problems = append(res.problems, schemaStage(node, d, where, opts)...)
+	res.problems = append(res.problems, lintStage(node, d, format, where, opts)...)
+	return res
+}
+
+// parseDocument is the syntax stage. On failure it reports the error with its
+// position and gathers suggested fixes for YAML documents.
+func parseDocument(d parser.Document, index int, format string, opts validationOptions) (*yaml.Node, documentResult) {
+	var res documentResult
+	lineOffset := d.StartLine - 1
+
+	if format == "json" {
+		var parsed any
+		if err := json.Unmarshal([]byte(d.Content), &parsed); err != nil {
+			line, col := parser.JSONErrorPosition(d.Content, err)
+			res.problems = append(res.problems, Problem{
+				Line:     line + lineOffset,
+				Column:   col,
+				Message:  fmt.Sprintf("JSON syntax error: %s", err.Error()),
+				Severity: "error",
+				Type:     "syntax",
+			})
+			res.syntaxError = true
+			return nil, res
+		}
+		node, err := parser.ParseJSONNode(d.Content)
+		if err != nil || !opts.hasRule("key-duplicates") {
+			// json.Unmarshal keeps the last of repeated keys; report them
+			// here unless the key-duplicates rule will
+			dups, _ := dupkeys.FindJSON(d.Content)
+			res.problems = append(res.problems, duplicateProblems(dups, lineOffset)...)
+		}
+		if err != nil {
+			// valid JSON that YAML cannot represent; skip node-based stages
+			return nil, res
+		}
+		return node, res
+	}
+
+	node, err := parser.ParseYAMLNode(d.Content)
+	if err == nil {
+		// decoding catches what the node parser accepts, e.g. duplicate keys
+		var parsed any
+		err = node.Decode(&parsed)
+		if onlyDuplicateKeys(err) {
+			if opts.hasRule("key-duplicates") {
+				// reported with both positions by the key-duplicates rule instead
+				err = nil
+			} else {
+				// report every pair, nested ones included, and check the
+				// document as a JSON decoder would read it: last key wins
+				res.problems = append(res.problems, duplicateProblems(dupkeys.FindYAML(node), lineOffset)...)
+				dupkeys.Resolve(node, dupkeys.KeepLast)
+				err = node.Decode(&parsed)
+			}
+		}
+	}
+	if err != nil {
+		line, col := parser.YAMLErrorPosition(d.Content, err)
+		if line > 0 {
+			line += lineOffset
+		}
+		res.problems = append(res.problems, Problem{
+			Line:     line,
+			Column:   col,
+			Message:  fmt.Sprintf("YAML syntax error in document %d: %s", index+1, parser.YAMLErrorMessage(err)),
+			Severity: "error",
+			Type:     "syntax",
+		})
+		res.syntaxError = true
+		res.suggestions = suggestForDocument(d.Content, err, opts.useAI)
+		offsetSuggestions(res.suggestions, lineOffset)
+		return nil, res
+	}
+	return node, res
+}
+
+// duplicateProblems reports repeated keys with the positions of both
+// occurrences.
+func duplicateProblems(dups []dupkeys.Duplicate, lineOffset int) []Problem {
+	out := make([]Problem, 0, len(dups))
+	for _, dup := range dups {
+		out = append(out, Problem{
+			Line:     dup.Second.Line + lineOffset,
+			Column:   dup.Second.Column,
+			Message:  fmt.Sprintf("duplicate key %q at %s (first defined at line %d, column %d)", dup.Key, dup.Path, dup.First.Line+lineOffset, dup.First.Column),
+			Severity: "error",
+			Type:     "key-duplicates",
+			Path:     dup.Path,
+		})
+	}
+	return out
+}
+
+// suggestForDocument tries the heuristic suggestion generators in order of
+// confidence and falls back to AI suggestions when requested.
+func suggestForDocument(doc string, parseErr error, useAI bool) []Suggestion {
+	if suggs, _ := sugg.SuggestYAML(doc, parseErr); len(suggs) > 0 {
+		return suggs
+	}
+	if fb := sugg.GenerateBackendSuggestion(doc); len(fb) > 0 {
+		return fb
+	}
+	// targeted detection for serviceName/servicePort misindent under backend
+	if det := sugg.DetectBackendMisindent(doc); len(det) > 0 {
+		return det
+	}
+	if useAI {
+		if aiSug := ai.CallGeminiSuggest(doc); len(aiSug) > 0 {
+			return aiSug
+		}
+	}
+	return nil
+}
+
+// schemaStage applies the schema checks selected by the request to a parsed document.
+func schemaStage(node *yaml.Node, d parser.Document, where string, opts validationOptions) []Problem {
+	if node == nil {
+		return nil
+	}
+	lineOffset := d.StartLine - 1
+	var errs []Problem
+
+	if opts.userSchema != nil {
+		errs = append(errs, schemaErrors(opts.userSchema.Validate(node), where, lineOffset)...)
+	}
+	if opts.k8sVersion != "" {
+		errs = append(errs, kubernetesErrors(node, opts.k8sVersion, where, lineOffset)...)
+	}
+	if opts.schemaName == "docker-compose" {
+		errs = append(errs, composeErrors(node, opts.env, where, lineOffset)...)
+	}
+	if opts.schemaName == "github-actions" {
+		errs = append(errs, workflowErrors(node, where, lineOffset)...)
+	}
+	return errs
+}
+
+// lintStage runs the selected lint rules over a parsed document.
+func lintStage(node *yaml.Node, d parser.Document, format, where string, opts validationOptions) []Problem {
+	if node == nil || len(opts.rules) == 0 {
+		return nil
+	}
+	doc := &lint.Document{
+		Node:     node,
+		Lines:    strings.Split(d.Content, "\n"),
+		Format:   format,
+		Explicit: d.Explicit,
+	}
+	lineOffset := d.StartLine - 1
+	var errs []Problem
+	for _, p := range lint.Run(doc, opts.rules) {
+		errs = append(errs, Problem{
+			Line:     p.Line + lineOffset,
+			Column:   p.Column,
+			Message:  p.Message + where,
+			Severity: p.Severity,
+			Type:     p.RuleID,
+		})
+	}
+	return errs
+}
+
+func (o validationOptions) hasRule(id string) bool {
+	for _, r := range o.rules {
+		if r.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// suppressProblems drops problems silenced by "# devformat-disable-next-line"
+// comments. Syntax errors are never suppressed.
+func suppressProblems(content string, problems []Problem) []Problem {
+	supp := lint.ParseSuppressions(content)
+	if len(supp) == 0 {
+		return problems
+	}
+	out := problems[:0]
+	for _, p := range problems {
+		if p.Type != "syntax" && supp.Suppressed(p.Line, p.Type) {
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// onlyDuplicateKeys reports whether a yaml.v3 decode error consists solely of
+// "mapping key already defined" errors.
+func onlyDuplicateKeys(err error) bool {
+	var te *yaml.TypeError
+	if !errors.As(err, &te) || len(te.Errors) == 0 {
+		return false
+	}
+	for _, e := range te.Errors {
+		if !strings.Contains(e, "already defined") {
+			return false
+		}
+	}
+	return true
+}
+
+// schemaErrors converts JSON Schema violations into validation errors. where is
+// appended to the message to identify the document (e.g. " in document 2") and
+// lineOffset shifts document-relative lines onto the whole stream.
+func schemaErrors(violations []schema.Violation, where string, lineOffset int) []Problem {
+	out := make([]Problem, 0, len(violations))
+	for _, v := range violations {
+		path := v.Path
+		if path == "" {
+			path = "(root)"
+		}
+		out = append(out, Problem{
+			Line:     v.Line + lineOffset,
+			Column:   v.Column,
+			Message:  fmt.Sprintf("Schema violation%s at %s: %s", where, path, v.Message),
+			Severity: "error",
+			Type:     "schema",
+			Path:     v.Path,
+		})
+	}
+	return out
+}
+
+// kubernetesErrors validates a manifest against the bundled Kubernetes schemas.
+// Kinds without a bundled schema (e.g. custom resources) produce a warning
+// instead of failing validation.
+func kubernetesErrors(node *yaml.Node, version, where string, lineOffset int) []Problem {
+	violations, err := kubernetes.Validate(node, version)
+	if err != nil {
+		line := 0
+		if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+			line = node.Content[0].Line + lineOffset
+		}
+		severity := "error"
+		if errors.Is(err, kubernetes.ErrNoSchema) {
+			severity = "warning"
+		}
+		return []Problem{{Line: line, Column: 1, Message: fmt.Sprintf("Kubernetes schema check skipped%s: %s", where, err.Error()), Severity: severity, Type: "schema"}}
+	}
+	return schemaErrors(violations, where, lineOffset)
+}
+
+// offsetSuggestions shifts document-relative suggestions onto the whole
+// multi-document stream.
+func offsetSuggestions(suggs []Suggestion, lineOffset int) {
+	for i := range suggs {
+		suggs[i].Shift(lineOffset)
+	}
+}
+
+// syntaxExplanation summarises which documents failed to parse.
+func syntaxExplanation(format string, failed []int) string {
+	if format == "json" {
+		return "JSON syntax error"
+	}
+	parts := make([]string, len(failed))
+	for i, n := range failed {
+		parts[i] = fmt.Sprint(n)
+	}
+	if len(failed) == 1 {
+		return fmt.Sprintf("YAML syntax error in document %s", parts[0])
+	}
+	return fmt.Sprintf("YAML syntax errors in documents %s", strings.Join(parts, ", "))
+}
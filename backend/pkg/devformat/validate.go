@@ -0,0 +1,133 @@
+package devformat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"devformat/backend/internal/config"
+	"devformat/backend/internal/parser"
+	sugg "devformat/backend/internal/suggestions"
+)
+
+// ErrEmptyContent is returned by Validate when there is nothing to validate.
+var ErrEmptyContent = errors.New("field 'content' is required unless using schema='custom' with schemaContent")
+
+// Validate runs every document of req.Content through the validation
+// pipeline: syntax, then schema checks and lint rules. Results of all
+// documents are aggregated so one broken document does not hide problems in
+// the others. Problems are ordered by line and column.
+func Validate(ctx context.Context, req Request) (Result, error) {
+	// Empty content is allowed only when a custom schema is checked on its own.
+	if strings.TrimSpace(req.Content) == "" && (strings.TrimSpace(req.Schema) != "custom" || strings.TrimSpace(req.SchemaContent) == "") {
+		return Result{}, ErrEmptyContent
+	}
+
+	cfg := req.Config
+	if cfg.Ignored(req.Filename) {
+		return Result{Valid: true, Problems: []Problem{}, Ignored: true, Explanation: fmt.Sprintf("%s is ignored by %s", req.Filename, config.FileName)}, nil
+	}
+	if req.Schema == "" {
+		req.Schema = cfg.SchemaFor(req.Filename)
+	}
+	if req.KubernetesVersion == "" && cfg != nil {
+		req.KubernetesVersion = cfg.KubernetesVersion
+	}
+
+	opts, problems, err := newValidationOptions(req)
+	if err != nil {
+		return Result{}, err
+	}
+	if problems == nil {
+		problems = []Problem{}
+	}
+
+	if parser.ContainsHelmTemplate(req.Content) {
+		return validateTemplate(ctx, req, opts, problems)
+	}
+
+	det := parser.Detect(req.Content, req.Filename)
+	if det.Format != parser.FormatYAML && det.Format != parser.FormatJSON {
+		return validateOther(det, problems), nil
+	}
+	format := det.Format
+	docs := []parser.Document{{Content: req.Content, StartLine: 1}}
+	if format != "json" {
+		docs = parser.SplitYAMLDocuments(req.Content)
+	}
+
+	checked, err := validateDocuments(ctx, docs, format, opts)
+	if err != nil {
+		return Result{}, err
+	}
+	problems = suppressProblems(req.Content, append(problems, checked.problems...))
+	sortProblems(problems)
+
+	explanation := fmt.Sprintf("Validated as %s format", format)
+	if len(checked.failed) > 0 {
+		explanation = syntaxExplanation(format, checked.failed)
+	}
+	res := Result{
+		Valid:            len(problems) == 0,
+		Problems:         problems,
+		Format:           format,
+		FormatConfidence: det.Confidence,
+		CanAutoFix:       len(checked.failed) == 0,
+		Explanation:      explanation,
+		Suggestions:      checked.suggestions,
+	}
+
+	// If there are problems and no document produced suggestions, run a
+	// conservative backend suggestion over the entire content and attach it.
+	if len(problems) > 0 && len(res.Suggestions) == 0 {
+		if fb := sugg.GenerateBackendSuggestion(req.Content); len(fb) > 0 {
+			res.Suggestions = fb
+		}
+	}
+	return res, nil
+}
+
+// documentsResult aggregates the pipeline results of a stream's documents.
+type documentsResult struct {
+	problems    []Problem
+	suggestions []Suggestion
+	// failed lists the 1-based numbers of documents that failed to parse.
+	failed []int
+}
+
+// validateDocuments runs every non-empty document through the pipeline.
+func validateDocuments(ctx context.Context, docs []parser.Document, format string, opts validationOptions) (documentsResult, error) {
+	out := documentsResult{suggestions: []Suggestion{}, failed: []int{}}
+	for i, d := range docs {
+		if err := ctx.Err(); err != nil {
+			return documentsResult{}, err
+		}
+		if strings.TrimSpace(d.Content) == "" {
+			continue
+		}
+		res := validateDocument(d, i, format, opts)
+		out.problems = append(out.problems, res.problems...)
+		out.suggestions = append(out.suggestions, res.suggestions...)
+		if res.syntaxError {
+			out.failed = append(out.failed, i+1)
+		}
+	}
+	return out, nil
+}
+
+// validateOther reports the syntax error, if any, of content detected as a
+// format other than YAML or JSON; schemas and lint rules do not apply to it.
+func validateOther(det parser.Detection, problems []Problem) Result {
+	if det.Err != nil {
+		problems = append(problems, Problem{Line: det.Err.Line, Column: det.Err.Column, Message: det.Err.Message, Severity: "error", Type: "syntax"})
+	}
+	return Result{
+		Valid:            len(problems) == 0,
+		Problems:         problems,
+		Format:           det.Format,
+		FormatConfidence: det.Confidence,
+		Suggestions:      []Suggestion{},
+		Explanation:      fmt.Sprintf("Detected %s format (%s confidence: %s); only its syntax is checked.", det.Format, det.Confidence, det.Reason),
+	}
+}
@@ -0,0 +1,172 @@
+package devformat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		req  Request
+		// problems lists "line:column type" of every problem, in order
+		problems   []string
+		format     string
+		canAutoFix bool
+	}{
+		{
+			name:       "valid yaml",
+			req:        Request{Content: "a: 1\nb:\n  - x\n"},
+			format:     "yaml",
+			canAutoFix: true,
+		},
+		{
+			name:     "syntax error in second document",
+			req:      Request{Content: "a: 1\n---\nb:\n  c: 1\n   d: 2\n"},
+			problems: []string{"5:4 syntax"},
+			format:   "yaml",
+		},
+		{
+			name:     "content after an indented root",
+			req:      Request{Content: "  apiVersion: v1\nkind: ConfigMap\n"},
+			problems: []string{"2:1 syntax"},
+			format:   "yaml",
+		},
+		{
+			name:       "duplicate keys",
+			req:        Request{Content: "a: 1\na: 2\n"},
+			problems:   []string{"2:1 key-duplicates"},
+			format:     "yaml",
+			canAutoFix: true,
+		},
+		{
+			name:     "json5 without a file name",
+			req:      Request{Content: "{\n  \"a\": 1,\n}\n"},
+			problems: []string{"3:1 syntax"},
+			format:   "json",
+		},
+		{
+			name:     "json5 by file name",
+			req:      Request{Content: "{\n  // comment\n  \"a\": 1\n}\n", Filename: "tsconfig.json"},
+			problems: []string{"2:3 syntax"},
+			format:   "json",
+		},
+		{
+			name:       "leading comments",
+			req:        Request{Content: "# header\n---\na: 1\n", Rules: []string{"all"}},
+			format:     "yaml",
+			canAutoFix: true,
+		},
+		{
+			name:       "missing document start",
+			req:        Request{Content: "a: 1\n---\nb: 2\n", Rules: []string{"document-start"}},
+			problems:   []string{"1:1 document-start"},
+			format:     "yaml",
+			canAutoFix: true,
+		},
+		{
+			name: "kubernetes schema",
+			req: Request{
+				Content: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: x\ndata:\n  k: 1\n",
+				Schema:  "kubernetes",
+			},
+			problems:   []string{"6:3 schema"},
+			format:     "yaml",
+			canAutoFix: true,
+		},
+		{
+			name: "custom schema",
+			req: Request{
+				Content:       "name: web\nport: http\n",
+				Schema:        "custom",
+				SchemaContent: `{"properties": {"port": {"type": "integer"}}, "required": ["image"]}`,
+			},
+			problems:   []string{"1:1 schema", "2:1 schema"},
+			format:     "yaml",
+			canAutoFix: true,
+		},
+		{
+			name:     "toml syntax only",
+			req:      Request{Content: "[server\nport = 1\n", Filename: "x.toml"},
+			problems: []string{"1:8 syntax"},
+			format:   "toml",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Validate(context.Background(), tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := problemPositions(res.Problems); got != strings.Join(tt.problems, ", ") {
+				t.Errorf("problems = %s, want %s\n%+v", got, strings.Join(tt.problems, ", "), res.Problems)
+			}
+			if res.Valid != (len(tt.problems) == 0) {
+				t.Errorf("Valid = %v with %d problems", res.Valid, len(res.Problems))
+			}
+			if res.Format != tt.format {
+				t.Errorf("Format = %s, want %s", res.Format, tt.format)
+			}
+			if res.CanAutoFix != tt.canAutoFix {
+				t.Errorf("CanAutoFix = %v, want %v", res.CanAutoFix, tt.canAutoFix)
+			}
+		})
+	}
+}
+
+func TestValidateSyntaxMessage(t *testing.T) {
+	res, err := Validate(context.Background(), Request{Content: "a: 1\n---\nb:\n  c: 1\n   d: 2\n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Problems) != 1 {
+		t.Fatalf("problems = %+v, want one", res.Problems)
+	}
+	// the message must not repeat yaml.v3's line, which counts from the
+	// start of the document
+	if msg := res.Problems[0].Message; strings.Contains(msg, "line") || !strings.Contains(msg, "document 2") {
+		t.Errorf("message = %q", msg)
+	}
+}
+
+func TestValidateRequestErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		req  Request
+		want error
+	}{
+		{"empty content", Request{Content: " \n"}, ErrEmptyContent},
+		{"unknown rule", Request{Content: "a: 1\n", Rules: []string{"no-such-rule"}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Validate(context.Background(), tt.req)
+			if err == nil {
+				t.Fatal("Validate() succeeded")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("Validate() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Validate(ctx, Request{Content: "a: 1\n"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Validate() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+// problemPositions renders problems as "line:column type", comma-separated.
+func problemPositions(problems []Problem) string {
+	var out []string
+	for _, p := range problems {
+		out = append(out, fmt.Sprintf("%d:%d %s", p.Line, p.Column, p.Type))
+	}
+	return strings.Join(out, ", ")
+}
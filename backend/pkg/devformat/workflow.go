@@ -0,0 +1,27 @@
+package devformat
+
+import (
+	"gopkg.in/yaml.v3"
+
+	"devformat/backend/internal/actions"
+)
+
+// workflowErrors checks a GitHub Actions workflow against the workflow
+// schema, then for what GitHub rejects or warns about when it loads the
+// workflow: undefined or circular needs, invalid expressions and contexts,
+// malformed uses references, actions not pinned to a commit SHA and
+// matrices generating too many jobs.
+func workflowErrors(node *yaml.Node, where string, lineOffset int) []Problem {
+	errs := schemaErrors(actions.Validate(node), where, lineOffset)
+	for _, i := range actions.Check(node) {
+		errs = append(errs, Problem{
+			Line:     i.Line + lineOffset,
+			Column:   i.Column,
+			Message:  i.Message + where,
+			Severity: i.Severity,
+			Type:     "workflow",
+			Path:     i.Path,
+		})
+	}
+	return errs
+}
@@ -29,6 +29,17 @@ const SCHEMA_TEMPLATES: Record<string, string> = {
 
 type SchemaOption = "none" | "kubernetes" | "helm" | "json" | "custom";
 
+// Suggestion mirrors the backend's JSON contract (see backend/openapi.json).
+type Suggestion = {
+  id: string;
+  description: string;
+  confidence: "high" | "medium" | "low";
+  startLine: number;
+  endLine: number;
+  replacement: string;
+  source: "heuristic" | "ai";
+};
+
 export default function ValidatePage() {
   const [content, setContent] = useState("");
   const [schema, setSchema] = useState<SchemaOption>("none");
@@ -193,14 +204,14 @@ export default function ValidatePage() {
               <div className="p-3 border border-yellow-200 rounded bg-yellow-50">
                 <div className="font-medium mb-2 text-yellow-700">Suggested Fixes</div>
                 <div className="space-y-3">
-                  {result.suggestedFixes.map((fix: any, i: number) => (
-                    <div key={i} className="text-sm">
-                      <div className="font-medium text-yellow-700">{fix.shortDescription}</div>
+                  {result.suggestedFixes.map((fix: Suggestion) => (
+                    <div key={fix.id} className="text-sm">
+                      <div className="font-medium text-yellow-700">{fix.description}</div>
                       {fix.confidence && (
-                        <div className="text-xs text-yellow-600 mb-2">Confidence: {fix.confidence}</div>
+                        <div className="text-xs text-yellow-600 mb-2">Confidence: {fix.confidence} · lines {fix.startLine}–{fix.endLine}</div>
                       )}
-                      {fix.fixedSnippet && (
-                        <pre className="bg-white p-2 rounded text-xs overflow-auto border">{fix.fixedSnippet}</pre>
+                      {fix.replacement && (
+                        <pre className="bg-white p-2 rounded text-xs overflow-auto border">{fix.replacement}</pre>
                       )}
                     </div>
                   ))}
@@ -4,6 +4,16 @@ export interface YamlValidationRequest {
   content: string;
   filename?: string;
   schema?: 'kubernetes' | 'docker-compose' | 'github-actions' | 'generic';
+  // e.g. "1.28"; empty uses the newest bundled Kubernetes schemas
+  kubernetesVersion?: string;
+  // lint rule IDs, or "all"
+  rules?: string[];
+  // content of a .devformat.yaml file
+  config?: string;
+  // YAML of the values Helm templates in content are rendered with
+  values?: string;
+  // content of the .env file a docker-compose file is interpolated with
+  env?: string;
 }
 
 export interface YamlValidationError {
@@ -11,22 +21,96 @@ export interface YamlValidationError {
   column: number;
   message: string;
   severity: 'error' | 'warning' | 'info';
-  type: 'syntax' | 'indentation' | 'schema' | 'type' | 'format';
+  type: YamlValidationErrorType;
+  // JSON pointer of the offending value for schema errors
+  path?: string;
   suggestion?: string;
 }
 
+export type YamlValidationErrorType =
+  | 'syntax'
+  | 'indentation'
+  | 'schema'
+  | 'type'
+  | 'format'
+  | 'template'
+  | 'chart'
+  | 'kustomize'
+  | 'compose'
+  | 'workflow'
+  | 'autofix'
+  | 'key-duplicates'
+  // a lint rule ID such as "truthy" or "line-length"
+  | (string & {});
+
+// Suggestion and Change mirror backend/internal/types (JSON contract "v1",
+// described in backend/openapi.json).
+export interface Suggestion {
+  id: string;
+  description: string;
+  confidence: 'high' | 'medium' | 'low';
+  startLine: number;
+  endLine: number;
+  replacement: string;
+  source: 'heuristic' | 'ai';
+}
+
+export interface Change {
+  line: number;
+  column?: number;
+  description: string;
+}
+
 export interface YamlValidationResponse {
+  apiVersion: string;
   isValid: boolean;
   errors: YamlValidationError[];
   fixedContent?: string;
   canAutoFix: boolean;
+  format?: 'yaml' | 'json' | 'toml' | 'hcl' | 'ini' | 'dotenv' | 'xml';
+  formatConfidence?: 'high' | 'medium' | 'low';
+  explanation?: string;
+  suggestedFixes?: Suggestion[];
+  renderedContent?: string;
+}
+
+export interface FixHunk {
+  oldStart: number;
+  oldLines: number;
+  newStart: number;
+  newLines: number;
+  before: string[];
+  after: string[];
+  // a fixer ID such as "indentation", "metadata-name" or "format"
+  kind: string;
+}
+
+export interface DocumentFixes {
+  document: number;
+  startLine: number;
+  // "metadata-name" when a Kubernetes metadata.name was added
+  fixers: (YamlFixType | 'metadata-name')[];
+}
+
+export interface YamlFixResponse {
+  apiVersion: string;
+  fixedContent?: string;
+  changes: Change[];
+  diff?: string;
+  hunks: FixHunk[];
+  appliedFixes: DocumentFixes[];
+  isValid: boolean;
+  errors: YamlValidationError[];
+  canAutoFix: boolean;
+  suggestedFixes?: Suggestion[];
   explanation?: string;
-  suggestions: string[];
 }
 
 export interface YamlFixRequest {
   content: string;
   fixTypes: YamlFixType[];
+  duplicateKeys?: 'keep-first' | 'keep-last' | 'merge';
+  indent?: number;
 }
 
 export type YamlFixType = 
@@ -38,6 +122,63 @@ export type YamlFixType =
   | 'duplicate-keys'
   | 'all';
 
+export type ConvertFormat = 'yaml' | 'json' | 'toml';
+
+export interface ConvertRequest {
+  content: string;
+  to: ConvertFormat;
+  filename?: string;
+  from?: ConvertFormat;
+  indent?: number;
+  ndjson?: boolean;
+}
+
+export interface ConvertWarning {
+  line: number;
+  message: string;
+}
+
+export interface ConvertResponse {
+  apiVersion: string;
+  content: string;
+  from: ConvertFormat;
+  to: ConvertFormat;
+  warnings: ConvertWarning[];
+}
+
+export interface HelmLintRequest {
+  // base64 of the chart .tgz or .zip
+  archive: string;
+  kubernetesVersion?: string;
+}
+
+export interface FileErrors {
+  file: string;
+  errors: YamlValidationError[];
+}
+
+export interface HelmLintResponse {
+  apiVersion: string;
+  isValid: boolean;
+  chart: string;
+  version: string;
+  files: FileErrors[];
+}
+
+export interface KustomizeRequest {
+  // base64 of a .tgz or .zip with the kustomization and its bases
+  archive: string;
+  path?: string;
+  kubernetesVersion?: string;
+}
+
+export interface KustomizeResponse {
+  apiVersion: string;
+  isValid: boolean;
+  content: string;
+  files: FileErrors[];
+}
+
 export interface ApiResponse<T = any> {
   success: boolean;
   data?: T;